@@ -0,0 +1,442 @@
+// Package backup writes every stream of a store into a portable backup and restores it into any store.
+//
+// A backup is a directory with a streams.ndjson file holding one JSON envelope per event and snapshot,
+// grouped by stream and in the order they have been stored, and one for the state of every stream that
+// isn't just active, and a manifest.json listing the streams and
+// the checksums of the data files. The manifest is written last, so a backup without one is incomplete.
+//
+//	b := backup.New(example.New())
+//	manifest, err := b.Write(dir, gormStore, gormStore)
+//	manifest, err = b.Restore(dir, memoryStore, memoryStore)
+package backup
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/store"
+)
+
+// Format is the version of the backup format written and restored by this package.
+const Format = 1
+
+// Files of a backup directory.
+const (
+	ManifestFile = "manifest.json"
+	DataFile     = "streams.ndjson"
+)
+
+// Kinds of envelopes.
+const (
+	KindEvent    = "event"
+	KindSnapshot = "snapshot"
+	KindStream   = "stream"
+)
+
+var (
+	// ErrChecksum is returned when a file of a backup doesn't match the checksum of the manifest.
+	ErrChecksum = errors.New("backup file doesn't match its checksum")
+
+	// ErrIncomplete is returned when a backup holds other streams or records than its manifest lists.
+	ErrIncomplete = errors.New("backup doesn't match its manifest")
+)
+
+// Manifest describes a backup.
+type Manifest struct {
+	Format    int       `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	Streams   []Stream  `json:"streams"`
+	Files     []File    `json:"files"`
+}
+
+// Stream is a stream of a backup and the number of its records.
+type Stream struct {
+	Tenant    string            `json:"tenant,omitempty"`
+	Name      lavender.Name     `json:"name"`
+	Events    int               `json:"events"`
+	Snapshots int               `json:"snapshots"`
+	State     store.StreamState `json:"state,omitempty"` // Set if the backup holds the state of the stream
+}
+
+// File is a data file of a backup.
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Envelope is a line of the data file, it holds an event or a snapshot encoded as JSON and the data the
+// store kept about it. Stream envelopes hold the state of the stream, its metadata as data and the
+// scavenged chain head as sequence and hash.
+type Envelope struct {
+	Kind      string            `json:"kind"`
+	Tenant    string            `json:"tenant,omitempty"`
+	Stream    lavender.Name     `json:"stream"`
+	Type      lavender.Name     `json:"type"` // Event name or aggregate of the snapshot
+	ID        string            `json:"id,omitempty"`
+	Sequence  uint64            `json:"sequence"`
+	CreatedAt time.Time         `json:"created_at"`
+	Version   lavender.Version  `json:"version"`
+	Schema    int               `json:"schema,omitempty"` // Schema version of the snapshot
+	State     store.StreamState `json:"state,omitempty"`  // Lifecycle state of the stream
+	Hash      []byte            `json:"hash,omitempty"`
+	PrevHash  []byte            `json:"prev_hash,omitempty"`
+	KeyID     string            `json:"key_id,omitempty"`
+	Signature []byte            `json:"signature,omitempty"`
+	Data      json.RawMessage   `json:"data"`
+}
+
+// Backup writes and restores backups of the streams of the given aggregates.
+type Backup[E lavender.Event, S lavender.Snapshot] struct {
+	// Aggregates are the prototypes used to decode the events and snapshots of their streams.
+	Aggregates []lavender.CustomAggregate[E, S]
+
+	// Clock tells the creation time of the manifest.
+	Clock lavender.Clock
+}
+
+// New creates a Backup for aggregates with default types.
+func New(aggregates ...lavender.Aggregate) *Backup[lavender.Event, lavender.Snapshot] {
+	return NewCustom(aggregates...)
+}
+
+// NewCustom creates a Backup for aggregates with custom types.
+func NewCustom[E lavender.Event, S lavender.Snapshot](aggregates ...lavender.CustomAggregate[E, S]) *Backup[E, S] {
+	return &Backup[E, S]{
+		Aggregates: aggregates,
+		Clock:      lavender.SystemClock{},
+	}
+}
+
+// UseClock sets the clock that tells the creation time of the manifest.
+func (b *Backup[E, S]) UseClock(clock lavender.Clock) *Backup[E, S] {
+	b.Clock = clock
+	return b
+}
+
+// Write writes a backup of every stream of the stores into the directory, which is created if needed.
+func (b *Backup[E, S]) Write(dir string, eventStore store.EventStore[E, S], snapshotStore store.SnapshotStore[E, S]) (*Manifest, error) {
+	streams, err := store.ListStreams(eventStore, b.Aggregates...)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(filepath.Join(dir, DataFile))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{writer: io.MultiWriter(file, hash)}
+	writer := bufio.NewWriter(counter)
+	encoder := json.NewEncoder(writer)
+	manifest := &Manifest{Format: Format, CreatedAt: lavender.ClockOr(b.Clock).Now()}
+	for _, ref := range streams {
+		stream, err := b.writeStream(encoder, ref, eventStore, snapshotStore)
+		if err != nil {
+			return nil, fmt.Errorf("backup %s: %w", ref, err)
+		}
+		if stream.Events > 0 || stream.Snapshots > 0 || stream.State != "" {
+			manifest.Streams = append(manifest.Streams, stream)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	manifest.Files = []File{{Name: DataFile, Size: counter.size, SHA256: hex.EncodeToString(hash.Sum(nil))}}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return manifest, os.WriteFile(filepath.Join(dir, ManifestFile), data, 0o644)
+}
+
+// writeStream writes the envelopes of a stream.
+func (b *Backup[E, S]) writeStream(encoder *json.Encoder, ref store.StreamRef, eventStore store.EventStore[E, S], snapshotStore store.SnapshotStore[E, S]) (Stream, error) {
+	stream := Stream{Tenant: ref.Tenant, Name: ref.Name}
+	aggregate, err := store.StreamAggregate(ref, b.Aggregates...)
+	if err != nil {
+		return stream, err
+	}
+	events, snapshots, err := store.ExportRecords(eventStore, snapshotStore, aggregate)
+	if err != nil {
+		return stream, err
+	}
+	record, err := store.ExportStream(eventStore, aggregate)
+	if err != nil {
+		return stream, err
+	}
+	if !record.IsZero() {
+		data, err := json.Marshal(record.Metadata)
+		if err != nil {
+			return stream, err
+		}
+		if err := encoder.Encode(Envelope{
+			Kind:     KindStream,
+			Tenant:   ref.Tenant,
+			Stream:   ref.Name,
+			Type:     ref.Name,
+			Sequence: record.ScavengedSequence,
+			Hash:     record.ScavengedHash,
+			State:    record.State,
+			Data:     data,
+		}); err != nil {
+			return stream, err
+		}
+		stream.State = record.State
+	}
+	for _, record := range events {
+		data, err := json.Marshal(record.Event)
+		if err != nil {
+			return stream, err
+		}
+		if err := encoder.Encode(Envelope{
+			Kind:      KindEvent,
+			Tenant:    ref.Tenant,
+			Stream:    ref.Name,
+			Type:      record.Event.Name(),
+			ID:        record.ID,
+			Sequence:  record.Sequence,
+			CreatedAt: record.CreatedAt,
+			Version:   record.Version,
+			Hash:      record.Hash,
+			PrevHash:  record.PrevHash,
+			KeyID:     record.Signature.KeyID,
+			Signature: record.Signature.Signature,
+			Data:      data,
+		}); err != nil {
+			return stream, err
+		}
+		stream.Events++
+	}
+	for _, record := range snapshots {
+		data, err := json.Marshal(record.Snapshot)
+		if err != nil {
+			return stream, err
+		}
+		if err := encoder.Encode(Envelope{
+			Kind:      KindSnapshot,
+			Tenant:    ref.Tenant,
+			Stream:    ref.Name,
+			Type:      record.Snapshot.AggregateID(),
+			ID:        record.ID,
+			Sequence:  record.Sequence,
+			CreatedAt: record.CreatedAt,
+			Version:   record.Version,
+			Schema:    record.Schema,
+			Hash:      record.ChainHead,
+			Data:      data,
+		}); err != nil {
+			return stream, err
+		}
+		stream.Snapshots++
+	}
+	return stream, nil
+}
+
+// Verify reads the manifest of the backup in the directory and checks the checksums of its files.
+func Verify(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(Manifest)
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	if manifest.Format != Format {
+		return nil, fmt.Errorf("unsupported backup format %d", manifest.Format)
+	}
+	for _, file := range manifest.Files {
+		if err := verifyFile(filepath.Join(dir, file.Name), file); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// verifyFile checks the size and checksum of a file.
+func verifyFile(path string, file File) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+	if size != file.Size || hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("%w: %s", ErrChecksum, file.Name)
+	}
+	return nil
+}
+
+// Restore verifies the backup in the directory and imports its streams into the stores. Stores that
+// implement store.RecordStore keep the IDs, sequences, timestamps and versions of the records, their
+// streams must be empty. The states of the streams are restored last, see store.ImportStream.
+func (b *Backup[E, S]) Restore(dir string, eventStore store.EventStore[E, S], snapshotStore store.SnapshotStore[E, S]) (*Manifest, error) {
+	manifest, err := Verify(dir)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(dir, DataFile))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<30)
+	var current *streamRecords[E, S]
+	restored := make([]Stream, 0, len(manifest.Streams))
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		if err := store.ImportRecords(eventStore, snapshotStore, current.aggregate, current.events, current.snapshots); err != nil {
+			return fmt.Errorf("restore %s: %w", current.ref, err)
+		}
+		stream := Stream{Tenant: current.ref.Tenant, Name: current.ref.Name, Events: len(current.events), Snapshots: len(current.snapshots)}
+		if current.stream != nil {
+			if err := store.ImportStream(eventStore, current.aggregate, *current.stream); err != nil {
+				return fmt.Errorf("restore %s: %w", current.ref, err)
+			}
+			stream.State = current.stream.State
+		}
+		restored = append(restored, stream)
+		return nil
+	}
+	for scanner.Scan() {
+		var envelope Envelope
+		if err := json.Unmarshal(scanner.Bytes(), &envelope); err != nil {
+			return nil, fmt.Errorf("read envelope: %w", err)
+		}
+		ref := store.StreamRef{Tenant: envelope.Tenant, Name: envelope.Stream}
+		if current == nil || current.ref != ref {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			aggregate, err := store.StreamAggregate(ref, b.Aggregates...)
+			if err != nil {
+				return nil, err
+			}
+			current = &streamRecords[E, S]{ref: ref, aggregate: aggregate}
+		}
+		if err := current.add(envelope); err != nil {
+			return nil, fmt.Errorf("restore %s: %w", ref, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if !slices.Equal(restored, manifest.Streams) {
+		return manifest, ErrIncomplete
+	}
+	return manifest, nil
+}
+
+// streamRecords collects the records of a stream while restoring it.
+type streamRecords[E lavender.Event, S lavender.Snapshot] struct {
+	ref       store.StreamRef
+	aggregate lavender.CustomAggregate[E, S]
+	events    []store.EventRecord[E]
+	snapshots []store.SnapshotRecord[S]
+	stream    *store.StreamRecord
+}
+
+// add decodes the envelope with the types of the aggregate and adds it to the records.
+func (r *streamRecords[E, S]) add(envelope Envelope) error {
+	switch envelope.Kind {
+	case KindEvent:
+		event, ok := r.event(envelope.Type)
+		if !ok {
+			return fmt.Errorf("invalid event type %s", envelope.Type)
+		}
+		if err := json.Unmarshal(envelope.Data, event); err != nil {
+			return fmt.Errorf("decode event %s: %w", envelope.Type, err)
+		}
+		r.events = append(r.events, store.EventRecord[E]{
+			Event:     event,
+			ID:        envelope.ID,
+			Sequence:  envelope.Sequence,
+			CreatedAt: envelope.CreatedAt,
+			Version:   envelope.Version,
+			Hash:      envelope.Hash,
+			PrevHash:  envelope.PrevHash,
+			Signature: store.Signature{KeyID: envelope.KeyID, Signature: envelope.Signature},
+		})
+	case KindSnapshot:
+		snapshot := lavender.NewOf(r.aggregate.TakeSnapshot())
+		if err := json.Unmarshal(envelope.Data, snapshot); err != nil {
+			return fmt.Errorf("decode snapshot %s: %w", envelope.Type, err)
+		}
+		r.snapshots = append(r.snapshots, store.SnapshotRecord[S]{
+			Snapshot:  snapshot,
+			ID:        envelope.ID,
+			CreatedAt: envelope.CreatedAt,
+			Version:   envelope.Version,
+			Sequence:  envelope.Sequence,
+			ChainHead: envelope.Hash,
+			Schema:    envelope.Schema,
+		})
+	case KindStream:
+		record := &store.StreamRecord{
+			State:             envelope.State,
+			ScavengedSequence: envelope.Sequence,
+			ScavengedHash:     envelope.Hash,
+		}
+		if err := json.Unmarshal(envelope.Data, &record.Metadata); err != nil {
+			return fmt.Errorf("decode stream metadata: %w", err)
+		}
+		r.stream = record
+	default:
+		return fmt.Errorf("invalid envelope kind %q", envelope.Kind)
+	}
+	return nil
+}
+
+// event returns a new event of the aggregate with the given name.
+func (r *streamRecords[E, S]) event(name lavender.Name) (E, bool) {
+	for _, event := range r.aggregate.Events() {
+		if event.Name() == name {
+			return lavender.NewOf(event), true
+		}
+	}
+	var zero E
+	return zero, false
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	writer io.Writer
+	size   int64
+}
+
+// Write implements io.Writer.
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.size += int64(n)
+	return n, err
+}
@@ -0,0 +1,146 @@
+package backup_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/backup"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/lavendertest"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBackup(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := lavendertest.NewTickingClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), time.Minute)
+	src := store.NewGormStore(db).UseHashChain().UseClock(clock).RegisterAggregates(example.New())
+	for _, tenant := range []string{"", "ducks"} {
+		aggregate := lavender.WithTenant(example.New(), tenant)
+		for _, email := range []string{"a@t.de", "b@t.de"} {
+			if err := src.SaveEvents(aggregate, []lavender.Event{&example.Create{User: *example.NewUser(email, "secret")}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := src.SaveSnapshot(aggregate, example.New().TakeSnapshot()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	b := backup.New(example.New()).UseClock(clock)
+	manifest, err := b.Write(dir, src, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, backup.Format, manifest.Format)
+	assert.Equal(t, []backup.Stream{
+		{Name: example.New().Name(), Events: 2, Snapshots: 1},
+		{Tenant: "ducks", Name: example.New().Name(), Events: 2, Snapshots: 1},
+	}, manifest.Streams)
+
+	dst := store.NewInMemoryStore().UseHashChain()
+	if _, err := b.Restore(dir, dst, dst); err != nil {
+		t.Fatal(err)
+	}
+	for _, tenant := range []string{"", "ducks"} {
+		aggregate := lavender.WithTenant(example.New(), tenant)
+		expected, expectedSnapshots, err := store.ExportRecords(src, src, aggregate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events, snapshots, err := store.ExportRecords(dst, dst, aggregate)
+		if !assert.NoError(t, err) || !assert.Len(t, events, len(expected)) || !assert.Len(t, snapshots, len(expectedSnapshots)) {
+			continue
+		}
+		for i, record := range expected {
+			assert.Equal(t, record.ID, events[i].ID)
+			assert.Equal(t, record.Sequence, events[i].Sequence)
+			assert.True(t, record.CreatedAt.Equal(events[i].CreatedAt))
+			assert.Equal(t, record.Hash, events[i].Hash)
+			assert.Equal(t, record.Event, events[i].Event)
+		}
+		assert.Equal(t, expectedSnapshots[0].ID, snapshots[0].ID)
+		assert.NoError(t, store.VerifyStream(dst, aggregate))
+	}
+
+	// Restoring twice fails because the streams aren't empty anymore.
+	_, err = b.Restore(dir, dst, dst)
+	assert.ErrorIs(t, err, store.ErrStreamNotEmpty)
+
+	// A modified data file is detected before anything is restored.
+	path := filepath.Join(dir, backup.DataFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = backup.Verify(dir)
+	assert.ErrorIs(t, err, backup.ErrChecksum)
+	_, err = b.Restore(dir, store.NewInMemoryStore(), store.NewInMemoryStore())
+	assert.ErrorIs(t, err, backup.ErrChecksum)
+}
+
+func TestBackupStreamStates(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := store.NewGormStore(db).UseHashChain().RegisterAggregates(example.New())
+	closed := example.New()
+	ducks := lavender.WithTenant(example.New(), "ducks")
+	geese := lavender.WithTenant(example.New(), "geese")
+	for _, aggregate := range []lavender.Aggregate{closed, ducks, geese} {
+		for _, email := range []string{"a@t.de", "b@t.de", "c@t.de"} {
+			if err := src.SaveEvents(aggregate, []lavender.Event{&example.Create{User: *example.NewUser(email, "secret")}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	assert.NoError(t, src.SetStreamMetadata(closed, store.StreamMetadata{MaxCount: 1}))
+	if _, err := src.Scavenge(); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, src.SetStreamState(closed, store.StreamClosed))
+	assert.NoError(t, src.SetStreamState(ducks, store.StreamDeleted))
+	assert.NoError(t, src.SetStreamState(geese, store.StreamTombstoned))
+
+	dir := t.TempDir()
+	b := backup.New(example.New())
+	manifest, err := b.Write(dir, src, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []backup.Stream{
+		{Name: example.New().Name(), Events: 1, State: store.StreamClosed},
+		{Tenant: "ducks", Name: example.New().Name(), Events: 3, State: store.StreamDeleted},
+		{Tenant: "geese", Name: example.New().Name(), State: store.StreamTombstoned},
+	}, manifest.Streams)
+
+	dst := store.NewInMemoryStore().UseHashChain()
+	if _, err := b.Restore(dir, dst, dst); err != nil {
+		t.Fatal(err)
+	}
+	for _, aggregate := range []lavender.Aggregate{closed, ducks, geese} {
+		expected, err := store.ExportStream(src, aggregate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		record, err := store.ExportStream(dst, aggregate)
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, record)
+		}
+	}
+	assert.NoError(t, store.VerifyStream(dst, closed))
+}
@@ -0,0 +1,253 @@
+// Package cli implements the lavender command line tool, which inspects and operates the streams of a
+// GORM store without writing SQL against its tables.
+//
+// The lavender binary (cmd/lavender) doesn't know any aggregate. It dumps CBOR, JSON and gob payloads without
+// their Go types, but can't verify, compare, snapshot or replay streams. Applications register their aggregates by
+// building their own binary with the same commands:
+//
+//	func main() {
+//		cli.Main(example.New())
+//	}
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/thesyncim/go-clone"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// App is the lavender command line tool.
+type App[E lavender.Event, S lavender.Snapshot] struct {
+	// Aggregates are the prototypes of the registered aggregates. Commands work on clones of them.
+	Aggregates []lavender.CustomAggregate[E, S]
+
+	// Open connects to the database named by the -db flag.
+	Open func(dsn string) (*gorm.DB, error)
+
+	// Stdout receives the output of the commands.
+	Stdout io.Writer
+
+	// Stderr receives the usage and the progress of the commands.
+	Stderr io.Writer
+}
+
+// New creates the tool for aggregates with default types.
+func New(aggregates ...lavender.Aggregate) *App[lavender.Event, lavender.Snapshot] {
+	return NewCustom(aggregates...)
+}
+
+// NewCustom creates the tool for aggregates with custom types.
+func NewCustom[E lavender.Event, S lavender.Snapshot](aggregates ...lavender.CustomAggregate[E, S]) *App[E, S] {
+	return &App[E, S]{
+		Aggregates: aggregates,
+		Open:       OpenSqlite,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+	}
+}
+
+// UseOutput sets the writers receiving the output and the usage of the commands.
+func (app *App[E, S]) UseOutput(stdout io.Writer, stderr io.Writer) *App[E, S] {
+	app.Stdout = stdout
+	app.Stderr = stderr
+	return app
+}
+
+// Main runs the tool with the arguments of the process for aggregates with default types and exits.
+func Main(aggregates ...lavender.Aggregate) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := New(aggregates...).Run(ctx, os.Args[1:])
+	stop()
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "lavender:", err)
+		os.Exit(1)
+	}
+}
+
+// OpenSqlite opens an existing SQLite database without logging.
+func OpenSqlite(dsn string) (*gorm.DB, error) {
+	// SQLite creates missing database files, a mistyped path would look like an empty store
+	if path, _, _ := strings.Cut(dsn, "?"); !strings.HasPrefix(dsn, "file:") && path != ":memory:" {
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+	}
+	return gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+}
+
+// command is a subcommand of the tool.
+type command[E lavender.Event, S lavender.Snapshot] struct {
+	args    string // Usage of the arguments
+	summary string // What the command does
+	writes  bool   // The command changes the database, its tables are migrated first
+	run     func(s *session[E, S], ctx context.Context, args []string) error
+}
+
+// commands returns the subcommands by name.
+func commands[E lavender.Event, S lavender.Snapshot]() map[string]command[E, S] {
+	return map[string]command[E, S]{
+		"aggregates":  {summary: "list the aggregates of the database", run: (*session[E, S]).aggregates},
+		"streams":     {args: "[aggregate]", summary: "list the streams with their event counts and states", run: (*session[E, S]).streams},
+		"events":      {args: "<aggregate>", summary: "dump the events of a stream as JSON lines", run: (*session[E, S]).events},
+		"snapshots":   {args: "<aggregate>", summary: "dump the snapshots of a stream as JSON lines", run: (*session[E, S]).snapshots},
+		"tail":        {args: "[aggregate...]", summary: "follow new events as JSON lines", run: (*session[E, S]).tail},
+		"verify":      {args: "[aggregate...]", summary: "verify the hash chains of the streams of registered aggregates", run: (*session[E, S]).verify},
+		"consistency": {args: "[aggregate...]", summary: "compare the latest snapshots of registered aggregates with a replay of their full history", run: (*session[E, S]).consistency},
+		"snapshot":    {args: "<aggregate>", summary: "take a snapshot of a registered aggregate", writes: true, run: (*session[E, S]).snapshot},
+		"replay":      {args: "<aggregate>", summary: "replay the full history of a registered aggregate and print its state", run: (*session[E, S]).replay},
+		"migrate":     {summary: "create or update the tables of the registered aggregates", writes: true, run: (*session[E, S]).migrate},
+	}
+}
+
+// Run runs the command line. It returns flag.ErrHelp if the usage has been printed.
+func (app *App[E, S]) Run(ctx context.Context, args []string) error {
+	global := flag.NewFlagSet("lavender", flag.ContinueOnError)
+	global.SetOutput(app.Stderr)
+	dsn := global.String("db", "lavender.db", "SQLite database of the store")
+	encoderName := global.String("encoder", "cbor", "encoder of written snapshots: cbor, json or gob")
+	shared := global.Bool("shared", false, "the events of all aggregates are stored in the shared events table")
+	hashChain := global.Bool("hash-chain", false, "the store links events by a hash chain")
+	archive := global.String("archive", "", "directory of the archived events")
+	cmds := commands[E, S]()
+	global.Usage = func() { app.usage(global, cmds) }
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		return flag.ErrHelp
+	}
+	name := global.Arg(0)
+	cmd, ok := cmds[name]
+	if !ok {
+		global.Usage()
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	encoder, err := encoderOf(*encoderName)
+	if err != nil {
+		return err
+	}
+	db, err := app.Open(*dsn)
+	if err != nil {
+		return err
+	}
+	// Inspecting a database must not change it, only the commands that write migrate its tables.
+	gormStore := store.NewGormCustomStore[E, S](db, encoders.NewMultiEncoder(encoder)).UseExistingSchema()
+	if *shared {
+		gormStore.UseSharedTable()
+	}
+	if *hashChain {
+		gormStore.UseHashChain()
+	}
+	if *archive != "" {
+		gormStore.UseArchive(*archive)
+	}
+	gormStore.RegisterAggregates(app.Aggregates...)
+	if cmd.writes {
+		err = gormStore.Migrate()
+	} else if err = gormStore.CheckSchema(); err != nil {
+		err = fmt.Errorf("%w\nrun the migrate command to create or update the tables", err)
+	}
+	if err != nil {
+		return err
+	}
+	s := &session[E, S]{app: app, db: db, store: gormStore, name: name, command: cmd}
+	return cmd.run(s, ctx, global.Args()[1:])
+}
+
+// usage prints the usage of the tool.
+func (app *App[E, S]) usage(global *flag.FlagSet, cmds map[string]command[E, S]) {
+	fmt.Fprintf(app.Stderr, "usage: lavender [flags] <command> [command flags] [args]\n\ncommands:\n")
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(app.Stderr, "  %-11s %s\n", name, cmds[name].summary)
+	}
+	fmt.Fprintf(app.Stderr, "\nflags:\n")
+	global.PrintDefaults()
+}
+
+// encoderOf returns the built-in encoder of the name.
+func encoderOf(name string) (encoders.Encoder, error) {
+	switch strings.ToLower(name) {
+	case "cbor":
+		return encoders.NewCBorEncoder(), nil
+	case "json":
+		return encoders.NewJsonEncoder(), nil
+	case "gob":
+		return encoders.NewGobEncoder(), nil
+	}
+	return nil, fmt.Errorf("unknown encoder %q", name)
+}
+
+// session is a command running on an opened store.
+type session[E lavender.Event, S lavender.Snapshot] struct {
+	app   *App[E, S]
+	db    *gorm.DB
+	store *store.GormStore[E, S]
+
+	name    string        // Name of the running command
+	command command[E, S] // The running command
+}
+
+// flags returns the flag set of the running command.
+func (s *session[E, S]) flags() *flag.FlagSet {
+	flags := flag.NewFlagSet(s.name, flag.ContinueOnError)
+	flags.SetOutput(s.app.Stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(s.app.Stderr, "usage: lavender [flags] %s [flags] %s\n\n%s\n", s.name, s.command.args, s.command.summary)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// prototype returns the registered aggregate of the name or nil.
+func (s *session[E, S]) prototype(name lavender.Name) lavender.CustomAggregate[E, S] {
+	for _, aggregate := range s.app.Aggregates {
+		if aggregate.Name() == name {
+			return aggregate
+		}
+	}
+	return nil
+}
+
+// aggregate returns a new aggregate of the stream, it fails if the aggregate isn't registered.
+func (s *session[E, S]) aggregate(ref store.StreamRef) (lavender.CustomAggregate[E, S], error) {
+	prototype := s.prototype(ref.Name)
+	if prototype == nil {
+		return nil, fmt.Errorf("aggregate %s is not registered", ref.Name)
+	}
+	return lavender.WithTenant(clone.Clone(prototype).(lavender.CustomAggregate[E, S]), ref.Tenant), nil
+}
+
+// oneArg returns the only argument of the running command.
+func oneArg(flags *flag.FlagSet) (lavender.Name, error) {
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return "", fmt.Errorf("%s expects one aggregate", flags.Name())
+	}
+	return lavender.Name(flags.Arg(0)), nil
+}
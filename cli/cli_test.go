@@ -0,0 +1,206 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/cli"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newDatabase creates a database with events of the account aggregate in the default tenant and the ducks tenant.
+func newDatabase(t *testing.T) (string, *store.GormStore[lavender.Event, lavender.Snapshot]) {
+	path := filepath.Join(t.TempDir(), "lavender.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormStore := store.NewGormStore(db).UseHashChain().RegisterAggregates(example.New())
+	for _, tenant := range []string{"", "ducks"} {
+		for _, email := range []string{"a@t.de", "b@t.de"} {
+			if err := gormStore.SaveEvents(lavender.WithTenant(example.New(), tenant), []lavender.Event{&example.Create{User: *example.NewUser(email, "secret")}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return path, gormStore
+}
+
+// run runs the tool and returns its output.
+func run(t *testing.T, app *cli.App[lavender.Event, lavender.Snapshot], args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	err := app.UseOutput(&stdout, &stderr).Run(context.Background(), args)
+	return stdout.String(), err
+}
+
+// lines decodes the JSON lines of the output.
+func lines(t *testing.T, output string) []map[string]any {
+	var result []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var value map[string]any
+		if err := json.Unmarshal([]byte(line), &value); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		result = append(result, value)
+	}
+	return result
+}
+
+func TestInspect(t *testing.T) {
+	path, _ := newDatabase(t)
+	for name, app := range map[string]*cli.App[lavender.Event, lavender.Snapshot]{
+		"unregistered": cli.New(),
+		"registered":   cli.New(example.New()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			output, err := run(t, app, "-db", path, "aggregates")
+			if assert.NoError(t, err) {
+				assert.Regexp(t, `account\s+4\s+0`, output)
+			}
+
+			output, err = run(t, app, "-db", path, "streams")
+			if assert.NoError(t, err) {
+				assert.Regexp(t, `-\s+account\s+2\s+0\s+0\s+active`, output)
+				assert.Regexp(t, `ducks\s+account\s+2\s+0\s+0\s+active`, output)
+			}
+
+			// Payloads are decoded with their types or without them, and look the same as JSON.
+			output, err = run(t, app, "-db", path, "events", "-tenant", "ducks", "account")
+			if assert.NoError(t, err) {
+				events := lines(t, output)
+				if assert.Len(t, events, 2) {
+					assert.Equal(t, "ducks", events[0]["tenant"])
+					assert.Equal(t, "create", events[0]["topic"])
+					assert.Equal(t, float64(2), events[1]["sequence"])
+					assert.Equal(t, events[0]["hash"], events[1]["prev_hash"])
+					assert.Equal(t, "b@t.de", events[1]["data"].(map[string]any)["Email"])
+				}
+			}
+		})
+	}
+
+	_, err := run(t, cli.New(), "-db", filepath.Join(t.TempDir(), "missing.db"), "streams")
+	assert.Error(t, err, "missing databases aren't created")
+}
+
+func TestMigrate(t *testing.T) {
+	path, gormStore := newDatabase(t)
+	migrator := gormStore.Db.Migrator()
+	assert.NoError(t, migrator.DropTable(store.ChainHeadTableName))
+	snapshots := gormStore.Db.Table(store.SnapshotTableName("account")).Migrator()
+	assert.NoError(t, snapshots.DropColumn(new(store.Snapshot), "schema"), "snapshot tables of an older version")
+	app := cli.New(example.New())
+
+	// Inspecting doesn't change the schema, it fails if the tables are missing or outdated.
+	for _, command := range []string{"streams", "events", "verify"} {
+		_, err := run(t, app, "-db", path, command, "account")
+		assert.ErrorIs(t, err, store.ErrSchemaOutdated, command)
+	}
+	assert.False(t, migrator.HasTable(store.ChainHeadTableName))
+	assert.False(t, snapshots.HasColumn(new(store.Snapshot), "schema"))
+
+	_, err := run(t, app, "-db", path, "migrate")
+	assert.NoError(t, err)
+	assert.True(t, migrator.HasTable(store.ChainHeadTableName))
+	assert.True(t, snapshots.HasColumn(new(store.Snapshot), "schema"))
+	_, err = run(t, app, "-db", path, "verify")
+	assert.NoError(t, err)
+
+	_, err = run(t, cli.New(), "-db", path, "migrate")
+	assert.Error(t, err, "only registered aggregates are migrated")
+}
+
+func TestTail(t *testing.T) {
+	path, gormStore := newDatabase(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	done := make(chan error)
+	go func() {
+		done <- cli.New().UseOutput(&stdout, new(bytes.Buffer)).Run(ctx, []string{"-db", path, "tail", "-all", "-interval", "10ms", "-n", "5", "account"})
+	}()
+	if err := gormStore.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *example.NewUser("c@t.de", "secret")}}); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, <-done)
+	events := lines(t, stdout.String())
+	if assert.Len(t, events, 5) {
+		assert.Equal(t, "c@t.de", events[4]["data"].(map[string]any)["Email"])
+	}
+}
+
+func TestOperate(t *testing.T) {
+	path, gormStore := newDatabase(t)
+	app := cli.New(example.New())
+
+	output, err := run(t, app, "-db", path, "verify")
+	assert.NoError(t, err)
+	assert.Equal(t, "ok\taccount\nok\tducks/account\n", output)
+
+	_, err = run(t, cli.New(), "-db", path, "verify")
+	assert.Error(t, err, "unregistered aggregates can't be verified")
+
+	_, err = run(t, app, "-db", path, "-hash-chain", "snapshot", "-tenant", "ducks", "account")
+	assert.NoError(t, err)
+	output, err = run(t, app, "-db", path, "snapshots", "-tenant", "ducks", "account")
+	if assert.NoError(t, err) {
+		snapshots := lines(t, output)
+		if assert.Len(t, snapshots, 1) {
+			assert.Equal(t, float64(2), snapshots[0]["sequence"])
+			assert.Len(t, snapshots[0]["data"].(map[string]any)["users"], 2)
+		}
+	}
+
+	// The covered events stay in the log and aren't applied again on top of the snapshots.
+	_, err = run(t, app, "-db", path, "replay", "-tenant", "ducks", "-snapshot", "account")
+	assert.NoError(t, err)
+	loaded, err := example.Load(repo.NewRepositoryConstructor(false, gormStore, gormStore).ForTenant("ducks"))
+	if assert.NoError(t, err) {
+		assert.Len(t, loaded.Users, 2)
+	}
+
+	output, err = run(t, app, "-db", path, "consistency", "-tenant", "ducks")
+	assert.NoError(t, err)
+	assert.Equal(t, "ok\tducks/account\n", output)
+
+	output, err = run(t, app, "-db", path, "replay", "account")
+	if assert.NoError(t, err) {
+		var snapshot example.AccountSnapshot
+		assert.NoError(t, json.Unmarshal([]byte(output), &snapshot))
+		assert.Len(t, snapshot.Users, 2)
+	}
+
+	// Tampered events fail the verification.
+	if err := gormStore.Db.Table(store.EventTableName("account")).Where("tenant = ? AND sequence = ?", "ducks", 1).Update("hash", []byte("tampered")).Error; err != nil {
+		t.Fatal(err)
+	}
+	output, err = run(t, app, "-db", path, "verify")
+	assert.Error(t, err)
+	assert.Contains(t, output, "ok\taccount\n")
+	assert.Contains(t, output, "FAIL\tducks/account")
+
+	// The replay of a cleared stream misses the events covered by its snapshots and isn't stored as snapshot.
+	if err := gormStore.ClearEvents(lavender.WithTenant(example.New(), "ducks")); err != nil {
+		t.Fatal(err)
+	}
+	_, err = run(t, app, "-db", path, "replay", "-tenant", "ducks", "-snapshot", "account")
+	assert.ErrorContains(t, err, "doesn't cover the 2 events")
+
+	// Its snapshot can't be verified, which isn't an inconsistency.
+	output, err = run(t, app, "-db", path, "consistency", "-tenant", "ducks")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "SKIP\tducks/account")
+}
@@ -0,0 +1,376 @@
+package cli
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/thesyncim/go-clone"
+	"gorm.io/gorm"
+)
+
+// eventLine is an event as printed by the events and tail commands.
+type eventLine struct {
+	Position    uint64           `json:"position"`
+	ID          string           `json:"id"`
+	Tenant      string           `json:"tenant,omitempty"`
+	Aggregate   lavender.Name    `json:"aggregate"`
+	Sequence    uint64           `json:"sequence"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Version     lavender.Version `json:"version"`
+	Topic       lavender.Name    `json:"topic"`
+	ContentType string           `json:"content_type,omitempty"`
+	Hash        string           `json:"hash,omitempty"`
+	PrevHash    string           `json:"prev_hash,omitempty"`
+	KeyID       string           `json:"key_id,omitempty"`
+	Signed      bool             `json:"signed,omitempty"`
+	Data        any              `json:"data"`
+}
+
+// snapshotLine is a snapshot as printed by the snapshots command.
+type snapshotLine struct {
+	ID          string           `json:"id"`
+	Tenant      string           `json:"tenant,omitempty"`
+	Aggregate   lavender.Name    `json:"aggregate"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Version     lavender.Version `json:"version"`
+	Sequence    uint64           `json:"sequence"`
+	ChainHead   string           `json:"chain_head,omitempty"`
+	ContentType string           `json:"content_type,omitempty"`
+	Data        any              `json:"data"`
+}
+
+// aggregates lists the aggregates that have tables in the database or are registered.
+func (s *session[E, S]) aggregates(_ context.Context, args []string) error {
+	flags := s.flags()
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	tables, err := s.db.Migrator().GetTables()
+	if err != nil {
+		return err
+	}
+	var names []lavender.Name
+	for _, table := range tables {
+		if name, ok := strings.CutPrefix(table, "event_"); ok {
+			names = append(names, lavender.Name(name))
+		} else if name, ok := strings.CutPrefix(table, "snapshot_"); ok {
+			names = append(names, lavender.Name(name))
+		}
+	}
+	if slices.Contains(tables, store.SharedEventTableName) {
+		var shared []lavender.Name
+		if err := s.db.Table(store.SharedEventTableName).Distinct("name").Pluck("name", &shared).Error; err != nil {
+			return err
+		}
+		names = append(names, shared...)
+	}
+	for _, aggregate := range s.app.Aggregates {
+		names = append(names, aggregate.Name())
+	}
+	slices.Sort(names)
+
+	writer := tabwriter.NewWriter(s.app.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "AGGREGATE\tEVENTS\tSNAPSHOTS\tREGISTERED")
+	for _, name := range slices.Compact(names) {
+		events, err := s.count(s.eventTable(name), s.db.Where("name = ?", name))
+		if err != nil {
+			return err
+		}
+		snapshots, err := s.count(store.SnapshotTableName(name), s.db.Where("name = ?", name))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%t\n", name, events, snapshots, s.prototype(name) != nil)
+	}
+	return writer.Flush()
+}
+
+// streams lists the streams of the store with their event counts and states.
+func (s *session[E, S]) streams(_ context.Context, args []string) error {
+	flags := s.flags()
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("streams expects at most one aggregate")
+	}
+	refs, err := s.store.Streams()
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(s.app.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "TENANT\tAGGREGATE\tEVENTS\tARCHIVED\tSNAPSHOTS\tSTATE")
+	for _, ref := range refs {
+		if flags.NArg() == 1 && ref.Name != lavender.Name(flags.Arg(0)) {
+			continue
+		}
+		events, err := s.count(s.eventTable(ref.Name), whereStream(s.db, ref))
+		if err != nil {
+			return err
+		}
+		var archived int64
+		if s.db.Migrator().HasTable(store.ArchiveTableName) {
+			if err := whereStream(s.db.Table(store.ArchiveTableName), ref).Select("COALESCE(SUM(count), 0)").Scan(&archived).Error; err != nil {
+				return err
+			}
+		}
+		snapshots, err := s.count(store.SnapshotTableName(ref.Name), whereStream(s.db, ref))
+		if err != nil {
+			return err
+		}
+		state, err := s.state(ref)
+		if err != nil {
+			return err
+		}
+		tenant := ref.Tenant
+		if tenant == "" {
+			tenant = "-"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%d\t%s\n", tenant, ref.Name, events, archived, snapshots, state)
+	}
+	return writer.Flush()
+}
+
+// events dumps the events of a stream, including archived ones.
+func (s *session[E, S]) events(_ context.Context, args []string) error {
+	flags := s.flags()
+	tenant := flags.String("tenant", "", "tenant of the stream")
+	from := flags.Uint64("from", 0, "first sequence to dump")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	name, err := oneArg(flags)
+	if err != nil {
+		return err
+	}
+	events, err := s.store.LoadRawEvents(store.StreamRef{Tenant: *tenant, Name: name})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(s.app.Stdout)
+	for _, event := range events {
+		if event.Sequence < *from {
+			continue
+		}
+		if err := s.writeEvent(encoder, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshots dumps the snapshots of a stream of all versions.
+func (s *session[E, S]) snapshots(_ context.Context, args []string) error {
+	flags := s.flags()
+	tenant := flags.String("tenant", "", "tenant of the stream")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	name, err := oneArg(flags)
+	if err != nil {
+		return err
+	}
+	snapshots, err := s.store.LoadRawSnapshots(store.StreamRef{Tenant: *tenant, Name: name})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(s.app.Stdout)
+	for _, snapshot := range snapshots {
+		data, err := s.decodeSnapshot(snapshot)
+		if err != nil {
+			return fmt.Errorf("snapshot %s: %w", snapshot.ID, err)
+		}
+		if err := encoder.Encode(snapshotLine{
+			ID:          snapshot.ID,
+			Tenant:      snapshot.Tenant,
+			Aggregate:   snapshot.Name,
+			CreatedAt:   snapshot.CreatedAt,
+			Version:     snapshot.Version,
+			Sequence:    snapshot.Sequence,
+			ChainHead:   hex.EncodeToString(snapshot.ChainHead),
+			ContentType: snapshot.ContentType,
+			Data:        data,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tail prints events appended to the event tables until the context is done.
+func (s *session[E, S]) tail(ctx context.Context, args []string) error {
+	flags := s.flags()
+	tenant := flags.String("tenant", "", "only follow the streams of the tenant")
+	interval := flags.Duration("interval", time.Second, "interval to poll for new events")
+	limit := flags.Int("n", 0, "stop after n events, 0 follows until interrupted")
+	all := flags.Bool("all", false, "start with the stored events instead of new ones")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	names := make([]lavender.Name, 0, flags.NArg())
+	for _, arg := range flags.Args() {
+		names = append(names, lavender.Name(arg))
+	}
+
+	encoder := json.NewEncoder(s.app.Stdout)
+	positions := make(map[string]uint64)
+	printed := 0
+	for first := true; ; first = false {
+		tables, err := s.eventTables(names)
+		if err != nil {
+			return err
+		}
+		for _, table := range tables {
+			// Tables that appear later are followed from their first event.
+			if _, ok := positions[table]; !ok && first && !*all {
+				var position uint64
+				if err := s.db.Table(table).Select("COALESCE(MAX(position), 0)").Scan(&position).Error; err != nil {
+					return err
+				}
+				positions[table] = position
+				continue
+			}
+			query := s.db.Table(table).Where("position > ?", positions[table])
+			if len(names) > 0 {
+				query = query.Where("name IN ?", names)
+			}
+			if *tenant != "" {
+				query = query.Where("tenant = ?", *tenant)
+			}
+			var rows []store.Event
+			if err := query.Order("position").Find(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				if err := s.writeEvent(encoder, row.Raw()); err != nil {
+					return err
+				}
+				positions[table] = row.Position
+				if printed++; *limit > 0 && printed >= *limit {
+					return nil
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// writeEvent prints an event as a JSON line.
+func (s *session[E, S]) writeEvent(encoder *json.Encoder, event store.RawEvent) error {
+	data, err := s.decodeEvent(event)
+	if err != nil {
+		return fmt.Errorf("event %s: %w", event.ID, err)
+	}
+	return encoder.Encode(eventLine{
+		Position:    event.Position,
+		ID:          event.ID,
+		Tenant:      event.Tenant,
+		Aggregate:   event.Name,
+		Sequence:    event.Sequence,
+		CreatedAt:   event.CreatedAt,
+		Version:     event.Version,
+		Topic:       event.Topic,
+		ContentType: event.ContentType,
+		Hash:        hex.EncodeToString(event.Hash),
+		PrevHash:    hex.EncodeToString(event.PrevHash),
+		KeyID:       event.Signature.KeyID,
+		Signed:      event.Signature.Signature != nil,
+		Data:        data,
+	})
+}
+
+// decodeEvent decodes the data of an event with the event type of its registered aggregate, or without a
+// type if the aggregate or event isn't registered.
+func (s *session[E, S]) decodeEvent(event store.RawEvent) (any, error) {
+	if prototype := s.prototype(event.Name); prototype != nil {
+		for _, prototypeEvent := range prototype.Events() {
+			if prototypeEvent.Name() == event.Topic {
+				value := clone.Clone(prototypeEvent)
+				stream := store.StreamRef{Tenant: event.Tenant, Name: event.Name}.String()
+				return value, encoders.UnmarshalStream(s.store.Encoder, stream, event.ContentType, event.Data, value)
+			}
+		}
+	}
+	return event.Tree()
+}
+
+// decodeSnapshot decodes the data of a snapshot with the snapshot type of its registered aggregate, or
+// without a type if the aggregate isn't registered.
+func (s *session[E, S]) decodeSnapshot(snapshot store.RawSnapshot) (any, error) {
+	if prototype := s.prototype(snapshot.Name); prototype != nil {
+		value := clone.Clone(prototype.TakeSnapshot())
+		stream := store.StreamRef{Tenant: snapshot.Tenant, Name: snapshot.Name}.String()
+		return value, encoders.UnmarshalStream(s.store.Encoder, stream, snapshot.ContentType, snapshot.Data, value)
+	}
+	return snapshot.Tree()
+}
+
+// eventTable returns the table holding the events of the aggregate.
+func (s *session[E, S]) eventTable(name lavender.Name) string {
+	if s.store.SharedTable {
+		return store.SharedEventTableName
+	}
+	return store.EventTableName(name)
+}
+
+// eventTables returns the existing tables holding the events of the aggregates, or of all aggregates if
+// none is given.
+func (s *session[E, S]) eventTables(names []lavender.Name) ([]string, error) {
+	tables, err := s.db.Migrator().GetTables()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(tables, func(table string) bool {
+		if s.store.SharedTable {
+			return table != store.SharedEventTableName
+		}
+		if len(names) > 0 {
+			return !slices.ContainsFunc(names, func(name lavender.Name) bool { return table == store.EventTableName(name) })
+		}
+		return !strings.HasPrefix(table, "event_")
+	}), nil
+}
+
+// count returns the number of rows of the query in the table, or 0 if the table doesn't exist.
+func (s *session[E, S]) count(table string, query *gorm.DB) (int64, error) {
+	if !s.db.Migrator().HasTable(table) {
+		return 0, nil
+	}
+	var count int64
+	err := query.Table(table).Count(&count).Error
+	return count, err
+}
+
+// state returns the lifecycle state of the stream.
+func (s *session[E, S]) state(ref store.StreamRef) (store.StreamState, error) {
+	if !s.db.Migrator().HasTable(store.StreamTableName) {
+		return store.StreamActive, nil
+	}
+	var streams []store.Stream
+	if err := whereStream(s.db, ref).Limit(1).Find(&streams).Error; err != nil {
+		return "", err
+	}
+	if len(streams) == 0 || streams[0].State == "" {
+		return store.StreamActive, nil
+	}
+	return streams[0].State, nil
+}
+
+// whereStream restricts a query to the rows of the stream.
+func whereStream(tx *gorm.DB, ref store.StreamRef) *gorm.DB {
+	return tx.Where("name = ? AND tenant = ?", ref.Name, ref.Tenant)
+}
@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+)
+
+// verify verifies the hash chains of the streams of registered aggregates.
+func (s *session[E, S]) verify(_ context.Context, args []string) error {
+	refs, err := s.registeredStreams(args, "verified")
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, ref := range refs {
+		aggregate, err := s.aggregate(ref)
+		if err != nil {
+			return err
+		}
+		if err := store.VerifyStream(s.store, aggregate); err != nil {
+			failed++
+			fmt.Fprintf(s.app.Stdout, "FAIL\t%s\t%v\n", ref, err)
+			continue
+		}
+		fmt.Fprintf(s.app.Stdout, "ok\t%s\n", ref)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d streams failed verification", failed)
+	}
+	return nil
+}
+
+// consistency compares the aggregates loaded from the latest snapshots of the streams of registered
+// aggregates with a replay of their full history and prints the fields that differ. Streams whose history
+// is incomplete are skipped.
+func (s *session[E, S]) consistency(_ context.Context, args []string) error {
+	refs, err := s.registeredStreams(args, "compared")
+	if err != nil {
+		return err
+	}
+	r := repo.NewRepositoryConstructor(false, s.store, s.store)
+	failed := 0
+	for _, ref := range refs {
+		aggregate, err := s.aggregate(ref)
+		if err != nil {
+			return err
+		}
+		divergence, err := r.VerifySnapshot(aggregate)
+		if errors.Is(err, repo.ErrHistoryIncomplete) {
+			fmt.Fprintf(s.app.Stdout, "SKIP\t%s\t%v\n", ref, err)
+			continue
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(s.app.Stdout, "FAIL\t%s\t%v\n", ref, err)
+			continue
+		}
+		if divergence != nil {
+			failed++
+			fmt.Fprintf(s.app.Stdout, "DIFF\t%s\tsnapshot %s\n", ref, divergence.Snapshot)
+			for _, field := range divergence.Fields {
+				fmt.Fprintf(s.app.Stdout, "\t%s: %v != %v\n", field.Path, field.Snapshot, field.Replay)
+			}
+			continue
+		}
+		fmt.Fprintf(s.app.Stdout, "ok\t%s\n", ref)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d streams are inconsistent", failed)
+	}
+	return nil
+}
+
+// registeredStreams parses the flags and aggregate arguments of the running command and returns the
+// streams of the selected registered aggregates, all of them if none is given.
+func (s *session[E, S]) registeredStreams(args []string, verb string) ([]store.StreamRef, error) {
+	flags := s.flags()
+	tenant := flags.String("tenant", "", "only use the streams of the tenant")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	var names []lavender.Name
+	for _, arg := range flags.Args() {
+		if s.prototype(lavender.Name(arg)) == nil {
+			return nil, fmt.Errorf("aggregate %s is not registered", arg)
+		}
+		names = append(names, lavender.Name(arg))
+	}
+	if len(names) == 0 {
+		for _, aggregate := range s.app.Aggregates {
+			names = append(names, aggregate.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no aggregate is registered, streams can only be %s by a binary that registers them", verb)
+	}
+
+	refs, err := s.store.Streams()
+	if err != nil {
+		return nil, err
+	}
+	var selected []store.StreamRef
+	for _, ref := range refs {
+		if slices.Contains(names, ref.Name) && (*tenant == "" || ref.Tenant == *tenant) {
+			selected = append(selected, ref)
+		}
+	}
+	return selected, nil
+}
+
+// snapshot takes a snapshot of the current state of a registered aggregate. The covered events stay in the
+// log, the repository only applies the ones stored after the snapshot.
+func (s *session[E, S]) snapshot(_ context.Context, args []string) error {
+	flags := s.flags()
+	tenant := flags.String("tenant", "", "tenant of the stream")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	name, err := oneArg(flags)
+	if err != nil {
+		return err
+	}
+	ref := store.StreamRef{Tenant: *tenant, Name: name}
+	aggregate, err := s.aggregate(ref)
+	if err != nil {
+		return err
+	}
+	if err := repo.NewRepositoryConstructor(false, s.store, s.store).CreateSnapshot(aggregate); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.app.Stderr, "created snapshot of %s\n", ref)
+	return nil
+}
+
+// replay rebuilds a registered aggregate from the full history of its stream and prints its state.
+func (s *session[E, S]) replay(_ context.Context, args []string) error {
+	flags := s.flags()
+	tenant := flags.String("tenant", "", "tenant of the stream")
+	at := flags.String("at", "", "replay the events stored up to the time (RFC 3339)")
+	save := flags.Bool("snapshot", false, "store the replayed state as a new snapshot")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	name, err := oneArg(flags)
+	if err != nil {
+		return err
+	}
+	var until time.Time
+	if *at != "" {
+		if until, err = time.Parse(time.RFC3339, *at); err != nil {
+			return err
+		}
+		if *save {
+			return fmt.Errorf("the state at a point in time can't be stored as snapshot")
+		}
+	}
+	ref := store.StreamRef{Tenant: *tenant, Name: name}
+	aggregate, err := s.aggregate(ref)
+	if err != nil {
+		return err
+	}
+	var head uint64
+	if *save {
+		if head, err = store.StreamSequence(s.store, aggregate); err != nil {
+			return err
+		}
+	}
+	if err := repo.NewRepositoryConstructor(false, s.store, s.store).LoadAggregateAt(aggregate, until); err != nil {
+		return err
+	}
+	snapshot := aggregate.TakeSnapshot()
+	if *save {
+		if err := s.checkReplayed(aggregate, head); err != nil {
+			return err
+		}
+		if err := s.store.SaveSnapshot(aggregate, snapshot); err != nil {
+			return err
+		}
+		fmt.Fprintf(s.app.Stderr, "created snapshot of %s\n", ref)
+	}
+	return json.NewEncoder(s.app.Stdout).Encode(snapshot)
+}
+
+// checkReplayed makes sure the replay of the aggregate covers the events up to the head the stored snapshot
+// will record, so the repository can skip them on top of the snapshot. Events that can't be loaded anymore,
+// e.g. because they have been cleared or scavenged, would be missing from the state, and events appended
+// during the replay would be skipped without having been applied.
+func (s *session[E, S]) checkReplayed(aggregate lavender.CustomAggregate[E, S], head uint64) error {
+	current, err := store.StreamSequence(s.store, aggregate)
+	if err != nil {
+		return err
+	}
+	if current != head {
+		return fmt.Errorf("events have been stored during the replay, the replayed state can't be stored as snapshot")
+	}
+	events, err := store.LoadHistory(s.store, aggregate, time.Time{})
+	if err != nil {
+		return err
+	}
+	if uint64(len(events)) != head {
+		return fmt.Errorf("the replay doesn't cover the %d events of the stream, the replayed state can't be stored as snapshot", head)
+	}
+	return nil
+}
+
+// migrate reports the migrated tables, the tables of the registered aggregates are migrated before every
+// command that writes.
+func (s *session[E, S]) migrate(_ context.Context, args []string) error {
+	flags := s.flags()
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(s.app.Aggregates) == 0 {
+		return fmt.Errorf("no aggregate is registered, tables can only be migrated by a binary that registers them")
+	}
+	for _, aggregate := range s.app.Aggregates {
+		fmt.Fprintf(s.app.Stderr, "migrated tables of %s\n", aggregate.Name())
+	}
+	return nil
+}
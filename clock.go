@@ -0,0 +1,53 @@
+package lavender
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Clock tells the time recorded for stored events and snapshots.
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator creates the unique IDs of stored events and snapshots.
+type IDGenerator interface {
+	NewID() string
+}
+
+// SystemClock is the wall clock.
+type SystemClock struct{}
+
+var _ Clock = SystemClock{}
+
+// Now implements Clock.
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// UUIDv7Generator creates time-ordered UUIDv7 IDs.
+type UUIDv7Generator struct{}
+
+var _ IDGenerator = UUIDv7Generator{}
+
+// NewID implements IDGenerator.
+func (UUIDv7Generator) NewID() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// ClockOr returns the clock, or SystemClock if it is nil.
+func ClockOr(clock Clock) Clock {
+	if clock == nil {
+		return SystemClock{}
+	}
+	return clock
+}
+
+// IDGeneratorOr returns the generator, or UUIDv7Generator if it is nil.
+func IDGeneratorOr(generator IDGenerator) IDGenerator {
+	if generator == nil {
+		return UUIDv7Generator{}
+	}
+	return generator
+}
@@ -0,0 +1,38 @@
+// Command lavender-gen generates the method sets of the annotated aggregates, events and snapshots of a
+// package, see package gen for the annotations. It is meant to be run by go generate:
+//
+//	//go:generate go run github.com/FlauschigDings/lavender/cmd/lavender-gen
+//
+// The package in the current directory is generated unless another directory is given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/FlauschigDings/lavender/gen"
+)
+
+func main() {
+	generator := gen.New()
+	flag.StringVar(&generator.Output, "output", generator.Output, "name of the generated file")
+	flag.StringVar(&generator.Registry, "registry", generator.Registry, "name of the function returning the aggregates, empty to skip it")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: lavender-gen [flags] [dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	if err := generator.Write(dir); err != nil {
+		fmt.Fprintln(os.Stderr, "lavender-gen:", err)
+		os.Exit(1)
+	}
+}
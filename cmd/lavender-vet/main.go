@@ -0,0 +1,15 @@
+// Command lavender-vet reports common mistakes in code using lavender types, see package vet. It runs
+// standalone or as vet tool:
+//
+//	lavender-vet ./...
+//	go vet -vettool=$(which lavender-vet) ./...
+package main
+
+import (
+	"github.com/FlauschigDings/lavender/vet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(vet.Analyzer)
+}
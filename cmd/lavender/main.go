@@ -0,0 +1,14 @@
+// Command lavender inspects and operates the streams of a lavender GORM store on SQLite.
+//
+//	lavender -db app.db streams
+//	lavender -db app.db events -tenant ducks account
+//	lavender -db app.db tail -interval 500ms
+//
+// It doesn't know the aggregates of an application, see package cli to build a binary that does.
+package main
+
+import "github.com/FlauschigDings/lavender/cli"
+
+func main() {
+	cli.Main()
+}
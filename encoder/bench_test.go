@@ -0,0 +1,80 @@
+package encoder_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/google/uuid"
+)
+
+type benchUser struct {
+	Id        uuid.UUID
+	Email     string
+	Password  string
+	Logins    int
+	CreatedAt time.Time
+}
+
+type benchSnapshot struct {
+	Users []benchUser
+}
+
+func newBenchSnapshot(users int) *benchSnapshot {
+	snapshot := &benchSnapshot{}
+	for i := 0; i < users; i++ {
+		snapshot.Users = append(snapshot.Users, benchUser{
+			Id:        uuid.New(),
+			Email:     fmt.Sprintf("user%d@ducky.com", i),
+			Password:  "iL0v3Duc7s",
+			Logins:    i,
+			CreatedAt: time.Now(),
+		})
+	}
+	return snapshot
+}
+
+var benchEncoders = map[string]encoders.Encoder{
+	"cbor": encoders.NewCBorEncoder(),
+	"json": encoders.NewJsonEncoder(),
+	"gob":  encoders.NewGobEncoder(),
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	for _, users := range []int{1, 1000} {
+		value := newBenchSnapshot(users)
+		for name, encoder := range benchEncoders {
+			b.Run(fmt.Sprintf("%s/%d", name, users), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := encoder.Marshal(value); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	for _, users := range []int{1, 1000} {
+		value := newBenchSnapshot(users)
+		for name, encoder := range benchEncoders {
+			data, err := encoder.Marshal(value)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(fmt.Sprintf("%s/%d", name, users), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(data)))
+				for i := 0; i < b.N; i++ {
+					var decoded benchSnapshot
+					if err := encoder.Unmarshal(data, &decoded); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
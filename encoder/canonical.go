@@ -0,0 +1,22 @@
+package encoder
+
+import "github.com/fxamacker/cbor"
+
+// CanonicalEncoder is a CBOR encoder that always produces the same bytes for the same value.
+// It uses the "Core Deterministic" encoding: map keys and struct fields are sorted, integers,
+// lengths and floats use their shortest form, and times are encoded as UTC seconds rounded to
+// microseconds. Its output is plain CBOR and can be hashed, deduplicated and signed.
+type CanonicalEncoder struct {
+	CBorEncoder
+}
+
+// CanonicalEncoder implements the Encoder interface with a deterministic output.
+var _ Encoder = new(CanonicalEncoder)
+var _ ContentTyper = new(CanonicalEncoder)
+
+// NewCanonicalEncoder creates and returns a new instance of CanonicalEncoder.
+func NewCanonicalEncoder() *CanonicalEncoder {
+	return &CanonicalEncoder{
+		CBorEncoder: *NewCBorCustomEncoder(cbor.CoreDetEncOptions()),
+	}
+}
@@ -0,0 +1,93 @@
+package encoder_test
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type canonicalPayload struct {
+	Name      string
+	Count     int
+	Ratio     float64
+	CreatedAt time.Time
+	Tags      map[string]int
+}
+
+func TestCanonicalEncoderStable(t *testing.T) {
+	encoder := encoders.NewCanonicalEncoder()
+	createdAt := time.Date(2025, 2, 1, 12, 30, 0, 0, time.UTC)
+
+	first, err := encoder.Marshal(canonicalPayload{
+		Name:      "duck",
+		Count:     1,
+		Ratio:     1.5,
+		CreatedAt: createdAt,
+		Tags:      map[string]int{"a": 1, "bb": 2, "c": 3, "dd": 4, "e": 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		// Same values built in another order and with another time zone.
+		tags := make(map[string]int)
+		for _, key := range []string{"dd", "e", "c", "bb", "a"} {
+			tags[key] = map[string]int{"a": 1, "bb": 2, "c": 3, "dd": 4, "e": 5}[key]
+		}
+		data, err := encoder.Marshal(canonicalPayload{
+			Name:      "duck",
+			Count:     1,
+			Ratio:     1.5,
+			CreatedAt: createdAt.In(time.FixedZone("CET", 3600)),
+			Tags:      tags,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, first, data)
+	}
+
+	// The encoding must not change between releases, otherwise stored hashes break.
+	data, err := encoder.Marshal(map[string]any{"b": 1.5, "a": uint64(1), "c": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "a36161016162f93e0061636178", hex.EncodeToString(data))
+}
+
+func TestCanonicalEncoderRoundTrip(t *testing.T) {
+	encoder := encoders.NewCanonicalEncoder()
+	assert.Equal(t, encoders.ContentTypeCBOR, encoders.ContentTypeOf(encoder))
+
+	value := typedPayload{
+		Id:        uuid.New(),
+		Count:     42,
+		Ratio:     0.25,
+		CreatedAt: time.Date(2025, 2, 1, 12, 30, 0, int(500*time.Millisecond), time.UTC),
+		Tags:      map[string]uint8{"a": 1},
+	}
+	data, err := encoder.Marshal(&value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded typedPayload
+	assert.NoError(t, encoder.Unmarshal(data, &decoded))
+	assert.Equal(t, value.Id, decoded.Id)
+	assert.Equal(t, value.Count, decoded.Count)
+	assert.Equal(t, value.Ratio, decoded.Ratio)
+	assert.True(t, value.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, value.Tags, decoded.Tags)
+
+	// Decoding the canonical data and encoding it again gives the same bytes.
+	again, err := encoder.Marshal(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data, again)
+}
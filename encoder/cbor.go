@@ -42,3 +42,8 @@ func (g *CBorEncoder) Marshal(value any) ([]byte, error) {
 func (g *CBorEncoder) Unmarshal(data []byte, value any) error {
 	return cbor.Unmarshal(data, value)
 }
+
+// ContentType implements ContentTyper.
+func (g *CBorEncoder) ContentType() string {
+	return ContentTypeCBOR
+}
@@ -0,0 +1,241 @@
+package encoder
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Header bytes written in front of compressed payloads. Custom compressors must use an ID in the ranges
+// 0x9C-0x9E, 0xBC-0xBE or 0xDC-0xDE: CBOR reserves those bytes, they aren't ASCII and no gob length prefix
+// uses them, so they never start an uncompressed CBOR, JSON or Gob payload.
+const (
+	CompressionGzip  byte = 0x9C
+	CompressionFlate byte = 0x9D
+)
+
+// Compressor compresses and decompresses payloads produced by an inner encoder.
+type Compressor interface {
+	// ID returns the header byte that marks payloads compressed by this compressor.
+	ID() byte
+	// Compress compresses the data.
+	Compress(data []byte) ([]byte, error)
+	// Decompress decompresses data produced by Compress.
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipCompressor compresses payloads with gzip.
+type GzipCompressor struct {
+	Level int
+}
+
+var _ Compressor = new(GzipCompressor)
+
+// NewGzipCompressor creates a GzipCompressor with the given compression level (e.g. gzip.DefaultCompression).
+func NewGzipCompressor(level int) *GzipCompressor {
+	return &GzipCompressor{Level: level}
+}
+
+// ID implements Compressor.
+func (c *GzipCompressor) ID() byte {
+	return CompressionGzip
+}
+
+// Compress implements Compressor.
+func (c *GzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, c.Level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress implements Compressor.
+func (c *GzipCompressor) Decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// FlateCompressor compresses payloads with raw deflate.
+type FlateCompressor struct {
+	Level int
+}
+
+var _ Compressor = new(FlateCompressor)
+
+// NewFlateCompressor creates a FlateCompressor with the given compression level (e.g. flate.DefaultCompression).
+func NewFlateCompressor(level int) *FlateCompressor {
+	return &FlateCompressor{Level: level}
+}
+
+// ID implements Compressor.
+func (c *FlateCompressor) ID() byte {
+	return CompressionFlate
+}
+
+// Compress implements Compressor.
+func (c *FlateCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := flate.NewWriter(&buf, c.Level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress implements Compressor.
+func (c *FlateCompressor) Decompress(data []byte) ([]byte, error) {
+	reader := flate.NewReader(bytes.NewReader(data))
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// CompressionEncoder decorates an encoder and compresses its output. Payloads smaller than the threshold
+// are stored raw without a header, payloads without a known header are decoded as uncompressed data.
+type CompressionEncoder struct {
+	Inner       Encoder
+	Compressor  Compressor
+	Threshold   int
+	compressors map[byte]Compressor
+}
+
+// CompressionEncoder implements the Encoder interface and keeps the content type of the inner encoder.
+var _ Encoder = new(CompressionEncoder)
+var _ ContentTyper = new(CompressionEncoder)
+var _ ContentTypeDecoder = new(CompressionEncoder)
+var _ StreamEncoder = new(CompressionEncoder)
+var _ TypeRegisterer = new(CompressionEncoder)
+
+// NewCompressionEncoder creates a CompressionEncoder that gzips every payload of at least 1 KiB.
+func NewCompressionEncoder(inner Encoder) *CompressionEncoder {
+	return NewCompressionCustomEncoder(inner, NewGzipCompressor(gzip.DefaultCompression), 1024)
+}
+
+// NewCompressionCustomEncoder creates a CompressionEncoder with a custom compressor and threshold.
+// Gzip and flate payloads can always be decoded, other compressors can be added with Register.
+func NewCompressionCustomEncoder(inner Encoder, compressor Compressor, threshold int) *CompressionEncoder {
+	encoder := &CompressionEncoder{
+		Inner:       inner,
+		Compressor:  compressor,
+		Threshold:   threshold,
+		compressors: make(map[byte]Compressor),
+	}
+	encoder.Register(NewGzipCompressor(gzip.DefaultCompression), NewFlateCompressor(flate.DefaultCompression), compressor)
+	return encoder
+}
+
+// Register adds compressors that can be used to decompress payloads.
+func (c *CompressionEncoder) Register(compressors ...Compressor) *CompressionEncoder {
+	for _, compressor := range compressors {
+		c.compressors[compressor.ID()] = compressor
+	}
+	return c
+}
+
+// RegisterTypes implements TypeRegisterer and forwards the types to the wrapped encoders.
+func (c *CompressionEncoder) RegisterTypes(values ...any) {
+	RegisterTypes(c.Inner, values...)
+}
+
+// ContentType implements ContentTyper and returns the content type of the inner encoder.
+func (c *CompressionEncoder) ContentType() string {
+	return ContentTypeOf(c.Inner)
+}
+
+// Marshal implements Encoder. It encodes the value with the inner encoder and compresses the result
+// if it reaches the threshold.
+func (c *CompressionEncoder) Marshal(value any) ([]byte, error) {
+	data, err := c.Inner.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return c.compress(data)
+}
+
+// Unmarshal implements Encoder. It decompresses the data if needed and decodes it with the inner encoder.
+func (c *CompressionEncoder) Unmarshal(data []byte, value any) error {
+	data, err := c.decompress(data)
+	if err != nil {
+		return err
+	}
+	return c.Inner.Unmarshal(data, value)
+}
+
+// UnmarshalContentType implements ContentTypeDecoder.
+func (c *CompressionEncoder) UnmarshalContentType(contentType string, data []byte, value any) error {
+	data, err := c.decompress(data)
+	if err != nil {
+		return err
+	}
+	return UnmarshalContentType(c.Inner, contentType, data, value)
+}
+
+// MarshalStream implements StreamEncoder.
+func (c *CompressionEncoder) MarshalStream(stream string, value any) ([]byte, error) {
+	data, err := MarshalStream(c.Inner, stream, value)
+	if err != nil {
+		return nil, err
+	}
+	return c.compress(data)
+}
+
+// UnmarshalStream implements StreamEncoder.
+func (c *CompressionEncoder) UnmarshalStream(stream string, contentType string, data []byte, value any) error {
+	data, err := c.decompress(data)
+	if err != nil {
+		return err
+	}
+	return UnmarshalStream(c.Inner, stream, contentType, data, value)
+}
+
+// compress compresses the data and prefixes it with the compressor ID.
+func (c *CompressionEncoder) compress(data []byte) ([]byte, error) {
+	if len(data) < c.Threshold {
+		return data, nil
+	}
+	compressed, err := c.Compressor.Compress(data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{c.Compressor.ID()}, compressed...), nil
+}
+
+// decompress strips the header and decompresses the data. Data without a header is returned unchanged.
+func (c *CompressionEncoder) decompress(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	compressor, ok := c.compressors[data[0]]
+	if !ok {
+		if compressionID(data[0]) {
+			return nil, fmt.Errorf("unknown compression %#x", data[0])
+		}
+		return data, nil
+	}
+	return compressor.Decompress(data[1:])
+}
+
+// compressionID reports if the header byte is in the ranges reserved for compressors.
+func compressionID(header byte) bool {
+	info := header & 0x1F
+	return header >= 0x80 && header < 0xE0 && info >= 0x1C && info <= 0x1E
+}
@@ -0,0 +1,86 @@
+package encoder_test
+
+import (
+	"compress/flate"
+	"reflect"
+	"strings"
+	"testing"
+
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/stretchr/testify/assert"
+)
+
+type payload struct {
+	Name  string
+	Items []string
+}
+
+func TestCompressionEncoder(t *testing.T) {
+	large := payload{Name: "large", Items: []string{strings.Repeat("lavender", 1024)}}
+	small := payload{Name: "small"}
+
+	for _, compressor := range []encoders.Compressor{
+		encoders.NewGzipCompressor(flate.BestCompression),
+		encoders.NewFlateCompressor(flate.BestSpeed),
+	} {
+		inner := encoders.NewCBorEncoder()
+		encoder := encoders.NewCompressionCustomEncoder(inner, compressor, 64)
+		assert.Equal(t, encoders.ContentTypeCBOR, encoders.ContentTypeOf(encoder))
+
+		t.Run("compressed", func(t *testing.T) {
+			data, err := encoder.Marshal(large)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, compressor.ID(), data[0])
+			assert.Less(t, len(data), len(large.Items[0]))
+
+			var decoded payload
+			assert.NoError(t, encoder.Unmarshal(data, &decoded))
+			assert.Equal(t, large, decoded)
+		})
+
+		t.Run("below threshold", func(t *testing.T) {
+			data, err := encoder.Marshal(small)
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw, _ := inner.Marshal(small)
+			assert.Equal(t, raw, data)
+		})
+
+		t.Run("legacy", func(t *testing.T) {
+			raw, _ := inner.Marshal(large)
+			var decoded payload
+			assert.NoError(t, encoder.Unmarshal(raw, &decoded))
+			assert.Equal(t, large, decoded)
+		})
+	}
+}
+
+func TestCompressionHeaders(t *testing.T) {
+	encoder := encoders.NewCompressionCustomEncoder(encoders.NewCBorEncoder(), encoders.NewGzipCompressor(flate.BestSpeed), 0)
+
+	// CBOR null, true and false are single bytes next to the compressor IDs.
+	t.Run("scalars", func(t *testing.T) {
+		var empty *payload
+		for _, value := range []any{empty, true, false} {
+			raw, _ := encoders.NewCBorEncoder().Marshal(value)
+			decoded := reflect.New(reflect.TypeOf(value))
+			assert.NoError(t, encoder.Unmarshal(raw, decoded.Interface()))
+			assert.Equal(t, value, decoded.Elem().Interface())
+
+			data, err := encoder.Marshal(value)
+			if assert.NoError(t, err) {
+				decoded := reflect.New(reflect.TypeOf(value))
+				assert.NoError(t, encoder.Unmarshal(data, decoded.Interface()))
+				assert.Equal(t, value, decoded.Elem().Interface())
+			}
+		}
+	})
+
+	t.Run("unknown header", func(t *testing.T) {
+		var decoded payload
+		assert.ErrorContains(t, encoder.Unmarshal([]byte{0x9E, 0x00}, &decoded), "unknown compression")
+	})
+}
@@ -0,0 +1,38 @@
+package encoder
+
+// Content types of the built-in encoders. They are stored next to every encoded payload so a
+// MultiEncoder can pick the matching decoder per row.
+const (
+	ContentTypeCBOR = "application/cbor"
+	ContentTypeJSON = "application/json"
+	ContentTypeGob  = "application/x-gob"
+)
+
+// ContentTyper is implemented by encoders that can name the format of the data they produce.
+type ContentTyper interface {
+	// ContentType returns the content type of the data produced by Marshal.
+	ContentType() string
+}
+
+// ContentTypeDecoder is implemented by encoders that can decode data of several content types.
+type ContentTypeDecoder interface {
+	// UnmarshalContentType decodes data that has been produced with the given content type.
+	UnmarshalContentType(contentType string, data []byte, value any) error
+}
+
+// ContentTypeOf returns the content type of the encoder or an empty string if it is unknown.
+func ContentTypeOf(encoder Encoder) string {
+	if typer, ok := encoder.(ContentTyper); ok {
+		return typer.ContentType()
+	}
+	return ""
+}
+
+// UnmarshalContentType decodes data with the decoder matching the content type if the encoder supports it,
+// otherwise it falls back to the plain Unmarshal of the encoder.
+func UnmarshalContentType(encoder Encoder, contentType string, data []byte, value any) error {
+	if decoder, ok := encoder.(ContentTypeDecoder); ok && contentType != "" {
+		return decoder.UnmarshalContentType(contentType, data, value)
+	}
+	return encoder.Unmarshal(data, value)
+}
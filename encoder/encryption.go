@@ -0,0 +1,209 @@
+package encoder
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrForgotten is returned when a payload can't be decrypted because the key of its stream has been deleted,
+// and when a payload is encrypted for a stream whose key has been deleted.
+var ErrForgotten = errors.New("stream key has been forgotten")
+
+// ErrStreamRequired is returned when an EncryptionEncoder is used without a stream.
+var ErrStreamRequired = errors.New("encryption requires a stream, use MarshalStream and UnmarshalStream")
+
+// KeyStore manages the encryption keys of the streams.
+type KeyStore interface {
+	// Key returns the key of the stream and creates a new one if the stream has none yet. It returns
+	// ErrForgotten if the key of the stream has been deleted.
+	Key(stream string) ([]byte, error)
+	// LookupKey returns the key of the stream or ErrForgotten if the stream has no key.
+	LookupKey(stream string) ([]byte, error)
+	// DeleteKey deletes the key of the stream, which makes all of its payloads unreadable. The deletion is
+	// remembered, the stream never gets a new key.
+	DeleteKey(stream string) error
+}
+
+// NewKey creates a random AES-256 key.
+func NewKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// InMemoryKeyStore is a thread-safe KeyStore that keeps the keys in memory.
+type InMemoryKeyStore struct {
+	mu        sync.Mutex
+	keys      map[string][]byte
+	forgotten map[string]bool
+}
+
+var _ KeyStore = new(InMemoryKeyStore)
+
+// NewInMemoryKeyStore creates an empty InMemoryKeyStore.
+func NewInMemoryKeyStore() *InMemoryKeyStore {
+	return &InMemoryKeyStore{
+		keys:      make(map[string][]byte),
+		forgotten: make(map[string]bool),
+	}
+}
+
+// Key implements KeyStore.
+func (k *InMemoryKeyStore) Key(stream string) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, ok := k.keys[stream]; ok {
+		return key, nil
+	}
+	if k.forgotten[stream] {
+		return nil, ErrForgotten
+	}
+	key, err := NewKey()
+	if err != nil {
+		return nil, err
+	}
+	k.keys[stream] = key
+	return key, nil
+}
+
+// LookupKey implements KeyStore.
+func (k *InMemoryKeyStore) LookupKey(stream string) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys[stream]
+	if !ok {
+		return nil, ErrForgotten
+	}
+	return key, nil
+}
+
+// DeleteKey implements KeyStore.
+func (k *InMemoryKeyStore) DeleteKey(stream string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.keys, stream)
+	k.forgotten[stream] = true
+	return nil
+}
+
+// EncryptionEncoder decorates an encoder and encrypts its output with AES-GCM using a key per stream.
+// The stream name is authenticated as additional data, so payloads can't be moved between streams.
+// Deleting the key of a stream from the KeyStore crypto-shreds all of its payloads. Every payload starts
+// with the ID of its key, so payloads of another key of the stream are reported as forgotten as well.
+//
+// Combine it with compression as EncryptionEncoder(CompressionEncoder(...)), encrypted data doesn't compress.
+type EncryptionEncoder struct {
+	Inner    Encoder
+	KeyStore KeyStore
+}
+
+// EncryptionEncoder implements the Encoder interface and keeps the content type of the inner encoder.
+var _ Encoder = new(EncryptionEncoder)
+var _ ContentTyper = new(EncryptionEncoder)
+var _ StreamEncoder = new(EncryptionEncoder)
+var _ TypeRegisterer = new(EncryptionEncoder)
+
+// NewEncryptionEncoder creates an EncryptionEncoder that encrypts the output of inner with keys of the key store.
+func NewEncryptionEncoder(inner Encoder, keyStore KeyStore) *EncryptionEncoder {
+	return &EncryptionEncoder{
+		Inner:    inner,
+		KeyStore: keyStore,
+	}
+}
+
+// RegisterTypes implements TypeRegisterer and forwards the types to the wrapped encoders.
+func (e *EncryptionEncoder) RegisterTypes(values ...any) {
+	RegisterTypes(e.Inner, values...)
+}
+
+// ContentType implements ContentTyper and returns the content type of the inner encoder.
+func (e *EncryptionEncoder) ContentType() string {
+	return ContentTypeOf(e.Inner)
+}
+
+// Marshal implements Encoder. It always fails with ErrStreamRequired since the key depends on the stream.
+func (e *EncryptionEncoder) Marshal(value any) ([]byte, error) {
+	return nil, ErrStreamRequired
+}
+
+// Unmarshal implements Encoder. It always fails with ErrStreamRequired since the key depends on the stream.
+func (e *EncryptionEncoder) Unmarshal(data []byte, value any) error {
+	return ErrStreamRequired
+}
+
+// MarshalStream implements StreamEncoder. It encodes the value with the inner encoder and seals the result
+// with the key of the stream. The output is the key ID, followed by the nonce and the ciphertext.
+func (e *EncryptionEncoder) MarshalStream(stream string, value any) ([]byte, error) {
+	data, err := MarshalStream(e.Inner, stream, value)
+	if err != nil {
+		return nil, err
+	}
+	key, err := e.KeyStore.Key(stream)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, keyIDSize+aead.NonceSize(), keyIDSize+aead.NonceSize()+len(data)+aead.Overhead())
+	copy(out, keyID(key))
+	nonce := out[keyIDSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, data, []byte(stream)), nil
+}
+
+// UnmarshalStream implements StreamEncoder. It opens the data with the key of the stream and decodes it with
+// the inner encoder. It returns ErrForgotten if the key of the stream has been deleted or isn't the key the
+// data has been encrypted with.
+func (e *EncryptionEncoder) UnmarshalStream(stream string, contentType string, data []byte, value any) error {
+	key, err := e.KeyStore.LookupKey(stream)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	if len(data) < keyIDSize+aead.NonceSize() {
+		return fmt.Errorf("encrypted payload of stream %s is too short", stream)
+	}
+	if !bytes.Equal(data[:keyIDSize], keyID(key)) {
+		return fmt.Errorf("payload of stream %s has been encrypted with another key: %w", stream, ErrForgotten)
+	}
+	data = data[keyIDSize:]
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(stream))
+	if err != nil {
+		return fmt.Errorf("decrypt payload of stream %s: %w", stream, err)
+	}
+	return UnmarshalStream(e.Inner, stream, contentType, plaintext, value)
+}
+
+// keyIDSize is the size of the key ID in front of the encrypted payloads.
+const keyIDSize = 8
+
+// keyID identifies a key by a truncated hash, which doesn't reveal the key.
+func keyID(key []byte) []byte {
+	sum := sha256.Sum256(key)
+	return sum[:keyIDSize]
+}
+
+// newAEAD creates an AES-GCM cipher for the key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,65 @@
+package encoder_test
+
+import (
+	"errors"
+	"testing"
+
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptionEncoder(t *testing.T) {
+	keyStore := encoders.NewInMemoryKeyStore()
+	encoder := encoders.NewEncryptionEncoder(encoders.NewJsonEncoder(), keyStore)
+	value := payload{Name: "secret", Items: []string{"duck@ducky.com"}}
+
+	data, err := encoders.MarshalStream(encoder, "account-1", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, string(data), "duck@ducky.com")
+
+	var decoded payload
+	assert.NoError(t, encoders.UnmarshalStream(encoder, "account-1", encoders.ContentTypeJSON, data, &decoded))
+	assert.Equal(t, value, decoded)
+
+	// Payloads are bound to their stream.
+	_, _ = keyStore.Key("account-2")
+	assert.Error(t, encoders.UnmarshalStream(encoder, "account-2", encoders.ContentTypeJSON, data, &decoded))
+
+	// Without a stream the encoder can't pick a key.
+	_, err = encoder.Marshal(value)
+	assert.ErrorIs(t, err, encoders.ErrStreamRequired)
+
+	assert.NoError(t, keyStore.DeleteKey("account-1"))
+	err = encoders.UnmarshalStream(encoder, "account-1", encoders.ContentTypeJSON, data, &decoded)
+	assert.True(t, errors.Is(err, encoders.ErrForgotten))
+
+	// Shredded streams don't get a new key.
+	_, err = encoders.MarshalStream(encoder, "account-1", value)
+	assert.ErrorIs(t, err, encoders.ErrForgotten)
+
+	// Payloads of another key of the stream are forgotten as well.
+	other := encoders.NewEncryptionEncoder(encoders.NewJsonEncoder(), encoders.NewInMemoryKeyStore())
+	data, err = encoders.MarshalStream(other, "account-3", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = keyStore.Key("account-3")
+	err = encoders.UnmarshalStream(encoder, "account-3", encoders.ContentTypeJSON, data, &decoded)
+	assert.ErrorIs(t, err, encoders.ErrForgotten)
+}
+
+func TestEncryptionCompression(t *testing.T) {
+	inner := encoders.NewCompressionCustomEncoder(encoders.NewCBorEncoder(), encoders.NewGzipCompressor(9), 0)
+	encoder := encoders.NewMultiEncoder(encoders.NewEncryptionEncoder(inner, encoders.NewInMemoryKeyStore()))
+	value := payload{Name: "secret"}
+
+	data, err := encoders.MarshalStream(encoder, "account", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded payload
+	assert.NoError(t, encoders.UnmarshalStream(encoder, "account", encoders.ContentTypeOf(encoder), data, &decoded))
+	assert.Equal(t, value, decoded)
+}
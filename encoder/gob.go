@@ -7,60 +7,66 @@ import (
 )
 
 // GobEncoder is a concrete implementation of the Encoder interface that uses
-// the Gob encoding format for serializing and deserializing data.
+// the Gob encoding format for serializing and deserializing data. Values are encoded
+// with their Go types, so integers, times and UUIDs keep their exact type.
 type GobEncoder struct {
 }
 
 // GobEncoder implements the Encoder interface by providing methods
 // for marshaling and unmarshaling data in the Gob format.
 var _ Encoder = new(GobEncoder)
+var _ TypeRegisterer = new(GobEncoder)
 
 // NewGobEncoder creates and returns a new instance of GobEncoder.
 func NewGobEncoder() *GobEncoder {
 	return &GobEncoder{}
 }
 
+// RegisterTypes implements TypeRegisterer. It registers the concrete types of the values with gob,
+// which is required when they are stored in interface typed fields.
+func (g *GobEncoder) RegisterTypes(values ...any) {
+	for _, value := range values {
+		gob.Register(value)
+	}
+}
+
 // Marshal implements Encoder. It converts a Go value into a byte slice using the Gob encoding format.
 func (g *GobEncoder) Marshal(value any) ([]byte, error) {
 	var buf bytes.Buffer
 	enc := gob.NewEncoder(&buf)
-	data, err := structToMap(value)
-	if err != nil {
-		return nil, err
-	}
-	if err := enc.Encode(data); err != nil {
+	if err := enc.Encode(value); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
 // Unmarshal implements Encoder. It decodes a byte slice into a Go value using the Gob encoding format.
+// Data written by older versions, which gob encoded a map[string]interface{}, is still decoded.
 func (g *GobEncoder) Unmarshal(data []byte, value any) error {
-	buf := bytes.NewBuffer(data)
-	dec := gob.NewDecoder(buf)
-	ret := make(map[string]interface{})
-	if err := dec.Decode(&ret); err != nil {
-		return err
+	dec := gob.NewDecoder(bytes.NewBuffer(data))
+	err := dec.Decode(value)
+	if err == nil {
+		return nil
 	}
-	return mapToStruct(ret, value)
+	if legacyErr := unmarshalLegacyGob(data, value); legacyErr == nil {
+		return nil
+	}
+	return err
 }
 
-// structToMap converts a Go struct to a map[string]interface{} using JSON encoding/decoding.
-func structToMap(s interface{}) (map[string]interface{}, error) {
-	// Marshal struct to JSON
-	bytes, err := json.Marshal(s)
-	if err != nil {
-		return nil, err
-	}
+// ContentType implements ContentTyper.
+func (g *GobEncoder) ContentType() string {
+	return ContentTypeGob
+}
 
-	// Unmarshal JSON to map
-	var result map[string]interface{}
-	err = json.Unmarshal(bytes, &result)
-	if err != nil {
-		return nil, err
+// unmarshalLegacyGob decodes data that has been written as a gob encoded map[string]interface{}.
+func unmarshalLegacyGob(data []byte, value any) error {
+	dec := gob.NewDecoder(bytes.NewBuffer(data))
+	ret := make(map[string]interface{})
+	if err := dec.Decode(&ret); err != nil {
+		return err
 	}
-
-	return result, nil
+	return mapToStruct(ret, value)
 }
 
 // mapToStruct converts a map[string]interface{} back into a Go struct using JSON encoding/decoding.
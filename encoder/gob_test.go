@@ -0,0 +1,61 @@
+package encoder_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type typedPayload struct {
+	Id        uuid.UUID
+	Count     int64
+	Ratio     float32
+	CreatedAt time.Time
+	Tags      map[string]uint8
+	Extra     any
+}
+
+type extraPayload struct {
+	Note string
+}
+
+func TestGobEncoderTypes(t *testing.T) {
+	encoder := encoders.NewGobEncoder()
+	encoder.RegisterTypes(&extraPayload{})
+
+	value := typedPayload{
+		Id:        uuid.New(),
+		Count:     1<<62 + 1,
+		Ratio:     0.5,
+		CreatedAt: time.Date(2025, 2, 1, 12, 30, 0, 42, time.UTC),
+		Tags:      map[string]uint8{"a": 1},
+		Extra:     &extraPayload{Note: "interface"},
+	}
+	data, err := encoder.Marshal(&value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded typedPayload
+	assert.NoError(t, encoder.Unmarshal(data, &decoded))
+	assert.Equal(t, value, decoded)
+}
+
+func TestGobEncoderLegacy(t *testing.T) {
+	// Older versions gob encoded the JSON representation of the value as a map.
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(map[string]interface{}{
+		"Name": "legacy",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded payload
+	assert.NoError(t, encoders.NewGobEncoder().Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, payload{Name: "legacy"}, decoded)
+}
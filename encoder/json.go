@@ -22,3 +22,8 @@ func (j *JsonEncoder) Marshal(value any) ([]byte, error) {
 func (j *JsonEncoder) Unmarshal(data []byte, v any) error {
 	return json.Unmarshal(data, v)
 }
+
+// ContentType implements ContentTyper.
+func (j *JsonEncoder) ContentType() string {
+	return ContentTypeJSON
+}
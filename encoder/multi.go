@@ -0,0 +1,99 @@
+package encoder
+
+import "fmt"
+
+// MultiEncoder encodes with a default encoder and decodes with the encoder matching the stored content type.
+// It allows switching the encoder of a store without rewriting the already stored data.
+type MultiEncoder struct {
+	Default  Encoder
+	decoders map[string]Encoder
+}
+
+// MultiEncoder implements the Encoder interface and decodes per content type.
+var _ Encoder = new(MultiEncoder)
+var _ ContentTyper = new(MultiEncoder)
+var _ ContentTypeDecoder = new(MultiEncoder)
+var _ StreamEncoder = new(MultiEncoder)
+var _ TypeRegisterer = new(MultiEncoder)
+
+// NewMultiEncoder creates a MultiEncoder that encodes with the default encoder. The built-in CBOR, JSON and Gob
+// encoders are always available for decoding, additional decoders can be passed and override them by content type.
+func NewMultiEncoder(defaultEncoder Encoder, decoders ...Encoder) *MultiEncoder {
+	multi := &MultiEncoder{
+		Default:  defaultEncoder,
+		decoders: make(map[string]Encoder),
+	}
+	multi.Register(NewCBorEncoder(), NewJsonEncoder(), NewGobEncoder())
+	multi.Register(decoders...)
+	multi.Register(defaultEncoder)
+	return multi
+}
+
+// Register adds decoders for their content types. Encoders without a content type are ignored.
+func (m *MultiEncoder) Register(decoders ...Encoder) *MultiEncoder {
+	for _, decoder := range decoders {
+		if contentType := ContentTypeOf(decoder); contentType != "" {
+			m.decoders[contentType] = decoder
+		}
+	}
+	return m
+}
+
+// RegisterTypes implements TypeRegisterer and forwards the types to the wrapped encoders.
+func (m *MultiEncoder) RegisterTypes(values ...any) {
+	RegisterTypes(m.Default, values...)
+	for _, decoder := range m.decoders {
+		RegisterTypes(decoder, values...)
+	}
+}
+
+// ContentType implements ContentTyper and returns the content type of the default encoder.
+func (m *MultiEncoder) ContentType() string {
+	return ContentTypeOf(m.Default)
+}
+
+// Marshal implements Encoder using the default encoder.
+func (m *MultiEncoder) Marshal(value any) ([]byte, error) {
+	return m.Default.Marshal(value)
+}
+
+// Unmarshal implements Encoder using the default encoder.
+func (m *MultiEncoder) Unmarshal(data []byte, value any) error {
+	return m.Default.Unmarshal(data, value)
+}
+
+// UnmarshalContentType implements ContentTypeDecoder. It decodes the data with the encoder registered for the
+// content type.
+func (m *MultiEncoder) UnmarshalContentType(contentType string, data []byte, value any) error {
+	decoder, err := m.decoder(contentType)
+	if err != nil {
+		return err
+	}
+	return UnmarshalContentType(decoder, contentType, data, value)
+}
+
+// MarshalStream implements StreamEncoder using the default encoder.
+func (m *MultiEncoder) MarshalStream(stream string, value any) ([]byte, error) {
+	return MarshalStream(m.Default, stream, value)
+}
+
+// UnmarshalStream implements StreamEncoder with the encoder registered for the content type.
+func (m *MultiEncoder) UnmarshalStream(stream string, contentType string, data []byte, value any) error {
+	decoder, err := m.decoder(contentType)
+	if err != nil {
+		return err
+	}
+	return UnmarshalStream(decoder, stream, contentType, data, value)
+}
+
+// decoder returns the encoder registered for the content type. An empty content type selects the default encoder.
+func (m *MultiEncoder) decoder(contentType string) (Encoder, error) {
+	if contentType == "" {
+		return m.Default, nil
+	}
+	decoder, ok := m.decoders[contentType]
+	if !ok {
+		return nil, fmt.Errorf("no decoder for content type %s", contentType)
+	}
+	return decoder, nil
+}
@@ -0,0 +1,15 @@
+package encoder
+
+// TypeRegisterer is implemented by encoders that need to know the concrete types they encode,
+// e.g. to decode values stored in interface typed fields.
+type TypeRegisterer interface {
+	// RegisterTypes registers the concrete types of the values.
+	RegisterTypes(values ...any)
+}
+
+// RegisterTypes registers the concrete types of the values if the encoder supports it.
+func RegisterTypes(encoder Encoder, values ...any) {
+	if registerer, ok := encoder.(TypeRegisterer); ok {
+		registerer.RegisterTypes(values...)
+	}
+}
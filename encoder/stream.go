@@ -0,0 +1,28 @@
+package encoder
+
+// StreamEncoder is implemented by encoders whose output depends on the stream a value belongs to
+// (e.g. encryption with a key per stream).
+type StreamEncoder interface {
+	// MarshalStream encodes a value that belongs to the given stream.
+	MarshalStream(stream string, value any) ([]byte, error)
+	// UnmarshalStream decodes data of the given stream that has been produced with the given content type.
+	UnmarshalStream(stream string, contentType string, data []byte, value any) error
+}
+
+// MarshalStream encodes a value of a stream with the stream aware encoding if the encoder supports it,
+// otherwise it falls back to the plain Marshal of the encoder.
+func MarshalStream(encoder Encoder, stream string, value any) ([]byte, error) {
+	if streamEncoder, ok := encoder.(StreamEncoder); ok {
+		return streamEncoder.MarshalStream(stream, value)
+	}
+	return encoder.Marshal(value)
+}
+
+// UnmarshalStream decodes data of a stream with the stream aware decoding if the encoder supports it,
+// otherwise it falls back to UnmarshalContentType.
+func UnmarshalStream(encoder Encoder, stream string, contentType string, data []byte, value any) error {
+	if streamEncoder, ok := encoder.(StreamEncoder); ok {
+		return streamEncoder.UnmarshalStream(stream, contentType, data, value)
+	}
+	return UnmarshalContentType(encoder, contentType, data, value)
+}
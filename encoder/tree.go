@@ -0,0 +1,97 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Keys annotating the maps of a decoded tree. They can't collide with the fields of decoded structs.
+const (
+	// TypeKey holds the Go type name of a gob struct or of a value encoded by its own marshaler,
+	// or the registered name of a value stored in an interface.
+	TypeKey = "@type"
+	// BinaryKey holds the data of a gob value encoded by its GobEncode or MarshalBinary method.
+	BinaryKey = "@binary"
+	// TextKey holds the data of a gob value encoded by its MarshalText method.
+	TextKey = "@text"
+)
+
+// maxTreeDepth limits the nesting of decoded trees, so corrupted payloads can't exhaust the stack.
+const maxTreeDepth = 1000
+
+// ErrUnknownContentType is returned when a tree is decoded from data of an unknown content type.
+var ErrUnknownContentType = errors.New("unknown content type")
+
+// DecodeTree decodes a payload of the built-in CBOR, JSON or Gob encoders without its Go type into a tree
+// of map[string]any, []any and scalars. Integers are decoded as int64 or uint64, floats as float64, byte
+// strings as []byte and gob times as time.Time. CBOR tags are skipped, the tagged items are kept. Type
+// information the format carries is kept in the annotation keys, e.g. gob structs carry their type name in
+// TypeKey. Payloads compressed by a CompressionEncoder are decompressed first, encrypted payloads can't be
+// decoded. Data without a content type has been stored before content types have been recorded and is
+// tried with every format.
+func DecodeTree(contentType string, data []byte) (any, error) {
+	data, err := NewCompressionEncoder(nil).decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	switch contentType {
+	case ContentTypeCBOR:
+		return decodeCBORTree(data)
+	case ContentTypeJSON:
+		return decodeJSONTree(data)
+	case ContentTypeGob:
+		return decodeGobTree(data)
+	case "":
+		var errs []error
+		for _, decode := range []func([]byte) (any, error){decodeCBORTree, decodeGobTree, decodeJSONTree} {
+			tree, err := decode(data)
+			if err == nil {
+				return tree, nil
+			}
+			errs = append(errs, err)
+		}
+		return nil, errors.Join(errs...)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownContentType, contentType)
+}
+
+// decodeJSONTree decodes a JSON payload, numbers become int64, uint64 or float64.
+func decodeJSONTree(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("json: trailing data")
+	}
+	return jsonNumbers(value), nil
+}
+
+// jsonNumbers replaces the json.Number values of a decoded JSON value.
+func jsonNumbers(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, item := range value {
+			value[key] = jsonNumbers(item)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = jsonNumbers(item)
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(value), 10, 64); err == nil {
+			return u
+		}
+		f, _ := value.Float64()
+		return f
+	}
+	return value
+}
@@ -0,0 +1,53 @@
+package encoder
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fxamacker/cbor"
+)
+
+// decodeCBORTree decodes a CBOR payload. Tags are skipped, the tagged items are kept as they are.
+func decodeCBORTree(data []byte) (any, error) {
+	rest, err := cbor.Valid(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("cbor: %d bytes of trailing data", len(rest))
+	}
+	var value any
+	if err := cbor.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return cborTree(value), nil
+}
+
+// cborTree normalizes a value decoded into any: maps get string keys and integers that fit become int64.
+func cborTree(value any) any {
+	switch value := value.(type) {
+	case uint64:
+		if value <= math.MaxInt64 {
+			return int64(value)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = cborTree(item)
+		}
+	case map[any]any:
+		object := make(map[string]any, len(value))
+		for key, item := range value {
+			object[treeKey(key)] = cborTree(item)
+		}
+		return object
+	}
+	return value
+}
+
+// treeKey returns the map key of a decoded key.
+func treeKey(key any) string {
+	if text, ok := key.(string); ok {
+		return text
+	}
+	return fmt.Sprint(cborTree(key))
+}
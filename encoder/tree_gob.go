@@ -0,0 +1,371 @@
+package encoder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Type IDs gob predefines for its basic types.
+const (
+	gobBool      = 1
+	gobInt       = 2
+	gobUint      = 3
+	gobFloat     = 4
+	gobBytes     = 5
+	gobString    = 6
+	gobComplex   = 7
+	gobInterface = 8
+)
+
+// gobKind is the kind of a type defined in a gob stream.
+type gobKind int
+
+const (
+	gobArray gobKind = iota
+	gobSlice
+	gobStruct
+	gobMap
+	gobEncoder
+	gobBinaryMarshaler
+	gobTextMarshaler
+)
+
+// gobType is a type defined in a gob stream, it mirrors the wireType of the gob package.
+type gobType struct {
+	kind   gobKind
+	name   string
+	key    int64 // Key type of maps
+	elem   int64 // Element type of arrays, slices and maps
+	fields []gobField
+}
+
+// gobField is a field of a struct type.
+type gobField struct {
+	name string
+	id   int64
+}
+
+// gobReader decodes a gob stream into a tree, following the rules of the gob decoder. The error is sticky,
+// after the first error every read returns zero values.
+type gobReader struct {
+	stream []byte // Messages not read yet
+	buf    []byte // Rest of the current message
+	types  map[int64]*gobType
+	depth  int
+	err    error
+}
+
+// decodeGobTree decodes a payload of a single gob value. Structs carry their type name in TypeKey, fields
+// with zero values are missing since gob doesn't transmit them.
+func decodeGobTree(data []byte) (any, error) {
+	reader := &gobReader{stream: data, types: make(map[int64]*gobType)}
+	id := reader.typeSequence(false)
+	value := reader.value(id)
+	if reader.err == nil && (len(reader.buf) > 0 || len(reader.stream) > 0) {
+		reader.err = fmt.Errorf("%d bytes of trailing data", len(reader.buf)+len(reader.stream))
+	}
+	if reader.err != nil {
+		return nil, fmt.Errorf("gob: %w", reader.err)
+	}
+	return value, nil
+}
+
+// fail records the first error.
+func (r *gobReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// readUint reads an unsigned integer: values below 128 are a single byte, larger ones are prefixed by
+// their negated byte count.
+func readUint(data []byte) (uint64, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	if data[0] < 0x80 {
+		return uint64(data[0]), data[1:], nil
+	}
+	size := -int(int8(data[0]))
+	if size > 8 {
+		return 0, nil, fmt.Errorf("invalid unsigned integer size %d", size)
+	}
+	if len(data) < size+1 {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	var buf [8]byte
+	copy(buf[8-size:], data[1:size+1])
+	return binary.BigEndian.Uint64(buf[:]), data[size+1:], nil
+}
+
+// uint reads an unsigned integer of the current message.
+func (r *gobReader) uint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	value, rest, err := readUint(r.buf)
+	if err != nil {
+		r.fail(err)
+		return 0
+	}
+	r.buf = rest
+	return value
+}
+
+// int reads a signed integer, its sign is stored in the lowest bit.
+func (r *gobReader) int() int64 {
+	value := r.uint()
+	if value&1 != 0 {
+		return ^int64(value >> 1)
+	}
+	return int64(value >> 1)
+}
+
+// bytes reads a length prefixed byte string.
+func (r *gobReader) bytes() []byte {
+	size := r.uint()
+	if r.err != nil {
+		return nil
+	}
+	if size > uint64(len(r.buf)) {
+		r.fail(io.ErrUnexpectedEOF)
+		return nil
+	}
+	data := append([]byte{}, r.buf[:size]...)
+	r.buf = r.buf[size:]
+	return data
+}
+
+// count reads the number of elements of an array, slice or map. Every element takes at least one byte.
+func (r *gobReader) count() int {
+	count := r.uint()
+	if count > uint64(len(r.buf)) {
+		r.fail(io.ErrUnexpectedEOF)
+		return 0
+	}
+	return int(count)
+}
+
+// recvMessage starts the next message of the stream.
+func (r *gobReader) recvMessage() {
+	size, rest, err := readUint(r.stream)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	if size > uint64(len(rest)) {
+		r.fail(io.ErrUnexpectedEOF)
+		return
+	}
+	r.buf, r.stream = rest[:size], rest[size:]
+}
+
+// typeSequence reads type definitions until the ID of the type of the next value.
+func (r *gobReader) typeSequence(isInterface bool) int64 {
+	for r.err == nil {
+		if len(r.buf) == 0 {
+			r.recvMessage()
+		}
+		id := r.int()
+		if id >= 0 {
+			return id
+		}
+		r.types[-id] = r.wireType()
+		// Type definitions of values in interfaces are followed by the byte count of the next one.
+		if len(r.buf) > 0 {
+			if !isInterface {
+				r.fail(errors.New("extra data after type definition"))
+			}
+			r.uint()
+		}
+	}
+	return 0
+}
+
+// fields reads the delta encoded fields of a struct and calls read with the number of every field.
+func (r *gobReader) fields(read func(field int)) {
+	field := -1
+	for r.err == nil && len(r.buf) > 0 {
+		delta := r.uint()
+		if delta == 0 {
+			return
+		}
+		if delta > math.MaxInt32 {
+			r.fail(fmt.Errorf("invalid field delta %d", delta))
+			return
+		}
+		field += int(delta)
+		read(field)
+	}
+}
+
+// wireType reads a type definition.
+func (r *gobReader) wireType() *gobType {
+	t := new(gobType)
+	r.fields(func(field int) {
+		t.kind = gobKind(field)
+		r.fields(func(field int) {
+			switch {
+			case field == 0:
+				r.fields(func(field int) {
+					switch field {
+					case 0:
+						t.name = string(r.bytes())
+					case 1:
+						r.int()
+					}
+				})
+			case t.kind == gobStruct && field == 1:
+				count := r.count()
+				for i := 0; i < count && r.err == nil; i++ {
+					var f gobField
+					r.fields(func(field int) {
+						switch field {
+						case 0:
+							f.name = string(r.bytes())
+						case 1:
+							f.id = r.int()
+						}
+					})
+					t.fields = append(t.fields, f)
+				}
+			case t.kind == gobMap && field == 1:
+				t.key = r.int()
+			case t.kind == gobMap && field == 2, (t.kind == gobArray || t.kind == gobSlice) && field == 1:
+				t.elem = r.int()
+			case t.kind == gobArray && field == 2:
+				r.int()
+			default:
+				r.fail(fmt.Errorf("invalid type definition field %d", field))
+			}
+		})
+	})
+	if t.kind > gobTextMarshaler {
+		r.fail(fmt.Errorf("invalid type definition kind %d", t.kind))
+	}
+	return t
+}
+
+// value reads a top-level value or the value of an interface. Values other than structs are sent as
+// singletons.
+func (r *gobReader) value(id int64) any {
+	if t := r.types[id]; t != nil && t.kind == gobStruct {
+		return r.structValue(t)
+	}
+	if r.uint() != 0 {
+		r.fail(errors.New("corrupted singleton"))
+	}
+	return r.field(id)
+}
+
+// field reads a value of the type.
+func (r *gobReader) field(id int64) any {
+	if r.err != nil {
+		return nil
+	}
+	if r.depth++; r.depth > maxTreeDepth {
+		r.fail(fmt.Errorf("nesting deeper than %d", maxTreeDepth))
+		return nil
+	}
+	defer func() { r.depth-- }()
+
+	switch id {
+	case gobBool:
+		return r.uint() != 0
+	case gobInt:
+		return r.int()
+	case gobUint:
+		return r.uint()
+	case gobFloat:
+		return gobFloatValue(r.uint())
+	case gobBytes:
+		return r.bytes()
+	case gobString:
+		return string(r.bytes())
+	case gobComplex:
+		real, imag := gobFloatValue(r.uint()), gobFloatValue(r.uint())
+		return map[string]any{TypeKey: "complex", "Real": real, "Imag": imag}
+	case gobInterface:
+		return r.interfaceValue()
+	}
+	t := r.types[id]
+	if t == nil {
+		r.fail(fmt.Errorf("undefined type id %d", id))
+		return nil
+	}
+	switch t.kind {
+	case gobStruct:
+		return r.structValue(t)
+	case gobArray, gobSlice:
+		items := make([]any, r.count())
+		for i := range items {
+			items[i] = r.field(t.elem)
+		}
+		return items
+	case gobMap:
+		count := r.count()
+		object := make(map[string]any, count)
+		for i := 0; i < count && r.err == nil; i++ {
+			key := r.field(t.key)
+			object[treeKey(key)] = r.field(t.elem)
+		}
+		return object
+	case gobTextMarshaler:
+		return map[string]any{TypeKey: t.name, TextKey: string(r.bytes())}
+	}
+	return marshaledValue(t.name, r.bytes())
+}
+
+// structValue reads the fields of a struct.
+func (r *gobReader) structValue(t *gobType) any {
+	object := map[string]any{TypeKey: t.name}
+	r.fields(func(field int) {
+		if field >= len(t.fields) {
+			r.fail(fmt.Errorf("field %d of %s out of range", field, t.name))
+			return
+		}
+		object[t.fields[field].name] = r.field(t.fields[field].id)
+	})
+	return object
+}
+
+// interfaceValue reads a value stored in an interface. Structs get the name the type has been
+// registered with.
+func (r *gobReader) interfaceValue() any {
+	name := string(r.bytes())
+	if name == "" {
+		return nil
+	}
+	id := r.typeSequence(true)
+	r.uint() // Byte count of the value
+	value := r.value(id)
+	if object, ok := value.(map[string]any); ok && r.types[id] != nil && r.types[id].kind == gobStruct {
+		object[TypeKey] = name
+	}
+	return value
+}
+
+// gobFloatValue decodes a float, gob sends its bytes reversed.
+func gobFloatValue(value uint64) float64 {
+	return math.Float64frombits(bits.ReverseBytes64(value))
+}
+
+// marshaledValue returns a value encoded by its GobEncode or MarshalBinary method. Times and UUIDs are
+// decoded, other values keep their data in BinaryKey.
+func marshaledValue(name string, data []byte) any {
+	switch {
+	case name == "Time":
+		var t time.Time
+		if err := t.UnmarshalBinary(data); err == nil {
+			return t
+		}
+	case name == "UUID" && len(data) == 16:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:16])
+	}
+	return map[string]any{TypeKey: name, BinaryKey: data}
+}
@@ -0,0 +1,147 @@
+package encoder_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type wrappedPayload struct {
+	Inner any
+	Items []extraPayload
+}
+
+func TestDecodeTree(t *testing.T) {
+	id := uuid.MustParse("3d1b22cb-5c16-438a-8f70-993eabcb03a9")
+	createdAt := time.Date(2025, 2, 1, 12, 30, 0, 42, time.UTC)
+	value := typedPayload{
+		Id:        id,
+		Count:     1<<62 + 1,
+		Ratio:     0.5,
+		CreatedAt: createdAt,
+		Tags:      map[string]uint8{"a": 1},
+		Extra:     &wrappedPayload{Inner: &extraPayload{Note: "nested"}, Items: []extraPayload{{Note: "item"}}},
+	}
+
+	gobEncoder := encoders.NewGobEncoder()
+	gobEncoder.RegisterTypes(&wrappedPayload{}, &extraPayload{})
+	for name, test := range map[string]struct {
+		encoder  encoders.Encoder
+		expected map[string]any
+	}{
+		"gob": {gobEncoder, map[string]any{
+			encoders.TypeKey: "typedPayload",
+			"Id":             id.String(),
+			"Count":          int64(1<<62 + 1),
+			"Ratio":          0.5,
+			"CreatedAt":      createdAt,
+			"Tags":           map[string]any{"a": uint64(1)},
+			"Extra": map[string]any{
+				encoders.TypeKey: "*encoder_test.wrappedPayload",
+				"Inner":          map[string]any{encoders.TypeKey: "*encoder_test.extraPayload", "Note": "nested"},
+				"Items":          []any{map[string]any{encoders.TypeKey: "extraPayload", "Note": "item"}},
+			},
+		}},
+		"cbor": {encoders.NewCBorEncoder(), map[string]any{
+			"Id":        id[:],
+			"Count":     int64(1<<62 + 1),
+			"Ratio":     0.5,
+			"CreatedAt": createdAt.Unix(),
+			"Tags":      map[string]any{"a": int64(1)},
+			"Extra": map[string]any{
+				"Inner": map[string]any{"Note": "nested"},
+				"Items": []any{map[string]any{"Note": "item"}},
+			},
+		}},
+		"json": {encoders.NewJsonEncoder(), map[string]any{
+			"Id":        id.String(),
+			"Count":     int64(1<<62 + 1),
+			"Ratio":     0.5,
+			"CreatedAt": "2025-02-01T12:30:00.000000042Z",
+			"Tags":      map[string]any{"a": int64(1)},
+			"Extra": map[string]any{
+				"Inner": map[string]any{"Note": "nested"},
+				"Items": []any{map[string]any{"Note": "item"}},
+			},
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := test.encoder.Marshal(&value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			contentType := encoders.ContentTypeOf(test.encoder)
+			tree, err := encoders.DecodeTree(contentType, data)
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.expected, tree)
+			}
+
+			// Compressed payloads and payloads without a content type
+			compressed, err := encoders.NewCompressionCustomEncoder(test.encoder, encoders.NewGzipCompressor(gzip.BestSpeed), 0).Marshal(&value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tree, err = encoders.DecodeTree(contentType, compressed)
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.expected, tree)
+			}
+			tree, err = encoders.DecodeTree("", data)
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.expected, tree)
+			}
+
+			_, err = encoders.DecodeTree(contentType, data[:len(data)-1])
+			assert.Error(t, err, "truncated payloads fail")
+		})
+	}
+
+	_, err := encoders.DecodeTree("application/x-unknown", []byte{0})
+	assert.ErrorIs(t, err, encoders.ErrUnknownContentType)
+}
+
+func TestDecodeTreeLegacyGob(t *testing.T) {
+	gob.Register([]any{})
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(map[string]any{
+		"Name":  "legacy",
+		"Count": 3.0,
+		"Items": []any{"a", true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tree, err := encoders.DecodeTree(encoders.ContentTypeGob, buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"Name": "legacy", "Count": 3.0, "Items": []any{"a", true}}, tree)
+}
+
+func TestDecodeTreeCBOR(t *testing.T) {
+	for name, test := range map[string]struct {
+		data     []byte
+		expected any
+	}{
+		"indefinite array":  {[]byte{0x9f, 0x01, 0x20, 0xff}, []any{int64(1), int64(-1)}},
+		"indefinite string": {[]byte{0x7f, 0x62, 'a', 'b', 0x61, 'c', 0xff}, "abc"},
+		"half float":        {[]byte{0xf9, 0x3e, 0x00}, 1.5},
+		"epoch time":        {[]byte{0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0}, int64(1363896240)},
+		"tag":               {[]byte{0xd8, 0x20, 0x61, 'u'}, "u"},
+		"integer keys":      {[]byte{0xa1, 0x01, 0xf6}, map[string]any{"1": nil}},
+		"empty strings":     {[]byte{0x83, 0x60, 0x60, 0x60}, []any{"", "", ""}},
+		"empty values":      {[]byte{0xa2, 0x60, 0x60, 0x61, 'a', 0x60}, map[string]any{"": "", "a": ""}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tree, err := encoders.DecodeTree(encoders.ContentTypeCBOR, test.data)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, tree)
+		})
+	}
+	_, err := encoders.DecodeTree(encoders.ContentTypeCBOR, []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	assert.Error(t, err, "lengths beyond the payload fail")
+	_, err = encoders.DecodeTree(encoders.ContentTypeCBOR, []byte{0x01, 0x02})
+	assert.Error(t, err, "trailing data fails")
+}
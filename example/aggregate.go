@@ -1,6 +1,9 @@
 package example
 
 import (
+	"slices"
+	"strings"
+
 	"github.com/FlauschigDings/lavender"
 	"github.com/FlauschigDings/lavender/repo"
 	"github.com/google/uuid"
@@ -67,6 +70,8 @@ func (a *AccountAggregate) TakeSnapshot() lavender.Snapshot {
 	for _, fileData := range a.Users {
 		users = append(users, *fileData)
 	}
+	// Keep the snapshot deterministic, maps are iterated in random order
+	slices.SortFunc(users, func(a, b User) int { return strings.Compare(a.Id.String(), b.Id.String()) })
 
 	return &AccountSnapshot{
 		Users: users,
@@ -7,26 +7,23 @@ import (
 	"github.com/FlauschigDings/lavender/store"
 )
 
-// Wrapper of the InMemoryEventStore
-type CustomMemoryStore[E Event, S lavender.Snapshot] struct {
-	*store.InMemoryEventStore[E, S]
-}
-
-// Typesafety checks for SnapshotStore and EventStore.
-var _ store.SnapshotStore[Event, lavender.Snapshot] = new(CustomMemoryStore[Event, lavender.Snapshot])
-var _ store.EventStore[Event, lavender.Snapshot] = new(CustomMemoryStore[Event, lavender.Snapshot])
+// Store of the custom events.
+type Store = store.Store[Event, lavender.Snapshot]
 
-// Create a custom store instance.
-func NewCustomMemoryStore() *CustomMemoryStore[Event, lavender.Snapshot] {
-	return &CustomMemoryStore[Event, lavender.Snapshot]{
-		store.NewInMemoryCustomStore[Event, lavender.Snapshot](),
-	}
+// Create a custom store instance, an InMemoryEventStore wrapped with the operator logging middleware.
+func NewCustomMemoryStore() Store {
+	return store.Chain(store.NewInMemoryCustomStore[Event, lavender.Snapshot](), OperatorLogging)
 }
 
-// Overwrite the old function and select the custom event field and write the operator in the chat.
-func (c *CustomMemoryStore[E, S]) SaveEvents(aggregate lavender.CustomAggregate[E, S], events []E) error {
-	for _, event := range events {
-		log.Printf("event has been added from %#v", event.Operator())
+// OperatorLogging is a store middleware that selects the custom event field and writes the operator in the chat.
+func OperatorLogging(next Store) Store {
+	return &store.StoreWrapper[Event, lavender.Snapshot]{
+		Next: next,
+		HookSaveEvents: func(aggregate lavender.CustomAggregate[Event, lavender.Snapshot], events []Event, signatures []store.Signature) error {
+			for _, event := range events {
+				log.Printf("event has been added from %#v", event.Operator())
+			}
+			return store.SaveSigned(next, aggregate, events, signatures)
+		},
 	}
-	return c.InMemoryEventStore.SaveEvents(aggregate, events)
 }
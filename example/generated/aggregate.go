@@ -0,0 +1,62 @@
+package generated
+
+import (
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/google/uuid"
+)
+
+//go:generate go run github.com/FlauschigDings/lavender/cmd/lavender-gen
+
+// AccountAggregate is the account aggregate of package example with its methods generated by lavender-gen.
+//
+//lavender:aggregate name=account version=0.0.1 new=New
+type AccountAggregate struct {
+	Users  map[uuid.UUID]*example.User
+	Emails map[string]*example.User
+}
+
+func New() *AccountAggregate {
+	return &AccountAggregate{
+		Users:  make(map[uuid.UUID]*example.User),
+		Emails: make(map[string]*example.User),
+	}
+}
+
+func Load(repo *repo.Repository) (*AccountAggregate, error) {
+	aggregate := New()
+	if err := repo.LoadAggregate(aggregate); err != nil {
+		return nil, err
+	}
+	return aggregate, nil
+}
+
+func (a *AccountAggregate) onCreate(event *Create) {
+	a.Users[event.Id] = &event.User
+	a.Emails[event.Email] = &event.User
+}
+
+func (a *AccountAggregate) onChangeEmail(event *ChangeEmail) {
+	user, ok := a.Users[event.Id]
+	if !ok {
+		return
+	}
+	delete(a.Emails, user.Email)
+	user.Email = event.Email
+	a.Emails[user.Email] = user
+}
+
+func (a *AccountAggregate) snapshot() *AccountSnapshot {
+	snapshot := new(AccountSnapshot)
+	for _, user := range a.Users {
+		snapshot.Users = append(snapshot.Users, *user)
+	}
+	return snapshot
+}
+
+func (a *AccountAggregate) restore(snapshot *AccountSnapshot) {
+	for _, user := range snapshot.Users {
+		a.Users[user.Id] = &user
+		a.Emails[user.Email] = &user
+	}
+}
@@ -0,0 +1,17 @@
+package generated
+
+import (
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/google/uuid"
+)
+
+//lavender:event aggregate=AccountAggregate name=create
+type Create struct {
+	example.User
+}
+
+//lavender:event aggregate=AccountAggregate name=change_email
+type ChangeEmail struct {
+	Id    uuid.UUID
+	Email string
+}
@@ -0,0 +1,54 @@
+package generated_test
+
+import (
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/example/generated"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestGenerated(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := store.NewGormStore(db).RegisterAggregates(generated.Aggregates()...)
+
+	user := *example.NewUser("duck@ducky.com", "iL0v3Duc7s")
+
+	repo := repo.NewRepositoryConstructor(false, store, store)
+
+	if err := repo.AddEvent(generated.New(), &generated.Create{User: user}); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+	if err := repo.AddEvent(generated.New(), &generated.ChangeEmail{Id: user.Id, Email: "goose@ducky.com"}); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+
+	aggregate, err := generated.Load(repo)
+	if err != nil {
+		t.Fatalf("failed to load aggregate: %v", err)
+	}
+	assert.Contains(t, aggregate.Emails, "goose@ducky.com")
+	assert.NotContains(t, aggregate.Emails, user.Email)
+
+	// The aggregate is restored from the snapshot by the generated ApplySnapshot.
+	if err := repo.CreateSnapshot(generated.New()); err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	snapshot, err := store.LoadSnapshot(generated.New())
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	assert.Equal(t, lavender.Name("account"), (*snapshot).AggregateID())
+	restored := generated.New()
+	restored.ApplySnapshot(*snapshot)
+	assert.Equal(t, aggregate.Emails, restored.Emails)
+}
@@ -0,0 +1,8 @@
+package generated
+
+import "github.com/FlauschigDings/lavender/example"
+
+//lavender:snapshot aggregate=AccountAggregate
+type AccountSnapshot struct {
+	Users []example.User `json:"users"`
+}
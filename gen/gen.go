@@ -0,0 +1,469 @@
+// Package gen generates the boilerplate of aggregates, events and snapshots from annotated structs. It is
+// run by the lavender-gen command (cmd/lavender-gen), usually from a go:generate directive:
+//
+//	//go:generate go run github.com/FlauschigDings/lavender/cmd/lavender-gen
+//
+// Types are annotated with a directive in their doc comment:
+//
+//	//lavender:aggregate name=account version=0.0.1 new=New
+//	type AccountAggregate struct{ ... }
+//
+//	//lavender:event aggregate=AccountAggregate name=create
+//	type Create struct{ ... }
+//
+//	//lavender:snapshot aggregate=AccountAggregate
+//	type AccountSnapshot struct{ ... }
+//
+// Aggregates get Name, Version, Events and ApplyEvent, which dispatches every annotated event to the
+// on<Event> handler of the aggregate, e.g. onCreate(event *Create). Events get Name and Apply, which calls
+// the same handler. Aggregates with an annotated snapshot get TakeSnapshot and ApplySnapshot, calling the
+// snapshot() *AccountSnapshot and restore(snapshot *AccountSnapshot) methods of the aggregate, and the
+// snapshot gets AggregateID and Version. Methods a type already declares are not generated.
+//
+// Without a name option, aggregates are named by their type name without the "Aggregate" suffix and events
+// by their type name, both starting lower case. The aggregate is created with new(T) unless the new option
+// names a constructor.
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Prefix of the directives annotating types.
+const directivePrefix = "//lavender:"
+
+// Generator generates the method sets of the annotated types of a package.
+type Generator struct {
+	// Output is the name of the generated file. It is skipped when the package is parsed.
+	Output string
+
+	// Registry is the name of the generated function returning new instances of the aggregates,
+	// empty to skip it.
+	Registry string
+}
+
+// New creates a generator writing lavender_gen.go with an Aggregates registry function.
+func New() *Generator {
+	return &Generator{
+		Output:   "lavender_gen.go",
+		Registry: "Aggregates",
+	}
+}
+
+// UseOutput sets the name of the generated file.
+func (g *Generator) UseOutput(output string) *Generator {
+	g.Output = output
+	return g
+}
+
+// UseRegistry sets the name of the registry function, empty to skip it.
+func (g *Generator) UseRegistry(registry string) *Generator {
+	g.Registry = registry
+	return g
+}
+
+// aggregateSpec is an annotated aggregate.
+type aggregateSpec struct {
+	Type        string
+	Name        string
+	Version     string
+	Constructor string
+	Snapshot    *snapshotSpec
+	Events      []*eventSpec
+	Methods     map[string]bool // Methods declared by the package
+}
+
+// eventSpec is an annotated event.
+type eventSpec struct {
+	Type       string
+	Name       string
+	Aggregates []*aggregateSpec
+	Methods    map[string]bool
+
+	aggregateTypes []string
+	pos            token.Position
+}
+
+// snapshotSpec is an annotated snapshot.
+type snapshotSpec struct {
+	Type      string
+	Aggregate *aggregateSpec
+	Methods   map[string]bool
+
+	aggregateType string
+	pos           token.Position
+}
+
+// pkgSpec holds the annotated types of a package.
+type pkgSpec struct {
+	Package    string
+	Registry   string
+	Aggregates []*aggregateSpec
+	Events     []*eventSpec
+	Snapshots  []*snapshotSpec
+}
+
+// Generate parses the package in dir and returns the formatted source of the generated file, nil if no
+// type is annotated.
+func (g *Generator) Generate(dir string) ([]byte, error) {
+	pkg, err := g.parse(dir)
+	if err != nil || pkg == nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, pkg); err != nil {
+		return nil, err
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return source, nil
+}
+
+// Write generates the file of the package in dir. A previously generated file is removed if no type is
+// annotated anymore.
+func (g *Generator) Write(dir string) error {
+	source, err := g.Generate(dir)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, g.Output)
+	if source == nil {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(path, source, 0o644)
+}
+
+// parse collects the annotated types and the declared methods of the package in dir.
+func (g *Generator) parse(dir string) (*pkgSpec, error) {
+	buildPkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	pkg := &pkgSpec{Package: buildPkg.Name}
+	aggregates := make(map[string]*aggregateSpec)
+	methods := make(map[string]map[string]bool)
+	declared := make(map[string]bool)
+	for _, name := range buildPkg.GoFiles {
+		if name == g.Output {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					declared[decl.Name.Name] = true
+					continue
+				}
+				receiver := receiverType(decl.Recv.List[0].Type)
+				if methods[receiver] == nil {
+					methods[receiver] = make(map[string]bool)
+				}
+				methods[receiver][decl.Name.Name] = true
+			case *ast.GenDecl:
+				if decl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					doc := typeSpec.Doc
+					if doc == nil && len(decl.Specs) == 1 {
+						doc = decl.Doc
+					}
+					if err := pkg.add(fset, typeSpec, doc, aggregates); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	if len(pkg.Aggregates)+len(pkg.Events)+len(pkg.Snapshots) == 0 {
+		return nil, nil
+	}
+
+	for _, aggregate := range pkg.Aggregates {
+		aggregate.Methods = methods[aggregate.Type]
+	}
+	for _, event := range pkg.Events {
+		event.Methods = methods[event.Type]
+		for _, name := range event.aggregateTypes {
+			aggregate := aggregates[name]
+			if aggregate == nil {
+				return nil, fmt.Errorf("%s: event %s: aggregate %s is not annotated", event.pos, event.Type, name)
+			}
+			event.Aggregates = append(event.Aggregates, aggregate)
+			aggregate.Events = append(aggregate.Events, event)
+		}
+	}
+	for _, snapshot := range pkg.Snapshots {
+		snapshot.Methods = methods[snapshot.Type]
+		aggregate := aggregates[snapshot.aggregateType]
+		switch {
+		case aggregate == nil:
+			return nil, fmt.Errorf("%s: snapshot %s: aggregate %s is not annotated", snapshot.pos, snapshot.Type, snapshot.aggregateType)
+		case aggregate.Snapshot != nil:
+			return nil, fmt.Errorf("%s: snapshot %s: aggregate %s already has the snapshot %s", snapshot.pos, snapshot.Type, aggregate.Type, aggregate.Snapshot.Type)
+		}
+		snapshot.Aggregate = aggregate
+		aggregate.Snapshot = snapshot
+	}
+	if g.Registry != "" && len(pkg.Aggregates) > 0 && !declared[g.Registry] {
+		pkg.Registry = g.Registry
+	}
+	return pkg, nil
+}
+
+// add adds the type if its doc comment holds a directive.
+func (pkg *pkgSpec) add(fset *token.FileSet, typeSpec *ast.TypeSpec, doc *ast.CommentGroup, aggregates map[string]*aggregateSpec) error {
+	if doc == nil {
+		return nil
+	}
+	for _, comment := range doc.List {
+		directive, ok := strings.CutPrefix(comment.Text, directivePrefix)
+		if !ok {
+			continue
+		}
+		pos := fset.Position(comment.Pos())
+		kind, options, err := parseDirective(directive)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pos, err)
+		}
+		if typeSpec.TypeParams != nil {
+			return fmt.Errorf("%s: generic type %s can't be annotated", pos, typeSpec.Name.Name)
+		}
+		typeName := typeSpec.Name.Name
+		switch kind {
+		case "aggregate":
+			if err := checkOptions(options, "name", "version", "new"); err != nil {
+				return fmt.Errorf("%s: %w", pos, err)
+			}
+			if options["version"] == "" {
+				return fmt.Errorf("%s: aggregate %s: missing version option", pos, typeName)
+			}
+			if aggregates[typeName] != nil {
+				return fmt.Errorf("%s: aggregate %s is annotated twice", pos, typeName)
+			}
+			name := options["name"]
+			if name == "" {
+				name = lowerFirst(strings.TrimSuffix(typeName, "Aggregate"))
+			}
+			aggregate := &aggregateSpec{
+				Type:        typeName,
+				Name:        name,
+				Version:     options["version"],
+				Constructor: options["new"],
+			}
+			aggregates[typeName] = aggregate
+			pkg.Aggregates = append(pkg.Aggregates, aggregate)
+		case "event":
+			if err := checkOptions(options, "name", "aggregate"); err != nil {
+				return fmt.Errorf("%s: %w", pos, err)
+			}
+			if options["aggregate"] == "" {
+				return fmt.Errorf("%s: event %s: missing aggregate option", pos, typeName)
+			}
+			name := options["name"]
+			if name == "" {
+				name = lowerFirst(typeName)
+			}
+			pkg.Events = append(pkg.Events, &eventSpec{
+				Type:           typeName,
+				Name:           name,
+				aggregateTypes: strings.Split(options["aggregate"], ","),
+				pos:            pos,
+			})
+		case "snapshot":
+			if err := checkOptions(options, "aggregate"); err != nil {
+				return fmt.Errorf("%s: %w", pos, err)
+			}
+			if options["aggregate"] == "" {
+				return fmt.Errorf("%s: snapshot %s: missing aggregate option", pos, typeName)
+			}
+			pkg.Snapshots = append(pkg.Snapshots, &snapshotSpec{
+				Type:          typeName,
+				aggregateType: options["aggregate"],
+				pos:           pos,
+			})
+		default:
+			return fmt.Errorf("%s: unknown directive lavender:%s", pos, kind)
+		}
+	}
+	return nil
+}
+
+// parseDirective splits a directive into its kind and its key=value options.
+func parseDirective(directive string) (string, map[string]string, error) {
+	fields := strings.Fields(directive)
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("empty lavender directive")
+	}
+	options := make(map[string]string)
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || key == "" {
+			return "", nil, fmt.Errorf("option %q of lavender:%s is not key=value", field, fields[0])
+		}
+		if _, ok := options[key]; ok {
+			return "", nil, fmt.Errorf("option %s of lavender:%s is given twice", key, fields[0])
+		}
+		options[key] = value
+	}
+	return fields[0], options, nil
+}
+
+// checkOptions returns an error for options that aren't allowed.
+func checkOptions(options map[string]string, allowed ...string) error {
+	for key := range options {
+		if !slices.Contains(allowed, key) {
+			return fmt.Errorf("unknown option %s", key)
+		}
+	}
+	return nil
+}
+
+// receiverType returns the name of the type of a method receiver.
+func receiverType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(expr.X)
+	case *ast.IndexExpr:
+		return receiverType(expr.X)
+	case *ast.IndexListExpr:
+		return receiverType(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// lowerFirst returns the text starting with a lower case letter.
+func lowerFirst(text string) string {
+	r, size := utf8.DecodeRuneInString(text)
+	return string(unicode.ToLower(r)) + text[size:]
+}
+
+// receiver returns the receiver name of the methods of a type.
+func receiver(typeName string) string {
+	r, _ := utf8.DecodeRuneInString(typeName)
+	return string(unicode.ToLower(r))
+}
+
+var fileTemplate = template.Must(template.New("file").Funcs(template.FuncMap{
+	"receiver": receiver,
+	"quote":    strconv.Quote,
+}).Parse(`// Code generated by lavender-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import "github.com/FlauschigDings/lavender"
+{{range .Aggregates}}{{$r := receiver .Type}}
+var _ lavender.Aggregate = new({{.Type}})
+{{if not .Methods.Name}}
+// Name implements lavender.CustomAggregate.
+func ({{$r}} *{{.Type}}) Name() lavender.Name {
+	return {{quote .Name}}
+}
+{{end}}{{if not .Methods.Version}}
+// Version implements lavender.CustomAggregate.
+func ({{$r}} *{{.Type}}) Version() lavender.Version {
+	return {{quote .Version}}
+}
+{{end}}{{if not .Methods.Events}}
+// Events implements lavender.CustomAggregate.
+func ({{$r}} *{{.Type}}) Events() []lavender.Event {
+	return []lavender.Event{
+{{- range .Events}}
+		new({{.Type}}),
+{{- end}}
+	}
+}
+{{end}}{{if not .Methods.ApplyEvent}}
+// ApplyEvent implements lavender.CustomAggregate. Events of other types apply themselves.
+func ({{$r}} *{{.Type}}) ApplyEvent(event lavender.Event) {
+	switch event := event.(type) {
+{{- range .Events}}
+	case *{{.Type}}:
+		{{$r}}.on{{.Type}}(event)
+{{- end}}
+	default:
+		event.Apply({{$r}})
+	}
+}
+{{end}}{{if .Snapshot}}{{if not .Methods.TakeSnapshot}}
+// TakeSnapshot implements lavender.CustomAggregate.
+func ({{$r}} *{{.Type}}) TakeSnapshot() lavender.Snapshot {
+	return {{$r}}.snapshot()
+}
+{{end}}{{if not .Methods.ApplySnapshot}}
+// ApplySnapshot implements lavender.CustomAggregate.
+func ({{$r}} *{{.Type}}) ApplySnapshot(snapshot lavender.Snapshot) {
+	if snapshot, ok := snapshot.(*{{.Snapshot.Type}}); ok {
+		{{$r}}.restore(snapshot)
+	}
+}
+{{end}}{{end}}{{end}}
+{{- range .Events}}{{$r := receiver .Type}}
+var _ lavender.Event = new({{.Type}})
+{{if not .Methods.Name}}
+// Name implements lavender.Event.
+func ({{$r}} *{{.Type}}) Name() lavender.Name {
+	return {{quote .Name}}
+}
+{{end}}{{if not .Methods.Apply}}{{$event := .}}
+// Apply implements lavender.Event.
+func ({{$r}} *{{.Type}}) Apply(aggregate lavender.Aggregate) {
+	switch aggregate := aggregate.(type) {
+{{- range .Aggregates}}
+	case *{{.Type}}:
+		aggregate.on{{$event.Type}}({{$r}})
+{{- end}}
+	}
+}
+{{end}}{{end}}
+{{- range .Snapshots}}{{$r := receiver .Type}}
+var _ lavender.Snapshot = new({{.Type}})
+{{if not .Methods.AggregateID}}
+// AggregateID implements lavender.Snapshot.
+func ({{$r}} *{{.Type}}) AggregateID() lavender.Name {
+	return {{quote .Aggregate.Name}}
+}
+{{end}}{{if not .Methods.Version}}
+// Version implements lavender.Snapshot.
+func ({{$r}} *{{.Type}}) Version() lavender.Version {
+	return {{quote .Aggregate.Version}}
+}
+{{end}}{{end}}
+{{- with .Registry}}
+// {{.}} returns new instances of the annotated aggregates, e.g. to register them with a store.
+func {{.}}() []lavender.Aggregate {
+	return []lavender.Aggregate{
+{{- range $.Aggregates}}
+		{{if .Constructor}}{{.Constructor}}(){{else}}new({{.Type}}){{end}},
+{{- end}}
+	}
+}
+{{end}}`))
@@ -0,0 +1,123 @@
+package gen_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FlauschigDings/lavender/gen"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestGenerate(t *testing.T) {
+	dirs, err := filepath.Glob("testdata/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The generated example has to be up to date.
+	for _, dir := range append(dirs, "../example/generated") {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			source, err := gen.New().Generate(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join(dir, "lavender_gen.go.golden")
+			if filepath.Base(dir) == "generated" {
+				golden = filepath.Join(dir, "lavender_gen.go")
+			}
+			if *update {
+				if err := os.WriteFile(golden, source, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, string(want), string(source))
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for name, test := range map[string]struct {
+		source string
+		err    string
+	}{
+		"missing version": {
+			source: "//lavender:aggregate name=account\ntype Account struct{}\n",
+			err:    "a.go:3:1: aggregate Account: missing version option",
+		},
+		"unknown option": {
+			source: "//lavender:aggregate version=1 snapshot=AccountSnapshot\ntype Account struct{}\n",
+			err:    "a.go:3:1: unknown option snapshot",
+		},
+		"invalid option": {
+			source: "//lavender:aggregate version\ntype Account struct{}\n",
+			err:    `a.go:3:1: option "version" of lavender:aggregate is not key=value`,
+		},
+		"unknown directive": {
+			source: "//lavender:projection\ntype Account struct{}\n",
+			err:    "a.go:3:1: unknown directive lavender:projection",
+		},
+		"unknown aggregate": {
+			source: "//lavender:event aggregate=Account\ntype Create struct{}\n",
+			err:    "a.go:3:1: event Create: aggregate Account is not annotated",
+		},
+		"missing aggregate": {
+			source: "//lavender:snapshot\ntype AccountSnapshot struct{}\n",
+			err:    "a.go:3:1: snapshot AccountSnapshot: missing aggregate option",
+		},
+		"second snapshot": {
+			source: "//lavender:aggregate version=1\ntype Account struct{}\n\n" +
+				"//lavender:snapshot aggregate=Account\ntype SnapshotV1 struct{}\n\n" +
+				"//lavender:snapshot aggregate=Account\ntype SnapshotV2 struct{}\n",
+			err: "a.go:9:1: snapshot SnapshotV2: aggregate Account already has the snapshot SnapshotV1",
+		},
+		"generic type": {
+			source: "//lavender:aggregate version=1\ntype Account[T any] struct{}\n",
+			err:    "a.go:3:1: generic type Account can't be annotated",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\n"+test.source), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := gen.New().Generate(dir)
+			if assert.Error(t, err) {
+				assert.Equal(t, filepath.Join(dir, test.err), err.Error())
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	source := "package a\n\n//lavender:aggregate version=1\ntype Account struct{}\n"
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	generator := gen.New().UseOutput("account_gen.go").UseRegistry("")
+	if err := generator.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	generated, err := os.ReadFile(filepath.Join(dir, "account_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(generated), "func (a *Account) Name() lavender.Name")
+	assert.NotContains(t, string(generated), "func Aggregates()")
+
+	// The generated file is removed once nothing is annotated.
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := generator.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoFileExists(t, filepath.Join(dir, "account_gen.go"))
+}
@@ -0,0 +1,32 @@
+package defaults
+
+import "github.com/FlauschigDings/lavender"
+
+//lavender:aggregate version=1.0.0
+type AccountAggregate struct {
+	Users map[string]string
+}
+
+// TakeSnapshot and ApplySnapshot are declared, they are not generated.
+func (a *AccountAggregate) TakeSnapshot() lavender.Snapshot {
+	return nil
+}
+
+func (a *AccountAggregate) ApplySnapshot(snapshot lavender.Snapshot) {}
+
+func (a *AccountAggregate) onUserCreated(event *UserCreated) {
+	a.Users[event.ID] = event.Email
+}
+
+// UserCreated is created when a user signs up.
+//
+//lavender:event aggregate=AccountAggregate
+type UserCreated struct {
+	ID    string
+	Email string
+}
+
+type (
+	// Not annotated.
+	UserDeleted struct{}
+)
@@ -0,0 +1,37 @@
+package shared
+
+//lavender:aggregate name=account version=1.0.0 new=NewV1
+type AccountAggregateV1 struct {
+	Users map[string]User
+}
+
+func NewV1() *AccountAggregateV1 {
+	return &AccountAggregateV1{Users: make(map[string]User)}
+}
+
+//lavender:aggregate name=account version=2.0.0
+type AccountAggregateV2 struct {
+	Users []User
+}
+
+// Name is declared, it is not generated.
+func (a AccountAggregateV2) Name() string {
+	return "account"
+}
+
+type (
+	//lavender:snapshot aggregate=AccountAggregateV2
+	AccountSnapshot struct {
+		Users []User
+	}
+
+	//lavender:event aggregate=AccountAggregateV1,AccountAggregateV2 name=create
+	Create struct {
+		User
+	}
+)
+
+// Aggregates is declared, the registry is not generated.
+func Aggregates() []any {
+	return nil
+}
@@ -0,0 +1,4 @@
+package shared
+
+//lavender:aggregate version=0.0.1
+type TestAggregate struct{}
@@ -0,0 +1,6 @@
+package shared
+
+type User struct {
+	ID    string
+	Email string
+}
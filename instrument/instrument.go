@@ -0,0 +1,122 @@
+// Package instrument defines the tracing and metrics hooks of the repository and the stores.
+// Bridge Instrumentation to a tracing stack (e.g. OpenTelemetry) to observe replays, snapshots and appends.
+package instrument
+
+import "time"
+
+// Span names used by the repository and the stores.
+const (
+	SpanLoadAggregate  = "lavender.repository.load_aggregate"
+	SpanAddEvent       = "lavender.repository.add_event"
+	SpanUpdate         = "lavender.repository.update"
+	SpanCreateSnapshot = "lavender.repository.create_snapshot"
+	SpanSaveEvents     = "lavender.store.save_events"
+	SpanLoadEvents     = "lavender.store.load_events"
+	SpanSaveSnapshot   = "lavender.store.save_snapshot"
+	SpanLoadSnapshot   = "lavender.store.load_snapshot"
+	SpanScavenge       = "lavender.store.scavenge"
+	SpanArchive        = "lavender.store.archive"
+)
+
+// Metric names used by the repository and the stores.
+const (
+	// MetricEventsLoaded records the number of events replayed per aggregate load (histogram).
+	MetricEventsLoaded = "lavender.events.loaded"
+	// MetricReplayDuration records how long applying snapshot and events took in seconds (histogram).
+	MetricReplayDuration = "lavender.replay.duration"
+	// MetricSnapshotHits counts aggregate loads that started from a snapshot.
+	MetricSnapshotHits = "lavender.snapshot.hits"
+	// MetricSnapshotMisses counts aggregate loads without a snapshot.
+	MetricSnapshotMisses = "lavender.snapshot.misses"
+	// MetricAutoSnapshots counts snapshots created by the AutoSnapshotHook.
+	MetricAutoSnapshots = "lavender.snapshot.auto"
+	// MetricSnapshotsDiscarded counts stored snapshots that couldn't be applied and were replaced by a replay.
+	MetricSnapshotsDiscarded = "lavender.snapshot.discarded"
+	// MetricSnapshotsUpgraded counts stored snapshots upgraded by the SnapshotUpgradeHook.
+	MetricSnapshotsUpgraded = "lavender.snapshot.upgraded"
+	// MetricCacheHits counts aggregate loads served by the aggregate cache.
+	MetricCacheHits = "lavender.cache.hits"
+	// MetricCacheMisses counts aggregate loads not served by the aggregate cache.
+	MetricCacheMisses = "lavender.cache.misses"
+	// MetricUpdateConflicts counts Update attempts that failed with a concurrency conflict.
+	MetricUpdateConflicts = "lavender.update.conflicts"
+	// MetricAppendDuration records how long storing events took in seconds (histogram).
+	MetricAppendDuration = "lavender.append.duration"
+	// MetricEventsScavenged counts the events removed by scavenging.
+	MetricEventsScavenged = "lavender.events.scavenged"
+	// MetricEventsArchived counts the events moved to archive files.
+	MetricEventsArchived = "lavender.events.archived"
+	// MetricStoreDuration records how long a store operation took in seconds (histogram).
+	MetricStoreDuration = "lavender.store.duration"
+	// MetricEncodedBytes counts the bytes produced by the encoder.
+	MetricEncodedBytes = "lavender.encoder.encoded_bytes"
+	// MetricDecodedBytes counts the bytes read by the encoder.
+	MetricDecodedBytes = "lavender.encoder.decoded_bytes"
+)
+
+// Attribute is a key value pair attached to spans and metrics.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Attr creates an Attribute.
+func Attr(key string, value any) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a timed operation.
+type Span interface {
+	// SetError marks the span as failed. A nil error is ignored.
+	SetError(err error)
+	// End finishes the span.
+	End()
+}
+
+// Instrumentation receives spans and metrics.
+type Instrumentation interface {
+	// StartSpan starts a span with the given name.
+	StartSpan(name string, attrs ...Attribute) Span
+	// Count adds the value to a counter.
+	Count(name string, value int64, attrs ...Attribute)
+	// Record adds an observation to a histogram.
+	Record(name string, value float64, attrs ...Attribute)
+}
+
+// Noop is an Instrumentation that drops everything.
+type Noop struct{}
+
+var _ Instrumentation = Noop{}
+
+// StartSpan implements Instrumentation.
+func (Noop) StartSpan(name string, attrs ...Attribute) Span {
+	return noopSpan{}
+}
+
+// Count implements Instrumentation.
+func (Noop) Count(name string, value int64, attrs ...Attribute) {}
+
+// Record implements Instrumentation.
+func (Noop) Record(name string, value float64, attrs ...Attribute) {}
+
+// noopSpan is the span of Noop.
+type noopSpan struct{}
+
+// SetError implements Span.
+func (noopSpan) SetError(err error) {}
+
+// End implements Span.
+func (noopSpan) End() {}
+
+// Or returns the instrumentation or Noop if it is nil.
+func Or(instrumentation Instrumentation) Instrumentation {
+	if instrumentation == nil {
+		return Noop{}
+	}
+	return instrumentation
+}
+
+// Since records the seconds elapsed since start in a histogram.
+func Since(instrumentation Instrumentation, name string, start time.Time, attrs ...Attribute) {
+	instrumentation.Record(name, time.Since(start).Seconds(), attrs...)
+}
@@ -0,0 +1,117 @@
+package instrument
+
+import (
+	"sync"
+	"time"
+)
+
+// RecordedSpan is a span captured by the Recorder.
+type RecordedSpan struct {
+	Name     string
+	Attrs    []Attribute
+	Err      error
+	Start    time.Time
+	Duration time.Duration
+	Ended    bool
+}
+
+// Recorder is a thread-safe Instrumentation that keeps everything in memory, intended for tests.
+type Recorder struct {
+	mu         sync.Mutex
+	spans      []*RecordedSpan
+	counters   map[string]int64
+	histograms map[string][]float64
+}
+
+var _ Instrumentation = new(Recorder)
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		counters:   make(map[string]int64),
+		histograms: make(map[string][]float64),
+	}
+}
+
+// StartSpan implements Instrumentation.
+func (r *Recorder) StartSpan(name string, attrs ...Attribute) Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	span := &RecordedSpan{Name: name, Attrs: attrs, Start: time.Now()}
+	r.spans = append(r.spans, span)
+	return &recorderSpan{recorder: r, span: span}
+}
+
+// Count implements Instrumentation.
+func (r *Recorder) Count(name string, value int64, attrs ...Attribute) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[name] += value
+}
+
+// Record implements Instrumentation.
+func (r *Recorder) Record(name string, value float64, attrs ...Attribute) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.histograms[name] = append(r.histograms[name], value)
+}
+
+// Counter returns the current value of a counter.
+func (r *Recorder) Counter(name string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counters[name]
+}
+
+// Histogram returns a copy of the observations of a histogram.
+func (r *Recorder) Histogram(name string) []float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]float64(nil), r.histograms[name]...)
+}
+
+// Spans returns copies of the recorded spans with the given name, or of all spans if the name is empty.
+func (r *Recorder) Spans(name string) []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var spans []RecordedSpan
+	for _, span := range r.spans {
+		if name == "" || span.Name == name {
+			spans = append(spans, *span)
+		}
+	}
+	return spans
+}
+
+// Reset drops everything recorded so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+	r.counters = make(map[string]int64)
+	r.histograms = make(map[string][]float64)
+}
+
+// recorderSpan is the span of Recorder.
+type recorderSpan struct {
+	recorder *Recorder
+	span     *RecordedSpan
+}
+
+// SetError implements Span.
+func (s *recorderSpan) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.span.Err = err
+}
+
+// End implements Span.
+func (s *recorderSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.span.Duration = time.Since(s.span.Start)
+	s.span.Ended = true
+}
@@ -0,0 +1,67 @@
+package lavendertest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+)
+
+// FakeClock is a lavender.Clock that only moves when it is told to.
+type FakeClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+var _ lavender.Clock = new(FakeClock)
+
+// NewFakeClock creates a clock that stands still at start.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// NewTickingClock creates a clock that starts at start and advances by step after every call of Now.
+func NewTickingClock(start time.Time, step time.Duration) *FakeClock {
+	return &FakeClock{now: start, step: step}
+}
+
+// Now implements lavender.Clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// SequentialIDs is a lavender.IDGenerator creating predictable, ordered UUID shaped IDs.
+type SequentialIDs struct {
+	mu   sync.Mutex
+	next uint64
+}
+
+var _ lavender.IDGenerator = new(SequentialIDs)
+
+// NewID implements lavender.IDGenerator.
+func (g *SequentialIDs) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next++
+	return fmt.Sprintf("00000000-0000-7000-8000-%012d", g.next)
+}
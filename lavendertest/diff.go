@@ -0,0 +1,77 @@
+package lavendertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+)
+
+// canonicalEncoder compares events by their deterministic encoding.
+var canonicalEncoder = encoders.NewCanonicalEncoder()
+
+// Diff returns a line diff of the expected and actual events, one event per line prefixed with "-" if it
+// is only expected, "+" if it only happened and " " if both match. It is empty if the events are equal.
+// Events are equal if they have the same name and canonical encoding.
+func Diff[E lavender.Event](expected, actual []E) string {
+	// Longest common subsequence of the events
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			if equal(expected[i], actual[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	if lcs[0][0] == len(expected) && len(expected) == len(actual) {
+		return ""
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case i < len(expected) && j < len(actual) && equal(expected[i], actual[j]):
+			fmt.Fprintf(&diff, "  %s\n", format(expected[i]))
+			i++
+			j++
+		case i < len(expected) && (j == len(actual) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&diff, "- %s\n", format(expected[i]))
+			i++
+		default:
+			fmt.Fprintf(&diff, "+ %s\n", format(actual[j]))
+			j++
+		}
+	}
+	return diff.String()
+}
+
+// equal reports if both events have the same name and canonical encoding.
+func equal[E lavender.Event](a, b E) bool {
+	if a.Name() != b.Name() {
+		return false
+	}
+	dataA, errA := canonicalEncoder.Marshal(a)
+	dataB, errB := canonicalEncoder.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
+
+// format renders an event or aggregate as its name and JSON data.
+func format(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%+v", v))
+	}
+	if named, ok := v.(interface{ Name() lavender.Name }); ok {
+		return fmt.Sprintf("%s %s", named.Name(), data)
+	}
+	return string(data)
+}
@@ -0,0 +1,168 @@
+// Package lavendertest provides Given/When/Then fixtures for aggregate unit tests.
+//
+//	lavendertest.Given(t, example.New(), &example.Create{User: duck}).
+//		When(register(duck)).
+//		ThenError(ErrEmailTaken)
+//
+// Every fixture runs against a fresh repository backed by an encoded InMemoryEventStore, so events and
+// snapshots pass through the encoder like they would in a real store.
+package lavendertest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/thesyncim/go-clone"
+)
+
+// Command handles a command against the current state of an aggregate and returns the resulting events.
+type Command[E lavender.Event, S lavender.Snapshot] interface {
+	Handle(aggregate lavender.CustomAggregate[E, S]) ([]E, error)
+}
+
+// Fixture is a Given/When/Then test of an aggregate.
+type Fixture[E lavender.Event, S lavender.Snapshot] struct {
+	// Encoder encodes the events and snapshots of the fixture, it defaults to CBOR.
+	Encoder encoders.Encoder
+
+	// Clock tells the time recorded by the store and the repository, it defaults to the wall clock.
+	Clock lavender.Clock
+
+	// IDGenerator creates the IDs of stored events and snapshots, it defaults to UUIDv7.
+	IDGenerator lavender.IDGenerator
+
+	// Setup is called with the repository before the given events are added, e.g. to set hooks.
+	Setup func(r *repo.CustomRepository[E, S])
+
+	t         testing.TB
+	aggregate lavender.CustomAggregate[E, S]
+	given     []E
+	when      repo.UpdateFunc[E, S]
+}
+
+// Given creates a fixture for an aggregate with default types and the events that already happened.
+// The aggregate must be freshly created, every run of the fixture works on a copy of it.
+func Given(t testing.TB, aggregate lavender.Aggregate, events ...lavender.Event) *Fixture[lavender.Event, lavender.Snapshot] {
+	return GivenCustom(t, aggregate, events...)
+}
+
+// GivenCustom creates a fixture for an aggregate with custom types and the events that already happened.
+func GivenCustom[E lavender.Event, S lavender.Snapshot](t testing.TB, aggregate lavender.CustomAggregate[E, S], events ...E) *Fixture[E, S] {
+	return &Fixture[E, S]{
+		Encoder:   encoders.NewCBorEncoder(),
+		t:         t,
+		aggregate: aggregate,
+		given:     events,
+	}
+}
+
+// When sets the decision that is run against the aggregate after the given events.
+func (f *Fixture[E, S]) When(decide repo.UpdateFunc[E, S]) *Fixture[E, S] {
+	f.when = decide
+	return f
+}
+
+// WhenCommand sets the command that is handled by the aggregate after the given events.
+func (f *Fixture[E, S]) WhenCommand(command Command[E, S]) *Fixture[E, S] {
+	return f.When(command.Handle)
+}
+
+// Then asserts that the decision succeeds and appends exactly the expected events. It also asserts that
+// the resulting aggregate survives a snapshot round-trip and returns it for further assertions.
+func (f *Fixture[E, S]) Then(expected ...E) lavender.CustomAggregate[E, S] {
+	f.t.Helper()
+	aggregate, actual, err := f.run()
+	if err != nil {
+		f.t.Errorf("expected events, got error: %v", err)
+		return aggregate
+	}
+	if diff := Diff(expected, actual); diff != "" {
+		f.t.Errorf("unexpected events (-expected +actual):\n%s", diff)
+	}
+	f.AssertSnapshot(aggregate)
+	return aggregate
+}
+
+// ThenError asserts that the decision fails with an error matching target and appends no events.
+func (f *Fixture[E, S]) ThenError(target error) {
+	f.t.Helper()
+	_, actual, err := f.run()
+	if err == nil {
+		f.t.Errorf("expected error %v, got none", target)
+	} else if !errors.Is(err, target) {
+		f.t.Errorf("expected error %v, got %v", target, err)
+	}
+	if len(actual) > 0 {
+		f.t.Errorf("expected no events, got:\n%s", Diff(nil, actual))
+	}
+}
+
+// AssertSnapshot asserts that the aggregate ends up in the same state after taking a snapshot, encoding
+// and decoding it and applying it to a fresh aggregate.
+func (f *Fixture[E, S]) AssertSnapshot(aggregate lavender.CustomAggregate[E, S]) {
+	f.t.Helper()
+	snapshot := aggregate.TakeSnapshot()
+	data, err := f.Encoder.Marshal(snapshot)
+	if err != nil {
+		f.t.Errorf("encode snapshot: %v", err)
+		return
+	}
+	decoded := lavender.NewOf(snapshot)
+	if err := f.Encoder.Unmarshal(data, decoded); err != nil {
+		f.t.Errorf("decode snapshot: %v", err)
+		return
+	}
+	restored := f.fresh()
+	restored.ApplySnapshot(decoded)
+	if !reflect.DeepEqual(aggregate, restored) {
+		f.t.Errorf("snapshot round-trip changed the aggregate:\nbefore: %s\nafter:  %s", format(aggregate), format(restored))
+	}
+}
+
+// run adds the given events to a fresh repository, runs the decision and returns the resulting aggregate
+// with the events it appended.
+func (f *Fixture[E, S]) run() (lavender.CustomAggregate[E, S], []E, error) {
+	f.t.Helper()
+	if f.when == nil {
+		f.t.Fatal("lavendertest: When has not been called")
+	}
+	memStore := store.NewInMemoryEncodedStore[E, S](f.Encoder).
+		UseClock(lavender.ClockOr(f.Clock)).
+		UseIDGenerator(lavender.IDGeneratorOr(f.IDGenerator))
+	r := repo.NewRepositoryConstructor[E, S](false, memStore, memStore)
+	r.Clock = lavender.ClockOr(f.Clock)
+	r.AutoSnapshotHook = func(aggregate lavender.CustomAggregate[E, S], items []E) bool {
+		return false
+	}
+	if f.Setup != nil {
+		f.Setup(r)
+	}
+	if len(f.given) > 0 {
+		if err := r.AddEvent(f.fresh(), f.given...); err != nil {
+			f.t.Fatalf("lavendertest: add given events: %v", err)
+		}
+	}
+
+	err := r.Update(f.fresh(), f.when)
+
+	// Replay the stored events, so the aggregate reflects what has been persisted.
+	aggregate := f.fresh()
+	if loadErr := r.LoadAggregate(aggregate); loadErr != nil {
+		f.t.Fatalf("lavendertest: load aggregate: %v", loadErr)
+	}
+	events, loadErr := memStore.LoadEvents(aggregate)
+	if loadErr != nil {
+		f.t.Fatalf("lavendertest: load events: %v", loadErr)
+	}
+	return aggregate, events[min(len(f.given), len(events)):], err
+}
+
+// fresh returns a copy of the fixture's aggregate.
+func (f *Fixture[E, S]) fresh() lavender.CustomAggregate[E, S] {
+	return clone.Clone(f.aggregate).(lavender.CustomAggregate[E, S])
+}
@@ -0,0 +1,85 @@
+package lavendertest_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/lavendertest"
+	"github.com/stretchr/testify/assert"
+)
+
+var errEmailTaken = errors.New("email taken")
+
+// register creates the user unless the email is taken.
+func register(user example.User) func(lavender.Aggregate) ([]lavender.Event, error) {
+	return func(aggregate lavender.Aggregate) ([]lavender.Event, error) {
+		if _, ok := aggregate.(*example.AccountAggregate).Emails[user.Email]; ok {
+			return nil, errEmailTaken
+		}
+		return []lavender.Event{&example.Create{User: user}}, nil
+	}
+}
+
+// recorder records the failures of a fixture instead of failing the test.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestFixture(t *testing.T) {
+	duck := *example.NewUser("duck@ducky.com", "secret")
+	goose := *example.NewUser("goose@ducky.com", "secret")
+
+	aggregate := lavendertest.Given(t, example.New(), &example.Create{User: duck}).
+		When(register(goose)).
+		Then(&example.Create{User: goose})
+	assert.Len(t, aggregate.(*example.AccountAggregate).Users, 2)
+
+	lavendertest.Given(t, example.New(), &example.Create{User: duck}).
+		When(register(duck)).
+		ThenError(errEmailTaken)
+
+	// Failures are reported with a diff.
+	rec := &recorder{TB: t}
+	lavendertest.Given(rec, example.New()).
+		When(register(goose)).
+		Then(&example.Create{User: duck})
+	if assert.Len(t, rec.failures, 1) {
+		assert.Contains(t, rec.failures[0], "- create "+format(duck))
+		assert.Contains(t, rec.failures[0], "+ create "+format(goose))
+	}
+
+	rec = &recorder{TB: t}
+	lavendertest.Given(rec, example.New()).
+		When(register(goose)).
+		ThenError(errEmailTaken)
+	if assert.Len(t, rec.failures, 2) {
+		assert.Contains(t, rec.failures[0], "got none")
+		assert.True(t, strings.HasPrefix(rec.failures[1], "expected no events"))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := &example.Create{User: *example.NewUser("a@t.de", "secret")}
+	b := &example.Create{User: *example.NewUser("b@t.de", "secret")}
+	c := &example.Create{User: *example.NewUser("c@t.de", "secret")}
+
+	assert.Empty(t, lavendertest.Diff([]lavender.Event{a, b}, []lavender.Event{a, b}))
+	assert.Equal(t, "  create "+format(a.User)+"\n- create "+format(b.User)+"\n+ create "+format(c.User)+"\n",
+		lavendertest.Diff([]lavender.Event{a, b}, []lavender.Event{a, c}))
+}
+
+// format renders a user like the JSON of its create event.
+func format(user example.User) string {
+	return fmt.Sprintf(`{"Id":"%s","Email":"%s","Password":"%s"}`, user.Id, user.Email, user.Password)
+}
@@ -1,4 +1,13 @@
 package lavender
 
+import "strings"
+
 // Name represents a identifier for an entity as a string.
 type Name string
+
+// Category returns the stream category of the name, which is the part before the first "-".
+// A name without a "-" is its own category (e.g. "account-42" -> "account", "account" -> "account").
+func (n Name) Category() Name {
+	category, _, _ := strings.Cut(string(n), "-")
+	return Name(category)
+}
@@ -0,0 +1,17 @@
+package lavender
+
+import "reflect"
+
+// NewOf returns a new zero value of the dynamic type of value, allocated if value is a pointer. Stores use it
+// to get a value to decode an event or snapshot into. A nil interface has no dynamic type, its zero value is
+// returned as it is.
+func NewOf[T any](value T) T {
+	typ := reflect.TypeOf(value)
+	if typ == nil {
+		return value
+	}
+	if typ.Kind() == reflect.Pointer {
+		return reflect.New(typ.Elem()).Interface().(T)
+	}
+	return reflect.New(typ).Elem().Interface().(T)
+}
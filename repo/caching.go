@@ -7,7 +7,7 @@ func (r *CustomRepository[E, S]) LoadCache(aggregate lavender.CustomAggregate[E,
 	if !r.aggregateCacheActive {
 		return nil
 	}
-	if cache, ok := r.aggregateCache.Load(aggregate.Name()); ok {
+	if cache, ok := r.aggregateCache.Load(r.cacheKey(aggregate)); ok {
 		item := cache.(lavender.CustomAggregate[E, S])
 		return &item
 	}
@@ -19,5 +19,23 @@ func (r *CustomRepository[E, S]) saveCache(aggregate lavender.CustomAggregate[E,
 	if !r.aggregateCacheActive {
 		return
 	}
-	r.aggregateCache.Store(aggregate.Name(), aggregate)
+	r.aggregateCache.Store(r.cacheKey(aggregate), aggregate)
+}
+
+// InvalidateCache removes an aggregate from the cache, the next load reads it from the stores again.
+func (r *CustomRepository[E, S]) InvalidateCache(aggregate lavender.CustomAggregate[E, S]) {
+	if r.aggregateCache != nil {
+		r.aggregateCache.Delete(r.cacheKey(aggregate))
+	}
+}
+
+// cacheKey partitions the cache by tenant.
+type cacheKey struct {
+	Tenant string
+	Name   lavender.Name
+}
+
+// cacheKey returns the key of the aggregate in the cache.
+func (r *CustomRepository[E, S]) cacheKey(aggregate lavender.CustomAggregate[E, S]) cacheKey {
+	return cacheKey{Tenant: lavender.TenantOf(r.scope(aggregate)), Name: aggregate.Name()}
 }
@@ -0,0 +1,221 @@
+package repo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/thesyncim/go-clone"
+)
+
+// ErrHistoryIncomplete is returned when events of a stream have been cleared or scavenged, so its full
+// history can't be replayed. The snapshot of the stream can't be verified, it isn't known to diverge.
+var ErrHistoryIncomplete = errors.New("history of the stream is incomplete")
+
+// canonicalEncoder compares snapshots by their deterministic encoding.
+var canonicalEncoder = encoders.NewCanonicalEncoder()
+
+// SnapshotDivergence reports that an aggregate loaded from its latest snapshot differs from the aggregate
+// replayed from the full history of its stream.
+type SnapshotDivergence struct {
+	Stream   store.StreamRef // Stream of the aggregate
+	Snapshot string          // ID of the snapshot the aggregate has been loaded from
+	Fields   []FieldDiff     // Fields of the taken snapshots whose values differ
+}
+
+// FieldDiff is a field of the snapshots taken of both aggregates that has different values.
+type FieldDiff struct {
+	Path     string // Path of the field in the canonical encoding, like Users.0.Email
+	Snapshot any    // Value of the aggregate loaded from the snapshot, nil if it is missing
+	Replay   any    // Value of the aggregate replayed from the full history, nil if it is missing
+}
+
+// SnapshotReport is the result of verifying the snapshots of many streams.
+type SnapshotReport struct {
+	Divergences  []SnapshotDivergence // Snapshots that differ from the replay of their stream
+	Unverifiable []store.StreamRef    // Streams whose history is incomplete, see ErrHistoryIncomplete
+}
+
+// String lists the differing fields, one per line as "<path>: <snapshot> != <replay>".
+func (d SnapshotDivergence) String() string {
+	var diff strings.Builder
+	for _, field := range d.Fields {
+		fmt.Fprintf(&diff, "%s: %v != %v\n", field.Path, field.Snapshot, field.Replay)
+	}
+	return diff.String()
+}
+
+// VerifySnapshot builds the aggregate twice, from its latest snapshot and the events stored after it like
+// LoadAggregate does and from the full history of its stream, and compares the snapshots taken of both by
+// their canonical encoding, so TakeSnapshot must be deterministic. It returns nil if they are equal or the
+// stream has no snapshot the repository applies as it is: snapshots that can't be decoded or have another
+// type or schema version are upgraded or discarded by LoadAggregate, and snapshots are ignored if the
+// SignaturePolicy is SignatureReject. The aggregate itself is left unchanged. The event store must implement
+// store.RecordStore, whose records include archived events. If the history of the stream is incomplete, e.g.
+// because AutoSnapshot cleared the events covered by the snapshot, ErrHistoryIncomplete is returned.
+func (r *CustomRepository[E, S]) VerifySnapshot(aggregate lavender.CustomAggregate[E, S]) (*SnapshotDivergence, error) {
+	recordStore, ok := r.EventStore.(store.RecordStore[E, S])
+	if !ok {
+		return nil, store.ErrUnsupported
+	}
+	if r.SignaturePolicy == SignatureReject {
+		return nil, nil
+	}
+	snapshot, stored, err := store.LoadStoredSnapshot(r.SnapshotStore, r.scope(aggregate))
+	if err != nil && !errors.Is(err, store.ErrSnapshotUndecodable) {
+		return nil, err
+	}
+	if err != nil || stored == nil || !applicable(aggregate, snapshot, *stored) {
+		return nil, nil
+	}
+	records, _, err := recordStore.LoadRecords(r.scope(aggregate))
+	if err != nil {
+		return nil, err
+	}
+	for i, record := range records {
+		if record.Sequence != uint64(i+1) {
+			return nil, fmt.Errorf("verify snapshot of %s: %w", aggregate.Name(), ErrHistoryIncomplete)
+		}
+	}
+	if uint64(len(records)) < stored.Sequence {
+		return nil, fmt.Errorf("verify snapshot of %s: %w", aggregate.Name(), ErrHistoryIncomplete)
+	}
+
+	loaded := clone.Clone(aggregate).(lavender.CustomAggregate[E, S])
+	events, err := r.loadEvents(loaded, stored.Sequence)
+	if err != nil {
+		return nil, err
+	}
+	loaded.ApplySnapshot(*snapshot)
+	for _, event := range events {
+		loaded.ApplyEvent(event)
+	}
+	replayed := clone.Clone(aggregate).(lavender.CustomAggregate[E, S])
+	for _, record := range records {
+		if record.Version == aggregate.Version() {
+			replayed.ApplyEvent(record.Event)
+		}
+	}
+
+	fields, err := diffSnapshots(loaded.TakeSnapshot(), replayed.TakeSnapshot())
+	if err != nil || len(fields) == 0 {
+		return nil, err
+	}
+	return &SnapshotDivergence{
+		Stream:   store.StreamRef{Tenant: lavender.TenantOf(r.scope(aggregate)), Name: aggregate.Name()},
+		Snapshot: stored.ID,
+		Fields:   fields,
+	}, nil
+}
+
+// VerifySnapshots verifies the snapshots of all streams of the aggregates, see VerifySnapshot, and reports
+// the divergent and the unverifiable ones. The aggregates are the prototypes of the streams, streams of
+// other aggregates are skipped. A repository scoped to a tenant only verifies the streams of the tenant.
+// Streams that fail to verify don't stop the verification, their errors are joined.
+func (r *CustomRepository[E, S]) VerifySnapshots(aggregates ...lavender.CustomAggregate[E, S]) (SnapshotReport, error) {
+	var report SnapshotReport
+	streams, err := store.ListStreams(r.EventStore, aggregates...)
+	if err != nil {
+		return report, err
+	}
+	var errs []error
+	for _, stream := range streams {
+		if r.Tenant != "" && stream.Tenant != r.Tenant {
+			continue
+		}
+		prototype, err := store.StreamAggregate(stream, aggregates...)
+		if err != nil {
+			continue
+		}
+		divergence, err := r.VerifySnapshot(clone.Clone(prototype).(lavender.CustomAggregate[E, S]))
+		switch {
+		case errors.Is(err, ErrHistoryIncomplete):
+			report.Unverifiable = append(report.Unverifiable, stream)
+		case err != nil:
+			errs = append(errs, fmt.Errorf("verify %s: %w", stream, err))
+		case divergence != nil:
+			report.Divergences = append(report.Divergences, *divergence)
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// diffSnapshots compares the canonical encodings of the snapshots and returns the fields that differ.
+func diffSnapshots(loaded, replayed any) ([]FieldDiff, error) {
+	dataLoaded, err := canonicalEncoder.Marshal(loaded)
+	if err != nil {
+		return nil, err
+	}
+	dataReplayed, err := canonicalEncoder.Marshal(replayed)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(dataLoaded, dataReplayed) {
+		return nil, nil
+	}
+	treeLoaded, err := encoders.DecodeTree(canonicalEncoder.ContentType(), dataLoaded)
+	if err != nil {
+		return nil, err
+	}
+	treeReplayed, err := encoders.DecodeTree(canonicalEncoder.ContentType(), dataReplayed)
+	if err != nil {
+		return nil, err
+	}
+	return diffTree("", treeLoaded, treeReplayed, nil), nil
+}
+
+// diffTree appends the leaves of the decoded trees that differ to the fields.
+func diffTree(path string, loaded, replayed any, fields []FieldDiff) []FieldDiff {
+	switch loaded := loaded.(type) {
+	case map[string]any:
+		if replayed, ok := replayed.(map[string]any); ok {
+			keys := make([]string, 0, len(loaded))
+			for key := range loaded {
+				keys = append(keys, key)
+			}
+			for key := range replayed {
+				if _, ok := loaded[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			slices.Sort(keys)
+			for _, key := range keys {
+				fields = diffTree(fieldPath(path, key), loaded[key], replayed[key], fields)
+			}
+			return fields
+		}
+	case []any:
+		if replayed, ok := replayed.([]any); ok {
+			for i := range max(len(loaded), len(replayed)) {
+				var a, b any
+				if i < len(loaded) {
+					a = loaded[i]
+				}
+				if i < len(replayed) {
+					b = replayed[i]
+				}
+				fields = diffTree(fieldPath(path, strconv.Itoa(i)), a, b, fields)
+			}
+			return fields
+		}
+	}
+	if !reflect.DeepEqual(loaded, replayed) {
+		fields = append(fields, FieldDiff{Path: path, Snapshot: loaded, Replay: replayed})
+	}
+	return fields
+}
+
+// fieldPath appends the key to the path of a field.
+func fieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
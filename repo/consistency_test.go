@@ -0,0 +1,157 @@
+package repo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// roster is an aggregate whose ApplySnapshot forgets to restore the names.
+type roster struct {
+	count int
+	names []string
+}
+
+func (a *roster) Name() lavender.Name             { return "roster" }
+func (a *roster) Version() lavender.Version       { return "1" }
+func (a *roster) Events() []lavender.Event        { return []lavender.Event{new(joined)} }
+func (a *roster) ApplyEvent(event lavender.Event) { event.Apply(a) }
+func (a *roster) TakeSnapshot() lavender.Snapshot {
+	return &rosterSnapshot{Count: a.count, Names: a.names}
+}
+func (a *roster) ApplySnapshot(snapshot lavender.Snapshot) {
+	a.count = snapshot.(*rosterSnapshot).Count
+}
+
+type joined struct {
+	Member string
+}
+
+func (e *joined) Name() lavender.Name { return "joined" }
+func (e *joined) Apply(aggregate lavender.Aggregate) {
+	aggregate.(*roster).count++
+	aggregate.(*roster).names = append(aggregate.(*roster).names, e.Member)
+}
+
+type rosterSnapshot struct {
+	Count int
+	Names []string
+}
+
+func (s *rosterSnapshot) AggregateID() lavender.Name { return "roster" }
+func (s *rosterSnapshot) Version() lavender.Version  { return "1" }
+
+func TestVerifySnapshot(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+		r := repo.NewRepositoryConstructor(false, s, s)
+		r.AutoSnapshotHook = func(lavender.Aggregate, []lavender.Event) bool { return false }
+		for _, tenant := range []string{"", "ducks"} {
+			if err := r.ForTenant(tenant).AddEvent(new(counter), new(counted)); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.ForTenant(tenant).AddEvent(new(roster), &joined{Member: "a"}, &joined{Member: "b"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Streams without snapshots have nothing to verify.
+		divergence, err := r.VerifySnapshot(new(roster))
+		assert.NoError(t, err)
+		assert.Nil(t, divergence)
+
+		for _, aggregate := range []lavender.Aggregate{new(roster), new(counter)} {
+			if err := r.CreateSnapshot(aggregate); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := r.AddEvent(new(roster), &joined{Member: "c"}); err != nil {
+			t.Fatal(err)
+		}
+
+		aggregate := new(roster)
+		divergence, err = r.VerifySnapshot(aggregate)
+		if assert.NoError(t, err) && assert.NotNil(t, divergence) {
+			assert.Equal(t, store.StreamRef{Name: "roster"}, divergence.Stream)
+			assert.NotEmpty(t, divergence.Snapshot)
+			assert.Equal(t, "Names.0: c != a\nNames.1: <nil> != b\nNames.2: <nil> != c\n", divergence.String())
+		}
+		assert.Equal(t, new(roster), aggregate, "the aggregate is left unchanged")
+
+		divergence, err = r.VerifySnapshot(new(counter))
+		assert.NoError(t, err)
+		assert.Nil(t, divergence)
+
+		report, err := r.VerifySnapshots(new(roster), new(counter))
+		if assert.NoError(t, err) && assert.Len(t, report.Divergences, 1) {
+			assert.Equal(t, store.StreamRef{Name: "roster"}, report.Divergences[0].Stream)
+		}
+		report, err = r.ForTenant("ducks").VerifySnapshots(new(roster), new(counter))
+		assert.NoError(t, err)
+		assert.Empty(t, report.Divergences)
+
+		// Cleared events can't be replayed, the snapshot can't be verified but the other streams are.
+		assert.NoError(t, r.ClearEventLog(new(roster)))
+		_, err = r.VerifySnapshot(new(roster))
+		assert.ErrorIs(t, err, repo.ErrHistoryIncomplete)
+		report, err = r.VerifySnapshots(new(roster), new(counter))
+		assert.NoError(t, err)
+		assert.Empty(t, report.Divergences)
+		assert.Equal(t, []store.StreamRef{{Name: "roster"}}, report.Unverifiable)
+
+		// Errors of the store aren't taken for streams without snapshots.
+		failing := errors.New("failing")
+		broken := &store.StoreWrapper[lavender.Event, lavender.Snapshot]{
+			Next: s,
+			HookLoadStored: func(lavender.Aggregate) (*lavender.Snapshot, *store.RawSnapshot, error) {
+				return nil, nil, failing
+			},
+		}
+		_, err = repo.NewRepositoryConstructor(false, broken, broken).VerifySnapshot(new(counter))
+		assert.ErrorIs(t, err, failing)
+		_, err = repo.NewRepositoryConstructor(false, broken, broken).VerifySnapshots(new(roster), new(counter))
+		assert.ErrorIs(t, err, failing)
+	}, new(roster), new(counter))
+}
+
+func TestVerifySnapshotLoadedState(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+		r := repo.NewRepositoryConstructor(false, s, s)
+		r.AutoSnapshotHook = func(lavender.Aggregate, []lavender.Event) bool { return false }
+		if err := r.AddEvent(&counter{schema: 1}, new(counted), new(counted)); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.CreateSnapshot(&counter{schema: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.AddEvent(&counter{schema: 1}, new(counted)); err != nil {
+			t.Fatal(err)
+		}
+
+		// The events covered by the snapshot stay in the log but aren't applied on top of it.
+		divergence, err := r.VerifySnapshot(&counter{schema: 1})
+		assert.NoError(t, err)
+		assert.Nil(t, divergence)
+
+		// Snapshots of another schema are discarded by LoadAggregate, there is nothing to verify.
+		divergence, err = r.VerifySnapshot(&counter{schema: 2})
+		assert.NoError(t, err)
+		assert.Nil(t, divergence)
+	}, new(counter))
+
+	// Snapshots of another type aren't applied.
+	s := store.NewInMemoryStore()
+	r := repo.NewRepositoryConstructor(false, s, s)
+	if err := r.AddEvent(new(counter), new(counted)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveSnapshot(new(counter), &rosterSnapshot{Count: 5}); err != nil {
+		t.Fatal(err)
+	}
+	divergence, err := r.VerifySnapshot(new(counter))
+	assert.NoError(t, err)
+	assert.Nil(t, divergence)
+}
@@ -1,6 +1,21 @@
 package repo
 
-import "github.com/FlauschigDings/lavender"
+import (
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/store"
+)
 
 // AutoSnapshotHook is a hook that can be used to automatically snapshot an aggregate when it has been modified.
 type AutoSnapshotHook[E lavender.Event, S lavender.Snapshot] func(aggregate lavender.CustomAggregate[E, S], items []E) bool
+
+// SignHook is a hook that signs the envelope of an event before the event is stored, see store.Envelope.
+type SignHook[E lavender.Event, S lavender.Snapshot] func(aggregate lavender.CustomAggregate[E, S], envelope store.Envelope, event E) (store.Signature, error)
+
+// SignatureHook is a hook that is called for every unsigned or invalid signed event when the
+// SignaturePolicy is SignatureFlag.
+type SignatureHook[E lavender.Event, S lavender.Snapshot] func(aggregate lavender.CustomAggregate[E, S], event E, err error)
+
+// SnapshotUpgradeHook is a hook that upgrades a stored snapshot that can't be applied as it is, e.g. because
+// it has an older schema version. The snapshot is nil if its data couldn't be decoded, the stored data is
+// available in stored. If the hook returns false, the snapshot is discarded and the events are replayed.
+type SnapshotUpgradeHook[E lavender.Event, S lavender.Snapshot] func(aggregate lavender.CustomAggregate[E, S], stored store.RawSnapshot, snapshot *S) (S, bool, error)
@@ -0,0 +1,77 @@
+package repo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/instrument"
+	"github.com/FlauschigDings/lavender/lavendertest"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentation(t *testing.T) {
+	recorder := instrument.NewRecorder()
+	gormStore := newGormStore(t)
+	gormStore.Instrumentation = recorder
+
+	r := repo.NewRepository(gormStore, gormStore)
+	r.Instrumentation = recorder
+	r.AutoSnapshotHook = func(aggregate lavender.Aggregate, items []lavender.Event) bool {
+		return len(items) >= 2
+	}
+
+	for _, email := range []string{"a@t.de", "b@t.de", "c@t.de"} {
+		if err := r.AddEvent(example.New(), &example.Create{User: *example.NewUser(email, "secret")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := example.Load(r); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without cache the aggregate is loaded from the snapshot.
+	uncached := repo.NewRepositoryConstructor(false, gormStore, gormStore)
+	uncached.Instrumentation = recorder
+	if _, err := example.Load(uncached); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, int64(1), recorder.Counter(instrument.MetricAutoSnapshots))
+	assert.Positive(t, recorder.Counter(instrument.MetricCacheHits))
+	assert.Positive(t, recorder.Counter(instrument.MetricCacheMisses))
+	assert.Positive(t, recorder.Counter(instrument.MetricSnapshotHits))
+	assert.Positive(t, recorder.Counter(instrument.MetricSnapshotMisses))
+	assert.Positive(t, recorder.Counter(instrument.MetricEncodedBytes))
+	assert.Positive(t, recorder.Counter(instrument.MetricDecodedBytes))
+	assert.Len(t, recorder.Histogram(instrument.MetricAppendDuration), 3)
+	assert.NotEmpty(t, recorder.Histogram(instrument.MetricReplayDuration))
+	assert.NotEmpty(t, recorder.Histogram(instrument.MetricEventsLoaded))
+
+	spans := recorder.Spans(instrument.SpanAddEvent)
+	if assert.Len(t, spans, 3) {
+		for _, span := range spans {
+			assert.True(t, span.Ended)
+			assert.NoError(t, span.Err)
+		}
+	}
+	assert.NotEmpty(t, recorder.Spans(instrument.SpanSaveEvents))
+	assert.NotEmpty(t, recorder.Spans(instrument.SpanLoadSnapshot))
+}
+
+func TestInstrumentationClock(t *testing.T) {
+	recorder := instrument.NewRecorder()
+	memStore := store.NewInMemoryStore()
+
+	r := repo.NewRepositoryConstructor(false, memStore, memStore)
+	r.Instrumentation = recorder
+	r.Clock = lavendertest.NewTickingClock(time.Unix(0, 0), time.Second)
+
+	if err := r.AddEvent(example.New(), &example.Create{User: *example.NewUser("a@t.de", "secret")}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []float64{1}, recorder.Histogram(instrument.MetricAppendDuration))
+}
@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/store"
+)
+
+// CloseStream closes the aggregate's stream. It can still be loaded but rejects new events with
+// store.ErrStreamClosed. The event store must implement store.LifecycleStore.
+func (r *CustomRepository[E, S]) CloseStream(aggregate lavender.CustomAggregate[E, S]) error {
+	return r.setStreamState(aggregate, store.StreamClosed)
+}
+
+// DeleteStream soft-deletes the aggregate's stream. Loading it fails with store.ErrStreamDeleted
+// until it is undeleted, its events are kept.
+func (r *CustomRepository[E, S]) DeleteStream(aggregate lavender.CustomAggregate[E, S]) error {
+	return r.setStreamState(aggregate, store.StreamDeleted)
+}
+
+// TombstoneStream hard-deletes the aggregate's stream. Its events and snapshots are removed and the
+// stream can never be used again.
+func (r *CustomRepository[E, S]) TombstoneStream(aggregate lavender.CustomAggregate[E, S]) error {
+	return r.setStreamState(aggregate, store.StreamTombstoned)
+}
+
+// UndeleteStream makes a soft-deleted stream active again.
+func (r *CustomRepository[E, S]) UndeleteStream(aggregate lavender.CustomAggregate[E, S]) error {
+	return r.setStreamState(aggregate, store.StreamActive)
+}
+
+// setStreamState changes the state of the aggregate's stream and drops it from the cache.
+func (r *CustomRepository[E, S]) setStreamState(aggregate lavender.CustomAggregate[E, S], state store.StreamState) error {
+	r.InvalidateCache(aggregate)
+	return store.SetState(r.EventStore, r.scope(aggregate), state)
+}
+
+// SetStreamMetadata replaces the metadata of the aggregate's stream, which limits the events that are
+// replayed, and drops the aggregate from the cache. The event store must implement store.MetadataStore.
+func (r *CustomRepository[E, S]) SetStreamMetadata(aggregate lavender.CustomAggregate[E, S], metadata store.StreamMetadata) error {
+	r.InvalidateCache(aggregate)
+	return store.SetMetadata(r.EventStore, r.scope(aggregate), metadata)
+}
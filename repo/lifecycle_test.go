@@ -0,0 +1,47 @@
+package repo_test
+
+import (
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamLifecycle(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+		r := repo.NewRepository(s, s)
+		if err := r.AddEvent(example.New(), createUser("a@ducks.de")); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.NoError(t, r.CloseStream(example.New()))
+		assert.ErrorIs(t, r.AddEvent(example.New(), createUser("b@ducks.de")), store.ErrStreamClosed)
+		aggregate, err := example.Load(r)
+		if assert.NoError(t, err) {
+			assert.Len(t, aggregate.Emails, 1)
+		}
+
+		assert.NoError(t, r.DeleteStream(example.New()))
+		_, err = example.Load(r)
+		assert.ErrorIs(t, err, store.ErrStreamDeleted)
+
+		// Undeleting reopens the stream.
+		assert.NoError(t, r.UndeleteStream(example.New()))
+		assert.NoError(t, r.AddEvent(example.New(), createUser("b@ducks.de")))
+		aggregate, err = example.Load(r)
+		if assert.NoError(t, err) {
+			assert.Len(t, aggregate.Emails, 2)
+		}
+
+		assert.NoError(t, r.TombstoneStream(example.New()))
+		_, err = example.Load(r)
+		assert.ErrorIs(t, err, store.ErrStreamDeleted)
+		assert.ErrorIs(t, r.UndeleteStream(example.New()), store.ErrInvalidTransition)
+
+		// Other tenants keep their own stream.
+		assert.NoError(t, r.ForTenant("geese").AddEvent(example.New(), createUser("a@geese.de")))
+	})
+}
@@ -0,0 +1,41 @@
+package repo_test
+
+import (
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/store"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newGormStore creates a GormStore on an in-memory SQLite database with the example aggregate registered.
+func newGormStore(t *testing.T) *store.GormStore[lavender.Event, lavender.Snapshot] {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store.NewGormStore(db).RegisterAggregates(example.New())
+}
+
+// forEachStore runs the test against a new in-memory store and a new GormStore, which has the example
+// aggregate and the given aggregates registered.
+func forEachStore(t *testing.T, test func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]), aggregates ...lavender.Aggregate) {
+	stores := map[string]func(t *testing.T) store.Store[lavender.Event, lavender.Snapshot]{
+		"memory": func(t *testing.T) store.Store[lavender.Event, lavender.Snapshot] {
+			return store.NewInMemoryStore()
+		},
+		"gorm": func(t *testing.T) store.Store[lavender.Event, lavender.Snapshot] {
+			return newGormStore(t).RegisterAggregates(aggregates...)
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore(t))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/instrument"
+	"github.com/FlauschigDings/lavender/store"
+)
+
+// ReplayAggregate rebuilds the aggregate from the full history of its stream, including archived events,
+// without using snapshots or the cache. The event store must implement store.HistoryStore.
+func (r *CustomRepository[E, S]) ReplayAggregate(aggregate lavender.CustomAggregate[E, S]) error {
+	return r.LoadAggregateAt(aggregate, time.Time{})
+}
+
+// LoadAggregateAt rebuilds the aggregate as it has been at the given time by replaying the events of its
+// stream stored up to then, including archived events. Snapshots and the cache are not used, and signatures
+// are not checked. The event store must implement store.HistoryStore.
+func (r *CustomRepository[E, S]) LoadAggregateAt(aggregate lavender.CustomAggregate[E, S], at time.Time) (err error) {
+	instrumentation := r.instrumentation()
+	attr := instrument.Attr("aggregate", aggregate.Name())
+	span := instrumentation.StartSpan(instrument.SpanLoadAggregate, attr)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	// Deleted streams can't be loaded
+	state, err := store.StateOf(r.EventStore, r.scope(aggregate))
+	if err != nil {
+		return err
+	}
+	if state.Deleted() {
+		return store.ErrStreamDeleted
+	}
+
+	events, err := store.LoadHistory(r.EventStore, r.scope(aggregate), at)
+	if err != nil {
+		return err
+	}
+	start := r.now()
+	for _, event := range events {
+		aggregate.ApplyEvent(event)
+	}
+	r.since(instrument.MetricReplayDuration, start, attr)
+	instrumentation.Record(instrument.MetricEventsLoaded, float64(len(events)), attr)
+	return nil
+}
@@ -0,0 +1,38 @@
+package repo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/lavendertest"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplay(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := lavendertest.NewFakeClock(start)
+	s := store.NewInMemoryStore().UseClock(clock)
+	r := repo.NewRepository(s, s)
+	for _, email := range []string{"a@ducks.de", "b@ducks.de"} {
+		if err := r.AddEvent(example.New(), createUser(email)); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Hour)
+	}
+
+	aggregate := example.New()
+	if assert.NoError(t, r.LoadAggregateAt(aggregate, start.Add(30*time.Minute))) {
+		assert.ElementsMatch(t, []string{"a@ducks.de"}, keys(aggregate.Emails))
+	}
+	aggregate = example.New()
+	if assert.NoError(t, r.ReplayAggregate(aggregate)) {
+		assert.ElementsMatch(t, []string{"a@ducks.de", "b@ducks.de"}, keys(aggregate.Emails))
+	}
+
+	// Stores without history can't replay.
+	wrapped := repo.NewRepository(store.Wrap(s, s), s)
+	assert.ErrorIs(t, wrapped.ReplayAggregate(example.New()), store.ErrUnsupported)
+}
@@ -1,10 +1,15 @@
 package repo
 
 import (
+	"crypto/ed25519"
+	"errors"
 	"sync"
+	"time"
 
 	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/instrument"
 	"github.com/FlauschigDings/lavender/store"
+	"github.com/thesyncim/go-clone"
 )
 
 // Repository is an alias for CustomRepository with lavender.Event and lavender.Snapshot types.
@@ -13,7 +18,7 @@ type Repository = CustomRepository[lavender.Event, lavender.Snapshot]
 // CustomRepository represents a repository that handles event and snapshot storage, including caching and auto-snapshot logic.
 type CustomRepository[E lavender.Event, S lavender.Snapshot] struct {
 	// aggregateCache is a thread-safe map to cache aggregates for faster access.
-	aggregateCache sync.Map // map[lavender.Name]lavender.CustomAggregate[E, S]
+	aggregateCache *sync.Map // map[cacheKey]lavender.CustomAggregate[E, S]
 
 	// aggregateCacheActive controls whether aggregate caching is enabled for faster access.
 	aggregateCacheActive bool
@@ -26,6 +31,42 @@ type CustomRepository[E lavender.Event, S lavender.Snapshot] struct {
 
 	// AutoSnapshotHook defines the logic for when to automatically create snapshots.
 	AutoSnapshotHook AutoSnapshotHook[E, S]
+
+	// SnapshotUpgradeHook upgrades stored snapshots that can't be applied as they are. Without it, such
+	// snapshots are discarded and the aggregate is rebuilt from its events.
+	SnapshotUpgradeHook SnapshotUpgradeHook[E, S]
+
+	// SignHook signs every added event if set. The signature covers the stream, sequence and ID of the event,
+	// so the event store must implement store.SignedEventStore and store.ConcurrentEventStore.
+	SignHook SignHook[E, S]
+
+	// SignaturePolicy defines how event signatures are checked while loading an aggregate.
+	SignaturePolicy SignaturePolicy
+
+	// SignatureKeys are the public keys, by key ID, used to verify event signatures.
+	SignatureKeys map[string]ed25519.PublicKey
+
+	// SignatureHook is called for unsigned or invalid events when the SignaturePolicy is SignatureFlag.
+	SignatureHook SignatureHook[E, S]
+
+	// Instrumentation receives the spans and metrics of the repository.
+	Instrumentation instrument.Instrumentation
+
+	// Clock tells the time used to measure replays and appends.
+	Clock lavender.Clock
+
+	// IDGenerator creates the IDs of signed events, which are part of their signed envelopes.
+	IDGenerator lavender.IDGenerator
+
+	// Tenant scopes all streams of the repository to a tenant. Aggregates implementing
+	// lavender.TenantScoped are used with their own tenant if it is empty.
+	Tenant string
+
+	// UpdateAttempts is the number of times Update tries to append its events before giving up.
+	UpdateAttempts int
+
+	// UpdateBackoff is the base delay between Update attempts. It doubles per attempt and is jittered.
+	UpdateBackoff time.Duration
 }
 
 // NewRepository creates a new CustomRepository with event and snapshot stores, and caching enabled by default.
@@ -37,9 +78,14 @@ func NewRepository[E lavender.Event, S lavender.Snapshot](eventStore store.Event
 // It accepts a boolean flag to activate or deactivate the aggregate cache.
 func NewRepositoryConstructor[E lavender.Event, S lavender.Snapshot](aggregateCacheActive bool, eventStore store.EventStore[E, S], snapshotStore store.SnapshotStore[E, S]) *CustomRepository[E, S] {
 	return &CustomRepository[E, S]{
+		aggregateCache:       new(sync.Map),
 		aggregateCacheActive: aggregateCacheActive,
 		EventStore:           eventStore,
 		SnapshotStore:        snapshotStore,
+		Instrumentation:      instrument.Noop{},
+		Clock:                lavender.SystemClock{},
+		UpdateAttempts:       5,
+		UpdateBackoff:        10 * time.Millisecond,
 		AutoSnapshotHook: func(aggregate lavender.CustomAggregate[E, S], items []E) bool {
 			// Hook to decide when to snapshot based on the number of events
 			return len(items) > 100
@@ -48,55 +94,109 @@ func NewRepositoryConstructor[E lavender.Event, S lavender.Snapshot](aggregateCa
 }
 
 // AutoSnapshot checks whether the aggregate should be snapshotted based on the number of events.
-// If the condition is met, it creates a snapshot and clears the event log.
+// If the condition is met, it creates a snapshot of the stored state and clears the events it covers.
+// The aggregate itself isn't changed.
 func (r *CustomRepository[E, S]) AutoSnapshot(aggregate lavender.CustomAggregate[E, S]) error {
 	// Load the aggregate's events
-	items, err := r.EventStore.LoadEvents(aggregate)
+	items, err := r.EventStore.LoadEvents(r.scope(aggregate))
 	if err != nil {
 		return err
 	}
 
-	// Apply snapshot if the auto-snapshot hook condition is met
-	if r.AutoSnapshotHook(aggregate, items) {
-		if err := r.CreateSnapshot(aggregate); err != nil {
+	// Apply snapshot if the auto-snapshot hook condition is met, snapshots aren't signed so signed events are
+	// never cleared if signatures are required
+	if r.SignaturePolicy != SignatureReject && r.AutoSnapshotHook(aggregate, items) {
+		r.instrumentation().Count(instrument.MetricAutoSnapshots, 1, instrument.Attr("aggregate", aggregate.Name()))
+		head, pinned, err := r.createSnapshot(clone.Clone(aggregate).(lavender.CustomAggregate[E, S]))
+		if errors.Is(err, store.ErrConflict) {
+			// Events have been appended while snapshotting, a later call snapshots them
+			return nil
+		}
+		if err != nil {
 			return err
 		}
-		// Clear the event log after snapshotting
-		err := r.EventStore.ClearEvents(aggregate)
-		return err
+		// Clear the event log after snapshotting, events appended after the snapshot are kept
+		if pinned {
+			if err := store.ClearUntil(r.EventStore, r.scope(aggregate), head); !errors.Is(err, store.ErrUnsupported) {
+				return err
+			}
+		}
+		return r.EventStore.ClearEvents(r.scope(aggregate))
 	}
 	return nil
 }
 
 // LoadAggregate loads the aggregate's state from either cache, snapshot, or events.
-func (r *CustomRepository[E, S]) LoadAggregate(aggregate lavender.CustomAggregate[E, S]) error {
+func (r *CustomRepository[E, S]) LoadAggregate(aggregate lavender.CustomAggregate[E, S]) (err error) {
+	instrumentation := r.instrumentation()
+	attr := instrument.Attr("aggregate", aggregate.Name())
+	span := instrumentation.StartSpan(instrument.SpanLoadAggregate, attr)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	// Deleted streams can't be loaded
+	state, err := store.StateOf(r.EventStore, r.scope(aggregate))
+	if err != nil {
+		return err
+	}
+	if state.Deleted() {
+		return store.ErrStreamDeleted
+	}
+
 	// First, try to load from cache if caching is enabled
 	if cache := r.LoadCache(aggregate); cache != nil {
+		instrumentation.Count(instrument.MetricCacheHits, 1, attr)
 		aggregate.ApplySnapshot((*cache).TakeSnapshot())
 		return nil
 	}
-
-	// Load the snapshot from the snapshot store
-	snapshot, err := r.SnapshotStore.LoadSnapshot(aggregate)
-	if err != nil {
-		return err
+	if r.aggregateCacheActive {
+		instrumentation.Count(instrument.MetricCacheMisses, 1, attr)
 	}
 
-	// Apply the snapshot if it exists
-	if snapshot != nil {
-		aggregate.ApplySnapshot(*snapshot)
+	// Load the snapshot from the snapshot store, snapshots that can't be applied are upgraded or discarded.
+	// Snapshots aren't signed, if signatures are required the aggregate is built from its events only.
+	var snapshot storedSnapshot[S]
+	if r.SignaturePolicy != SignatureReject {
+		if snapshot, err = r.loadSnapshot(aggregate); err != nil {
+			return err
+		}
 	}
 
-	// Load events and apply them to the aggregate
-	events, err := r.EventStore.LoadEvents(aggregate)
+	// Load the events stored after the snapshot to apply them to the aggregate
+	events, err := r.loadEvents(aggregate, snapshot.Sequence)
 	if err != nil {
 		return err
 	}
+	if snapshot.Discarded != nil {
+		if events, err = r.replayEvents(aggregate, *snapshot.Discarded, events); err != nil {
+			return err
+		}
+	}
+
+	start := r.now()
+	// Apply the snapshot if it exists
+	if snapshot.Snapshot != nil {
+		instrumentation.Count(instrument.MetricSnapshotHits, 1, attr)
+		aggregate.ApplySnapshot(*snapshot.Snapshot)
+	} else {
+		instrumentation.Count(instrument.MetricSnapshotMisses, 1, attr)
+	}
 
 	// Apply all loaded events to the aggregate
 	for _, event := range events {
 		aggregate.ApplyEvent(event)
 	}
+	r.since(instrument.MetricReplayDuration, start, attr)
+	instrumentation.Record(instrument.MetricEventsLoaded, float64(len(events)), attr)
+
+	// Replace a discarded snapshot by one of the replayed state
+	if snapshot.Discarded != nil {
+		if err := r.resnapshot(aggregate, *snapshot.Discarded); err != nil {
+			return err
+		}
+	}
 
 	// Cache the aggregate for future access
 	r.saveCache(aggregate)
@@ -104,29 +204,66 @@ func (r *CustomRepository[E, S]) LoadAggregate(aggregate lavender.CustomAggregat
 }
 
 // CreateSnapshot creates a snapshot of the aggregate's current state and stores it in the snapshot store.
-func (r *CustomRepository[E, S]) CreateSnapshot(aggregate lavender.CustomAggregate[E, S]) error {
-	// Ensure the aggregate is fully loaded before snapshotting
+// The aggregate is loaded from the stores first, bypassing the cache. If the snapshot store implements
+// store.ConcurrentSnapshotStore, store.ErrConflict is returned when events are appended meanwhile.
+func (r *CustomRepository[E, S]) CreateSnapshot(aggregate lavender.CustomAggregate[E, S]) (err error) {
+	_, _, err = r.createSnapshot(aggregate)
+	return err
+}
+
+// createSnapshot loads the aggregate and stores a snapshot of it. If the stores support it, the snapshot is
+// pinned to the stream head read before loading and pinned is true.
+func (r *CustomRepository[E, S]) createSnapshot(aggregate lavender.CustomAggregate[E, S]) (head uint64, pinned bool, err error) {
+	span := r.instrumentation().StartSpan(instrument.SpanCreateSnapshot, instrument.Attr("aggregate", aggregate.Name()))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	// Read the stream head before loading, the snapshot must not claim events appended in between
+	head, err = store.StreamSequence(r.EventStore, r.scope(aggregate))
+	if err != nil && !errors.Is(err, store.ErrUnsupported) {
+		return 0, false, err
+	}
+	pinned = err == nil
+
+	// Ensure the aggregate is fully loaded before snapshotting, the cached aggregate may miss events
+	// appended by other processes
+	r.InvalidateCache(aggregate)
 	if err := r.LoadAggregate(aggregate); err != nil {
-		return err
+		return 0, false, err
 	}
 
 	// Take a snapshot of the aggregate
 	snapshot := aggregate.TakeSnapshot()
 
 	// Save the snapshot in the snapshot store
-	err := r.SnapshotStore.SaveSnapshot(aggregate, snapshot)
-	return err
+	if pinned {
+		err := store.SaveSnapshotAt(r.SnapshotStore, r.scope(aggregate), snapshot, head)
+		if !errors.Is(err, store.ErrUnsupported) {
+			return head, true, err
+		}
+	}
+	return 0, false, r.SnapshotStore.SaveSnapshot(r.scope(aggregate), snapshot)
 }
 
 // ClearEventLog clears the event log for the given aggregate in the event store.
 func (r *CustomRepository[E, S]) ClearEventLog(aggregate lavender.CustomAggregate[E, S]) error {
 	// Clear the events stored for the aggregate
-	err := r.EventStore.ClearEvents(aggregate)
+	err := r.EventStore.ClearEvents(r.scope(aggregate))
 	return err
 }
 
 // AddEvent appends events to the aggregate, potentially triggering a snapshot based on the auto-snapshot condition.
-func (r *CustomRepository[E, S]) AddEvent(aggregate lavender.CustomAggregate[E, S], events ...E) error {
+func (r *CustomRepository[E, S]) AddEvent(aggregate lavender.CustomAggregate[E, S], events ...E) (err error) {
+	instrumentation := r.instrumentation()
+	attr := instrument.Attr("aggregate", aggregate.Name())
+	span := instrumentation.StartSpan(instrument.SpanAddEvent, attr)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	// Automatically snapshot the aggregate if needed
 	if err := r.AutoSnapshot(aggregate); err != nil {
 		return err
@@ -145,6 +282,54 @@ func (r *CustomRepository[E, S]) AddEvent(aggregate lavender.CustomAggregate[E,
 	// Cache the aggregate for future access
 	r.saveCache(aggregate)
 
-	// Save the new events to the event store
-	return r.EventStore.SaveEvents(aggregate, events)
+	// Save the new events to the event store, signed if a sign hook is set
+	defer r.since(instrument.MetricAppendDuration, r.now(), attr)
+	if err := r.saveEvents(aggregate, events, nil); err != nil {
+		// Drop the cached aggregate, it contains events that haven't been stored
+		r.InvalidateCache(aggregate)
+		return err
+	}
+	return nil
+}
+
+// saveEvents stores the events, signed if a sign hook is set. If expected is set the events are only
+// stored if the stream is still at the expected sequence.
+func (r *CustomRepository[E, S]) saveEvents(aggregate lavender.CustomAggregate[E, S], events []E, expected *uint64) error {
+	var signatures []store.Signature
+	if r.SignHook != nil {
+		// The signatures bind the events to their sequences, so they are only stored at the signed head
+		if expected == nil {
+			head, err := store.StreamSequence(r.EventStore, r.scope(aggregate))
+			if errors.Is(err, store.ErrUnsupported) {
+				return ErrSigningUnsupported
+			}
+			if err != nil {
+				return err
+			}
+			expected = &head
+		}
+		var err error
+		if signatures, err = r.signEvents(aggregate, events, *expected); err != nil {
+			return err
+		}
+	}
+	if expected != nil {
+		return store.SaveAt(r.EventStore, r.scope(aggregate), events, signatures, *expected)
+	}
+	return r.EventStore.SaveEvents(r.scope(aggregate), events)
+}
+
+// instrumentation returns the instrumentation of the repository, which defaults to instrument.Noop.
+func (r *CustomRepository[E, S]) instrumentation() instrument.Instrumentation {
+	return instrument.Or(r.Instrumentation)
+}
+
+// now returns the time of the repository's clock, which defaults to lavender.SystemClock.
+func (r *CustomRepository[E, S]) now() time.Time {
+	return lavender.ClockOr(r.Clock).Now()
+}
+
+// since records the seconds elapsed on the repository's clock since start in a histogram.
+func (r *CustomRepository[E, S]) since(name string, start time.Time, attrs ...instrument.Attribute) {
+	r.instrumentation().Record(name, r.now().Sub(start).Seconds(), attrs...)
 }
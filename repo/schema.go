@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/instrument"
+	"github.com/FlauschigDings/lavender/store"
+)
+
+// ErrSnapshotIncompatible is returned when a stored snapshot can't be applied and the aggregate can't be
+// rebuilt from its events either, because the events covered by the snapshot have been cleared.
+var ErrSnapshotIncompatible = errors.New("snapshot is incompatible and the events it covers are gone")
+
+// storedSnapshot is the snapshot an aggregate is loaded from.
+type storedSnapshot[S lavender.Snapshot] struct {
+	Snapshot  *S                 // Snapshot to apply, nil if there is none or it has been discarded
+	Sequence  uint64             // Sequence of the last event covered by the snapshot, 0 if the store doesn't keep it
+	Discarded *store.RawSnapshot // Stored snapshot that can't be applied, it is replaced by a replay
+}
+
+// loadSnapshot loads the latest snapshot of the aggregate. A snapshot that can't be decoded, has another
+// type or another schema version than the aggregate's current snapshots is upgraded by the
+// SnapshotUpgradeHook or discarded.
+func (r *CustomRepository[E, S]) loadSnapshot(aggregate lavender.CustomAggregate[E, S]) (storedSnapshot[S], error) {
+	snapshot, stored, err := store.LoadStoredSnapshot(r.SnapshotStore, r.scope(aggregate))
+	if err != nil && !errors.Is(err, store.ErrSnapshotUndecodable) {
+		return storedSnapshot[S]{}, err
+	}
+	if stored == nil {
+		return storedSnapshot[S]{Snapshot: snapshot}, nil
+	}
+	if applicable(aggregate, snapshot, *stored) {
+		return storedSnapshot[S]{Snapshot: snapshot, Sequence: stored.Sequence}, nil
+	}
+
+	// Snapshots of a newer schema are left to the code that wrote them
+	attr := instrument.Attr("aggregate", aggregate.Name())
+	if r.SnapshotUpgradeHook != nil && stored.Schema <= lavender.SchemaVersionOf(aggregate.TakeSnapshot()) {
+		upgraded, ok, err := r.SnapshotUpgradeHook(aggregate, *stored, snapshot)
+		if err != nil {
+			return storedSnapshot[S]{}, fmt.Errorf("upgrade snapshot of %s: %w", aggregate.Name(), err)
+		}
+		if ok {
+			if err := store.ReplaceSnapshot(r.SnapshotStore, r.scope(aggregate), stored.ID, upgraded); err != nil {
+				return storedSnapshot[S]{}, err
+			}
+			r.instrumentation().Count(instrument.MetricSnapshotsUpgraded, 1, attr)
+			return storedSnapshot[S]{Snapshot: &upgraded, Sequence: stored.Sequence}, nil
+		}
+	}
+	r.instrumentation().Count(instrument.MetricSnapshotsDiscarded, 1, attr)
+	return storedSnapshot[S]{Discarded: stored}, nil
+}
+
+// applicable reports if the stored snapshot has been decoded and has the type and schema version of the
+// aggregate's current snapshots, so it can be applied as it is.
+func applicable[E lavender.Event, S lavender.Snapshot](aggregate lavender.CustomAggregate[E, S], snapshot *S, stored store.RawSnapshot) bool {
+	current := aggregate.TakeSnapshot()
+	return snapshot != nil && stored.Schema == lavender.SchemaVersionOf(current) && sameType(*snapshot, current)
+}
+
+// replayEvents returns the events replacing the discarded snapshot, the replay must cover the whole stream.
+// If some of the loaded events are missing, e.g. because they have been archived or the stream metadata
+// hides them, the history of the stream is replayed if the event store implements store.HistoryStore.
+// ErrSnapshotIncompatible is returned if the history is incomplete as well, e.g. because AutoSnapshot
+// cleared the events or they have been scavenged.
+func (r *CustomRepository[E, S]) replayEvents(aggregate lavender.CustomAggregate[E, S], discarded store.RawSnapshot, events []E) ([]E, error) {
+	if complete, err := r.completeReplay(aggregate, discarded, len(events)); err != nil || complete {
+		return events, err
+	}
+	history, err := store.LoadHistory(r.EventStore, r.scope(aggregate), time.Time{})
+	if err != nil && !errors.Is(err, store.ErrUnsupported) {
+		return nil, err
+	}
+	if err == nil {
+		if complete, err := r.completeReplay(aggregate, discarded, len(history)); err != nil || complete {
+			return history, err
+		}
+	}
+	return nil, fmt.Errorf("load %s: %w", aggregate.Name(), ErrSnapshotIncompatible)
+}
+
+// completeReplay reports if the replayed number of events covers the whole stream, or at least the events
+// covered by the discarded snapshot if the event store doesn't know the head of the stream.
+func (r *CustomRepository[E, S]) completeReplay(aggregate lavender.CustomAggregate[E, S], discarded store.RawSnapshot, replayed int) (bool, error) {
+	head, err := store.StreamSequence(r.EventStore, r.scope(aggregate))
+	if errors.Is(err, store.ErrUnsupported) {
+		return uint64(replayed) >= discarded.Sequence, nil
+	}
+	return uint64(replayed) == head, err
+}
+
+// resnapshot stores a snapshot of the replayed aggregate in place of the discarded one, unless the discarded
+// snapshot has a newer schema than the aggregate's.
+func (r *CustomRepository[E, S]) resnapshot(aggregate lavender.CustomAggregate[E, S], discarded store.RawSnapshot) error {
+	snapshot := aggregate.TakeSnapshot()
+	if discarded.Schema > lavender.SchemaVersionOf(snapshot) {
+		return nil
+	}
+	return r.SnapshotStore.SaveSnapshot(r.scope(aggregate), snapshot)
+}
+
+// sameType reports if the snapshot has the type of the current snapshot.
+func sameType(snapshot, current any) bool {
+	want := reflect.TypeOf(current)
+	return want == nil || reflect.TypeOf(snapshot) == want
+}
@@ -0,0 +1,206 @@
+package repo_test
+
+import (
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// counter is an aggregate whose snapshots carry the schema version of the aggregate.
+type counter struct {
+	schema int
+	count  int
+}
+
+func (a *counter) Name() lavender.Name             { return "counter" }
+func (a *counter) Version() lavender.Version       { return "1" }
+func (a *counter) Events() []lavender.Event        { return []lavender.Event{new(counted)} }
+func (a *counter) ApplyEvent(event lavender.Event) { event.Apply(a) }
+func (a *counter) TakeSnapshot() lavender.Snapshot {
+	return &counterSnapshot{Count: a.count, Schema: a.schema}
+}
+func (a *counter) ApplySnapshot(snapshot lavender.Snapshot) {
+	a.count = snapshot.(*counterSnapshot).Count
+}
+
+type counted struct{}
+
+func (e *counted) Name() lavender.Name                { return "counted" }
+func (e *counted) Apply(aggregate lavender.Aggregate) { aggregate.(*counter).count++ }
+
+type counterSnapshot struct {
+	Count  int
+	Schema int
+}
+
+func (s *counterSnapshot) AggregateID() lavender.Name { return "counter" }
+func (s *counterSnapshot) Version() lavender.Version  { return "1" }
+func (s *counterSnapshot) SchemaVersion() int         { return s.Schema }
+
+func TestSnapshotSchema(t *testing.T) {
+	// setup stores three events and a snapshot of schema 1.
+	setup := func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) *repo.Repository {
+		r := repo.NewRepositoryConstructor(false, s, s)
+		r.AutoSnapshotHook = func(lavender.Aggregate, []lavender.Event) bool { return false }
+		if err := r.AddEvent(&counter{schema: 1}, new(counted), new(counted), new(counted)); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.CreateSnapshot(&counter{schema: 1}); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		forEachStore(t, func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+			r := setup(t, s)
+			aggregate := &counter{schema: 2}
+			if assert.NoError(t, r.LoadAggregate(aggregate)) {
+				assert.Equal(t, 3, aggregate.count)
+			}
+
+			// The replayed state has been snapshotted with the new schema.
+			_, stored, err := store.LoadStoredSnapshot[lavender.Event, lavender.Snapshot](s, aggregate)
+			if assert.NoError(t, err) && assert.NotNil(t, stored) {
+				assert.Equal(t, 2, stored.Schema)
+				assert.Equal(t, uint64(3), stored.Sequence)
+			}
+
+			// The new snapshot covers the replayed events, they aren't applied again.
+			aggregate = &counter{schema: 2}
+			if assert.NoError(t, r.LoadAggregate(aggregate)) {
+				assert.Equal(t, 3, aggregate.count)
+			}
+			if assert.NoError(t, r.AddEvent(&counter{schema: 2}, new(counted))) {
+				aggregate = &counter{schema: 2}
+				assert.NoError(t, r.LoadAggregate(aggregate))
+				assert.Equal(t, 4, aggregate.count)
+			}
+
+			// Older code keeps away from snapshots of a newer schema.
+			assert.NoError(t, r.LoadAggregate(&counter{schema: 1}))
+			_, stored, _ = store.LoadStoredSnapshot[lavender.Event, lavender.Snapshot](s, aggregate)
+			assert.Equal(t, 2, stored.Schema)
+		}, new(counter))
+	})
+
+	t.Run("cleared events", func(t *testing.T) {
+		forEachStore(t, func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+			r := setup(t, s)
+			assert.NoError(t, r.ClearEventLog(&counter{}))
+			assert.ErrorIs(t, r.LoadAggregate(&counter{schema: 2}), repo.ErrSnapshotIncompatible)
+		}, new(counter))
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		forEachStore(t, func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+			r := setup(t, s)
+			assert.NoError(t, r.ClearEventLog(&counter{}))
+			_, before, err := store.LoadStoredSnapshot[lavender.Event, lavender.Snapshot](s, &counter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.SnapshotUpgradeHook = func(aggregate lavender.Aggregate, stored store.RawSnapshot, snapshot *lavender.Snapshot) (lavender.Snapshot, bool, error) {
+				assert.Equal(t, 1, stored.Schema)
+				if !assert.NotNil(t, snapshot) {
+					return nil, false, nil
+				}
+				return &counterSnapshot{Count: (*snapshot).(*counterSnapshot).Count * 10, Schema: 2}, true, nil
+			}
+			aggregate := &counter{schema: 2}
+			if assert.NoError(t, r.LoadAggregate(aggregate)) {
+				assert.Equal(t, 30, aggregate.count)
+			}
+
+			// The snapshot has been upgraded in place.
+			_, after, err := store.LoadStoredSnapshot[lavender.Event, lavender.Snapshot](s, aggregate)
+			if assert.NoError(t, err) && assert.NotNil(t, after) {
+				assert.Equal(t, before.ID, after.ID)
+				assert.Equal(t, before.Sequence, after.Sequence)
+				assert.Equal(t, 2, after.Schema)
+			}
+		}, new(counter))
+	})
+}
+
+func TestSnapshotCoveredEvents(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+		r := repo.NewRepositoryConstructor(false, s, s)
+		r.AutoSnapshotHook = func(lavender.Aggregate, []lavender.Event) bool { return false }
+		if err := r.AddEvent(&counter{}, new(counted), new(counted)); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.CreateSnapshot(&counter{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.AddEvent(&counter{}, new(counted)); err != nil {
+			t.Fatal(err)
+		}
+
+		// Only the event stored after the snapshot is applied on top of it.
+		aggregate := &counter{}
+		if assert.NoError(t, r.LoadAggregate(aggregate)) {
+			assert.Equal(t, 3, aggregate.count)
+		}
+	}, new(counter))
+}
+
+func TestSnapshotArchivedEvents(t *testing.T) {
+	s := newGormStore(t).RegisterAggregates(new(counter)).UseArchive(t.TempDir())
+	r := repo.NewRepositoryConstructor(false, s, s)
+	r.AutoSnapshotHook = func(lavender.Aggregate, []lavender.Event) bool { return false }
+	if err := r.AddEvent(&counter{schema: 1}, new(counted), new(counted), new(counted)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CreateSnapshot(&counter{schema: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ArchiveEvents(-1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The archived events aren't loaded with the stream, the discarded snapshot is replaced by a replay of
+	// its history.
+	aggregate := &counter{schema: 2}
+	if assert.NoError(t, r.LoadAggregate(aggregate)) {
+		assert.Equal(t, 3, aggregate.count)
+	}
+	_, stored, err := s.LoadStoredSnapshot(&counter{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, stored.Schema, "the snapshot is replaced")
+	}
+
+	// Undecodable snapshots are replaced the same way.
+	if err := s.Db.Table(store.SnapshotTableName("counter")).Where("1 = 1").Update("snapshot", []byte("broken")).Error; err != nil {
+		t.Fatal(err)
+	}
+	aggregate = &counter{schema: 2}
+	if assert.NoError(t, r.LoadAggregate(aggregate)) {
+		assert.Equal(t, 3, aggregate.count)
+	}
+}
+
+func TestUndecodableSnapshot(t *testing.T) {
+	s := newGormStore(t).RegisterAggregates(new(counter))
+	r := repo.NewRepositoryConstructor(false, s, s)
+	if err := r.AddEvent(&counter{}, new(counted), new(counted)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CreateSnapshot(&counter{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Db.Table(store.SnapshotTableName("counter")).Where("1 = 1").Update("snapshot", []byte("broken")).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := s.LoadStoredSnapshot(&counter{})
+	assert.ErrorIs(t, err, store.ErrSnapshotUndecodable)
+
+	aggregate := &counter{}
+	if assert.NoError(t, r.LoadAggregate(aggregate)) {
+		assert.Equal(t, 2, aggregate.count)
+	}
+}
@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/store"
+)
+
+var (
+	// ErrUnsigned is returned when an event without signature is loaded and signatures are required.
+	ErrUnsigned = errors.New("event is not signed")
+	// ErrInvalidSignature is returned when the signature of an event doesn't verify.
+	ErrInvalidSignature = errors.New("event signature is invalid")
+	// ErrSigningUnsupported is returned when signing is configured but the event store can't keep signatures.
+	ErrSigningUnsupported = errors.New("event store doesn't support signatures")
+)
+
+// SignaturePolicy defines how event signatures are checked while loading an aggregate.
+type SignaturePolicy int
+
+const (
+	// SignatureIgnore doesn't check signatures.
+	SignatureIgnore SignaturePolicy = iota
+	// SignatureFlag checks signatures and reports unsigned or invalid events to the SignatureHook.
+	SignatureFlag
+	// SignatureReject checks signatures and fails loading on unsigned or invalid events. Snapshots aren't
+	// signed, so aggregates are built from their events only and AutoSnapshot never clears events.
+	SignatureReject
+)
+
+// Ed25519SignHook creates a SignHook that signs every event envelope with the ed25519 key.
+func Ed25519SignHook[E lavender.Event, S lavender.Snapshot](keyID string, key ed25519.PrivateKey) SignHook[E, S] {
+	return func(aggregate lavender.CustomAggregate[E, S], envelope store.Envelope, event E) (store.Signature, error) {
+		data, err := envelope.Bytes(event)
+		if err != nil {
+			return store.Signature{}, err
+		}
+		return store.Signature{
+			KeyID:     keyID,
+			Signature: ed25519.Sign(key, data),
+		}, nil
+	}
+}
+
+// VerifySignature verifies the signature of an event envelope with the public keys by key ID.
+// It returns ErrUnsigned or ErrInvalidSignature if the event can't be attributed.
+func VerifySignature(keys map[string]ed25519.PublicKey, envelope store.Envelope, event lavender.Event, signature store.Signature) error {
+	if len(signature.Signature) == 0 {
+		return ErrUnsigned
+	}
+	key, ok := keys[signature.KeyID]
+	if !ok {
+		return fmt.Errorf("%w: unknown key %s", ErrInvalidSignature, signature.KeyID)
+	}
+	data, err := envelope.Bytes(event)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, data, signature.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signEvents signs the events appended after the head with the SignHook. The event store must be able to
+// store the signatures.
+func (r *CustomRepository[E, S]) signEvents(aggregate lavender.CustomAggregate[E, S], events []E, head uint64) ([]store.Signature, error) {
+	if _, ok := r.EventStore.(store.SignedEventStore[E, S]); !ok {
+		return nil, ErrSigningUnsupported
+	}
+	signatures := make([]store.Signature, 0, len(events))
+	for i, event := range events {
+		envelope := r.envelope(aggregate, head+uint64(i)+1, lavender.IDGeneratorOr(r.IDGenerator).NewID())
+		signature, err := r.SignHook(aggregate, envelope, event)
+		if err != nil {
+			return nil, err
+		}
+		signature.EventID, signature.Sequence = envelope.EventID, envelope.Sequence
+		signatures = append(signatures, signature)
+	}
+	return signatures, nil
+}
+
+// envelope returns the envelope of an event of the aggregate's stream.
+func (r *CustomRepository[E, S]) envelope(aggregate lavender.CustomAggregate[E, S], sequence uint64, id string) store.Envelope {
+	return store.Envelope{
+		Tenant:    lavender.TenantOf(r.scope(aggregate)),
+		Aggregate: aggregate.Name(),
+		Version:   aggregate.Version(),
+		Sequence:  sequence,
+		EventID:   id,
+	}
+}
+
+// loadEvents loads the events of the aggregate stored after the given sequence and checks their signatures
+// according to the SignaturePolicy.
+func (r *CustomRepository[E, S]) loadEvents(aggregate lavender.CustomAggregate[E, S], after uint64) ([]E, error) {
+	if r.SignaturePolicy == SignatureIgnore {
+		events, _, err := store.LoadEventsAfter(r.EventStore, r.scope(aggregate), after)
+		return events, err
+	}
+	if _, ok := r.EventStore.(store.SignedEventStore[E, S]); !ok {
+		return nil, ErrSigningUnsupported
+	}
+	events, signatures, err := store.LoadEventsAfter(r.EventStore, r.scope(aggregate), after)
+	if err != nil {
+		return nil, err
+	}
+	for i, event := range events {
+		err := VerifySignature(r.SignatureKeys, r.envelope(aggregate, signatures[i].Sequence, signatures[i].EventID), event, signatures[i])
+		if err == nil {
+			continue
+		}
+		if r.SignaturePolicy == SignatureReject {
+			return nil, fmt.Errorf("load event %s of %s: %w", event.Name(), aggregate.Name(), err)
+		}
+		if r.SignatureHook != nil {
+			r.SignatureHook(aggregate, event, err)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,131 @@
+package repo_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedEvents(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memStore := store.NewInMemoryStore()
+
+	partner := repo.NewRepositoryConstructor(false, memStore, memStore)
+	partner.SignHook = repo.Ed25519SignHook[lavender.Event, lavender.Snapshot]("partner", private)
+	if err := partner.AddEvent(example.New(), &example.Create{User: *example.NewUser("duck@ducky.com", "secret")}); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := repo.NewRepositoryConstructor(false, memStore, memStore)
+	reader.SignaturePolicy = repo.SignatureReject
+	reader.SignatureKeys = map[string]ed25519.PublicKey{"partner": public}
+
+	aggregate, err := example.Load(reader)
+	if assert.NoError(t, err) {
+		assert.Len(t, aggregate.Users, 1)
+	}
+
+	// An event that bypassed the signing hook.
+	if err := memStore.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *example.NewUser("evil@ducky.com", "secret")}}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = example.Load(reader)
+	assert.ErrorIs(t, err, repo.ErrUnsigned)
+
+	// Flagging keeps loading but reports the unsigned event.
+	var flagged []error
+	reader.SignaturePolicy = repo.SignatureFlag
+	reader.SignatureHook = func(aggregate lavender.Aggregate, event lavender.Event, err error) {
+		flagged = append(flagged, err)
+	}
+	aggregate, err = example.Load(reader)
+	if assert.NoError(t, err) {
+		assert.Len(t, aggregate.Users, 2)
+		assert.Len(t, flagged, 1)
+	}
+
+	// Signatures of unknown keys are invalid.
+	reader.SignaturePolicy = repo.SignatureReject
+	reader.SignatureKeys = map[string]ed25519.PublicKey{}
+	_, err = example.Load(reader)
+	assert.ErrorIs(t, err, repo.ErrInvalidSignature)
+}
+
+func TestSignedEventsGorm(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormStore := newGormStore(t)
+
+	r := repo.NewRepositoryConstructor(false, gormStore, gormStore)
+	r.SignHook = repo.Ed25519SignHook[lavender.Event, lavender.Snapshot]("partner", private)
+	r.SignaturePolicy = repo.SignatureReject
+	r.SignatureKeys = map[string]ed25519.PublicKey{"partner": public}
+
+	for _, email := range []string{"a@t.de", "b@t.de"} {
+		if err := r.AddEvent(example.New(), &example.Create{User: *example.NewUser(email, "secret")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Signed events can't be duplicated or copied into another tenant's stream.
+	var rows []store.Event
+	if err := gormStore.Db.Table("event_account").Where("sequence = 1").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	duplicate, copied := rows[0], rows[0]
+	duplicate.Sequence, duplicate.Position = 3, 100
+	copied.Tenant, copied.Position = "ducks", 101
+	assert.NoError(t, gormStore.Db.Table("event_account").Create(&[]store.Event{duplicate, copied}).Error)
+	_, err = example.Load(r)
+	assert.ErrorIs(t, err, repo.ErrInvalidSignature)
+	_, err = example.Load(r.ForTenant("ducks"))
+	assert.ErrorIs(t, err, repo.ErrInvalidSignature)
+	assert.NoError(t, gormStore.Db.Exec("DELETE FROM event_account WHERE position >= 100").Error)
+	_, err = example.Load(r)
+	assert.NoError(t, err)
+
+	// Move a signature to another event.
+	assert.NoError(t, gormStore.Db.Exec("UPDATE event_account SET signature = (SELECT signature FROM event_account WHERE sequence = 1) WHERE sequence = 2").Error)
+	_, err = example.Load(r)
+	assert.ErrorIs(t, err, repo.ErrInvalidSignature)
+}
+
+func TestSignedEventsSnapshots(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memStore := store.NewInMemoryStore()
+	r := repo.NewRepositoryConstructor(false, memStore, memStore)
+	r.SignHook = repo.Ed25519SignHook[lavender.Event, lavender.Snapshot]("partner", private)
+	r.SignaturePolicy = repo.SignatureReject
+	r.SignatureKeys = map[string]ed25519.PublicKey{"partner": public}
+	r.AutoSnapshotHook = func(lavender.Aggregate, []lavender.Event) bool { return true }
+	for _, email := range []string{"a@t.de", "b@t.de"} {
+		if err := r.AddEvent(example.New(), &example.Create{User: *example.NewUser(email, "secret")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Snapshots aren't signed: they are ignored and never replace the signed events.
+	forged := example.New()
+	forged.ApplyEvent(&example.Create{User: *example.NewUser("forged@t.de", "secret")})
+	if err := memStore.SaveSnapshot(example.New(), forged.TakeSnapshot()); err != nil {
+		t.Fatal(err)
+	}
+	aggregate, err := example.Load(r)
+	if assert.NoError(t, err) {
+		assert.Len(t, aggregate.Users, 2)
+		assert.NotContains(t, aggregate.Emails, "forged@t.de")
+	}
+}
@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/FlauschigDings/lavender"
+)
+
+// ForTenant returns a repository with the same stores and settings that is scoped to the tenant.
+// It has its own aggregate cache.
+func (r *CustomRepository[E, S]) ForTenant(tenant string) *CustomRepository[E, S] {
+	scoped := *r
+	scoped.aggregateCache = new(sync.Map)
+	scoped.Tenant = tenant
+	return &scoped
+}
+
+// WithContext returns a repository scoped to the tenant carried by the context, see
+// lavender.ContextWithTenant. Without a tenant in the context the repository itself is returned.
+func (r *CustomRepository[E, S]) WithContext(ctx context.Context) *CustomRepository[E, S] {
+	tenant := lavender.TenantFromContext(ctx)
+	if tenant == "" || tenant == r.Tenant {
+		return r
+	}
+	return r.ForTenant(tenant)
+}
+
+// scope assigns the aggregate to the tenant of the repository before it is passed to the stores.
+func (r *CustomRepository[E, S]) scope(aggregate lavender.CustomAggregate[E, S]) lavender.CustomAggregate[E, S] {
+	return lavender.WithTenant(aggregate, r.Tenant)
+}
@@ -0,0 +1,80 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/lavendertest"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenants(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+		r := repo.NewRepository(s, s)
+		r.AutoSnapshotHook = func(aggregate lavender.Aggregate, items []lavender.Event) bool {
+			return len(items) >= 1
+		}
+		ducks := r.WithContext(lavender.ContextWithTenant(context.Background(), "ducks"))
+		geese := r.ForTenant("geese")
+
+		for _, email := range []string{"a@ducks.de", "b@ducks.de"} {
+			if err := ducks.AddEvent(example.New(), createUser(email)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := geese.AddEvent(example.New(), createUser("a@geese.de")); err != nil {
+			t.Fatal(err)
+		}
+
+		// Fresh repositories don't share the caches of the scoped ones.
+		for tenant, emails := range map[string][]string{
+			"ducks": {"a@ducks.de", "b@ducks.de"},
+			"geese": {"a@geese.de"},
+			"":      nil,
+		} {
+			aggregate, err := example.Load(repo.NewRepositoryConstructor(false, s, s).ForTenant(tenant))
+			if assert.NoError(t, err) {
+				assert.ElementsMatch(t, emails, keys(aggregate.Emails), "tenant %q", tenant)
+			}
+		}
+
+		// The cache is partitioned by tenant.
+		aggregate, err := example.Load(r)
+		if assert.NoError(t, err) {
+			assert.Empty(t, aggregate.Emails)
+		}
+		aggregate, err = example.Load(geese)
+		if assert.NoError(t, err) {
+			assert.Len(t, aggregate.Emails, 1)
+		}
+	})
+}
+
+// keys returns the keys of a map.
+func keys[V any](m map[string]V) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
+
+func TestForTenantSettings(t *testing.T) {
+	s := store.NewInMemoryStore()
+	r := repo.NewRepository(s, s)
+	r.UpdateAttempts = 7
+	r.SignaturePolicy = repo.SignatureFlag
+	r.IDGenerator = new(lavendertest.SequentialIDs)
+
+	ducks := r.ForTenant("ducks")
+	assert.Equal(t, "ducks", ducks.Tenant)
+	assert.Equal(t, "", r.Tenant)
+	assert.Equal(t, 7, ducks.UpdateAttempts)
+	assert.Equal(t, repo.SignatureFlag, ducks.SignaturePolicy)
+	assert.Same(t, r.IDGenerator, ducks.IDGenerator)
+	assert.NotNil(t, ducks.AutoSnapshotHook)
+}
@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/instrument"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/thesyncim/go-clone"
+)
+
+// UpdateFunc decides which events to append based on the current state of the aggregate.
+type UpdateFunc[E lavender.Event, S lavender.Snapshot] func(aggregate lavender.CustomAggregate[E, S]) ([]E, error)
+
+// Update loads the aggregate from the stores, bypassing the cache, runs decide on it and appends the
+// returned events. If the stream has been changed concurrently, Update retries with a fresh copy of the
+// aggregate, up to UpdateAttempts times with a jittered exponential backoff. The returned error wraps the
+// errors of all attempts. Errors of decide are returned without retrying.
+//
+// The aggregate should be freshly created, it receives the state of the successful attempt.
+// Conflicts are only detected if the event store implements store.ConcurrentEventStore.
+func (r *CustomRepository[E, S]) Update(aggregate lavender.CustomAggregate[E, S], decide UpdateFunc[E, S]) (err error) {
+	instrumentation := r.instrumentation()
+	attr := instrument.Attr("aggregate", aggregate.Name())
+	span := instrumentation.StartSpan(instrument.SpanUpdate, attr)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	attempts := max(r.UpdateAttempts, 1)
+	var errs []error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(r.updateBackoff(attempt - 1))
+		}
+
+		current := clone.Clone(aggregate).(lavender.CustomAggregate[E, S])
+		err := r.update(current, decide)
+		if err == nil {
+			aggregate.ApplySnapshot(current.TakeSnapshot())
+			r.saveCache(aggregate)
+			return nil
+		}
+		if !errors.Is(err, store.ErrConflict) {
+			return err
+		}
+		instrumentation.Count(instrument.MetricUpdateConflicts, 1, attr)
+		errs = append(errs, fmt.Errorf("attempt %d: %w", attempt, err))
+	}
+	r.InvalidateCache(aggregate)
+	return fmt.Errorf("update %s: giving up after %d attempts: %w", aggregate.Name(), attempts, errors.Join(errs...))
+}
+
+// update runs a single attempt of Update on the aggregate.
+func (r *CustomRepository[E, S]) update(aggregate lavender.CustomAggregate[E, S], decide UpdateFunc[E, S]) error {
+	// Automatically snapshot the aggregate if needed
+	if err := r.AutoSnapshot(aggregate); err != nil {
+		return err
+	}
+
+	// Read the stream head before loading, an append in between is detected as a conflict
+	var expected *uint64
+	sequence, err := store.StreamSequence(r.EventStore, r.scope(aggregate))
+	if err == nil {
+		expected = &sequence
+	} else if !errors.Is(err, store.ErrUnsupported) {
+		return err
+	}
+
+	// The cached aggregate may miss events appended by other processes, decide on the stored state
+	r.InvalidateCache(aggregate)
+	if err := r.LoadAggregate(aggregate); err != nil {
+		return err
+	}
+
+	events, err := decide(aggregate)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	for _, event := range events {
+		aggregate.ApplyEvent(event)
+	}
+
+	defer r.since(instrument.MetricAppendDuration, r.now(), instrument.Attr("aggregate", aggregate.Name()))
+	return r.saveEvents(aggregate, events, expected)
+}
+
+// updateBackoff returns the jittered delay before the next attempt, between half and one and a half
+// of the exponential backoff.
+func (r *CustomRepository[E, S]) updateBackoff(attempt int) time.Duration {
+	delay := r.UpdateBackoff << (attempt - 1)
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay)
+}
@@ -0,0 +1,141 @@
+package repo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// createUser returns an event creating a user with the given email.
+func createUser(email string) lavender.Event {
+	return &example.Create{User: *example.NewUser(email, "secret")}
+}
+
+func TestUpdate(t *testing.T) {
+	update := func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+		r := repo.NewRepository(s, s)
+		r.UpdateBackoff = 0
+		if err := r.AddEvent(example.New(), createUser("a@t.de")); err != nil {
+			t.Fatal(err)
+		}
+
+		// A concurrent writer appends while the first attempt decides.
+		calls := 0
+		aggregate := example.New()
+		err := r.Update(aggregate, func(agg lavender.Aggregate) ([]lavender.Event, error) {
+			calls++
+			if calls == 1 {
+				if err := s.SaveEvents(example.New(), []lavender.Event{createUser("b@t.de")}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, ok := agg.(*example.AccountAggregate).Emails["b@t.de"]; !ok {
+				return []lavender.Event{createUser("c@t.de")}, nil
+			}
+			return []lavender.Event{createUser("d@t.de")}, nil
+		})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 2, calls)
+			assert.Len(t, aggregate.Users, 3)
+			assert.Contains(t, aggregate.Emails, "d@t.de")
+		}
+
+		loaded, err := example.Load(repo.NewRepositoryConstructor(false, s, s))
+		if assert.NoError(t, err) {
+			assert.Len(t, loaded.Users, 3)
+			assert.NotContains(t, loaded.Emails, "c@t.de")
+		}
+
+		// Every attempt conflicts.
+		r.UpdateAttempts = 3
+		calls = 0
+		err = r.Update(example.New(), func(agg lavender.Aggregate) ([]lavender.Event, error) {
+			calls++
+			if err := s.SaveEvents(example.New(), []lavender.Event{createUser("x@t.de")}); err != nil {
+				t.Fatal(err)
+			}
+			return []lavender.Event{createUser("y@t.de")}, nil
+		})
+		assert.ErrorIs(t, err, store.ErrConflict)
+		assert.ErrorContains(t, err, "attempt 3")
+		assert.Equal(t, 3, calls)
+
+		// Errors of the decision are not retried.
+		decline := errors.New("declined")
+		calls = 0
+		err = r.Update(example.New(), func(agg lavender.Aggregate) ([]lavender.Event, error) {
+			calls++
+			return nil, decline
+		})
+		assert.ErrorIs(t, err, decline)
+		assert.Equal(t, 1, calls)
+	}
+	forEachStore(t, update)
+	t.Run("chain", func(t *testing.T) {
+		update(t, store.Chain(store.NewInMemoryStore(), store.Retry[lavender.Event, lavender.Snapshot](3, 0)))
+	})
+}
+
+func TestUpdateCached(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+		r := repo.NewRepositoryConstructor(true, s, s)
+		if err := r.AddEvent(example.New(), createUser("a@t.de")); err != nil {
+			t.Fatal(err)
+		}
+		// Another process appends, the cached aggregate misses the event.
+		if err := s.SaveEvents(example.New(), []lavender.Event{createUser("b@t.de")}); err != nil {
+			t.Fatal(err)
+		}
+
+		aggregate := example.New()
+		err := r.Update(aggregate, func(agg lavender.Aggregate) ([]lavender.Event, error) {
+			assert.Contains(t, agg.(*example.AccountAggregate).Emails, "b@t.de", "decide sees the stored state")
+			return []lavender.Event{createUser("c@t.de")}, nil
+		})
+		if assert.NoError(t, err) {
+			assert.Len(t, aggregate.Users, 3)
+		}
+	})
+}
+
+func TestUpdateAutoSnapshot(t *testing.T) {
+	stores := map[string]store.Store[lavender.Event, lavender.Snapshot]{
+		"memory": store.NewInMemoryStore(),
+		"gorm":   newGormStore(t),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			first, second := repo.NewRepository(s, s), repo.NewRepository(s, s)
+			if err := first.AddEvent(example.New(), createUser("a1@t.de")); err != nil {
+				t.Fatal(err)
+			}
+			if err := second.AddEvent(example.New(), createUser("b1@t.de")); err != nil {
+				t.Fatal(err)
+			}
+
+			// The first repository has cached the aggregate without the event of the second one.
+			first.AutoSnapshotHook = func(aggregate lavender.Aggregate, items []lavender.Event) bool {
+				return len(items) >= 2
+			}
+			err := first.Update(example.New(), func(agg lavender.Aggregate) ([]lavender.Event, error) {
+				return []lavender.Event{createUser("a2@t.de")}, nil
+			})
+			assert.NoError(t, err)
+
+			loaded, err := example.Load(repo.NewRepositoryConstructor(false, s, s))
+			if assert.NoError(t, err) {
+				assert.Len(t, loaded.Users, 3)
+				assert.Contains(t, loaded.Emails, "b1@t.de")
+			}
+			snapshot, err := s.LoadSnapshot(example.New())
+			if assert.NoError(t, err) && assert.NotNil(t, snapshot) {
+				assert.Len(t, (*snapshot).(*example.AccountSnapshot).Users, 2)
+			}
+		})
+	}
+}
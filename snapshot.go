@@ -7,3 +7,20 @@ type Snapshot interface {
 	// Version returns the version of the aggregate at the time the snapshot was taken.
 	Version() Version
 }
+
+// SchemaVersioned is implemented by snapshots that carry the version of their own schema, independent of the
+// aggregate version. Stores record it with the snapshot, so snapshots of an older schema are detected when
+// they are loaded and upgraded or replaced by a replay of the events.
+type SchemaVersioned interface {
+	// SchemaVersion returns the version of the snapshot's schema. It changes whenever stored snapshots
+	// can't be applied by the current code anymore.
+	SchemaVersion() int
+}
+
+// SchemaVersionOf returns the schema version of the snapshot, 0 if it doesn't implement SchemaVersioned.
+func SchemaVersionOf(snapshot any) int {
+	if versioned, ok := snapshot.(SchemaVersioned); ok {
+		return versioned.SchemaVersion()
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/lavendertest"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	for _, encoder := range []encoders.Encoder{encoders.NewCBorEncoder(), encoders.NewJsonEncoder(), encoders.NewGobEncoder()} {
+		t.Run(encoders.ContentTypeOf(encoder), func(t *testing.T) {
+			db, err := Sqlite(":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			clock := lavendertest.NewFakeClock(start)
+			dir := t.TempDir()
+			gormStore := store.NewGormCustomStore[lavender.Event, lavender.Snapshot](db.DB, encoder).
+				UseHashChain().
+				UseClock(clock).
+				UseArchive(dir).
+				RegisterAggregates(example.New())
+
+			// Three events covered by a snapshot and one after it, an hour apart.
+			save := func(email string) {
+				t.Helper()
+				if err := gormStore.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *example.NewUser(email, "secret")}}); err != nil {
+					t.Fatal(err)
+				}
+				clock.Advance(time.Hour)
+			}
+			save("a@t.de")
+			save("b@t.de")
+			save("c@t.de")
+			if err := gormStore.SaveSnapshot(example.New(), example.New().TakeSnapshot()); err != nil {
+				t.Fatal(err)
+			}
+			save("d@t.de")
+
+			// Only events older than the retention are archived.
+			archived, err := gormStore.ArchiveEvents(150 * time.Minute)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 2, archived)
+			}
+			var stored int64
+			db.Table(store.EventTableName("account")).Count(&stored)
+			assert.EqualValues(t, 2, stored)
+
+			archived, err = gormStore.ArchiveEvents(0)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 1, archived)
+			}
+			events, err := gormStore.LoadEvents(example.New())
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"d@t.de"}, emails(events))
+			}
+
+			// Replays read the archive transparently.
+			events, err = gormStore.LoadHistory(example.New(), time.Time{})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"a@t.de", "b@t.de", "c@t.de", "d@t.de"}, emails(events))
+			}
+			events, err = gormStore.LoadHistory(example.New(), start.Add(90*time.Minute))
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"a@t.de", "b@t.de"}, emails(events))
+			}
+			assert.NoError(t, gormStore.Verify(example.New()))
+
+			// Tampered archive files are detected.
+			var segments []store.ArchiveSegment
+			if err := db.Order("first_sequence").Find(&segments).Error; err != nil {
+				t.Fatal(err)
+			}
+			if assert.Len(t, segments, 2) {
+				assert.Equal(t, []uint64{1, 2}, []uint64{segments[0].FirstSequence, segments[0].LastSequence})
+				path := filepath.Join(dir, segments[0].Path)
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data[len(data)/2] ^= 0xFF
+				if err := os.WriteFile(path, data, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				_, err = gormStore.LoadHistory(example.New(), time.Time{})
+				assert.ErrorIs(t, err, store.ErrArchiveCorrupted)
+			}
+		})
+	}
+}
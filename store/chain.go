@@ -0,0 +1,134 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+)
+
+// ErrChainBroken is returned (wrapped in a ChainError) when the hash chain of a stream doesn't verify.
+var ErrChainBroken = errors.New("hash chain is broken")
+
+// ChainError reports the first broken link of the hash chain of a stream.
+type ChainError struct {
+	Stream   lavender.Name // Stream with the broken chain
+	Sequence uint64        // Sequence of the first event that doesn't verify
+	Reason   string        // Why the link is broken
+}
+
+// Error implements error.
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("stream %s: event %d: %s: %s", e.Stream, e.Sequence, ErrChainBroken, e.Reason)
+}
+
+// Unwrap allows errors.Is(err, ErrChainBroken).
+func (e *ChainError) Unwrap() error {
+	return ErrChainBroken
+}
+
+// Verifier is implemented by stores that can verify the hash chain of a stream.
+type Verifier[E lavender.Event, S lavender.Snapshot] interface {
+	// Verify walks the hash chain of the aggregate's stream and returns a *ChainError for the first broken link.
+	Verify(aggregate lavender.CustomAggregate[E, S]) error
+}
+
+// canonicalEncoder encodes the events for hashing, independent of the encoder of the store.
+var canonicalEncoder = encoders.NewCanonicalEncoder()
+
+// ChainHash returns the hash of an event linked to the hash of the previous event of the stream.
+// The hash covers the previous hash, the event name and the canonical encoding of the event.
+func ChainHash(prevHash []byte, topic lavender.Name, event any) ([]byte, error) {
+	payload, err := canonicalEncoder.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return chainDigest(prevHash, topic, payload), nil
+}
+
+// chainDigest hashes the canonical payload of an event linked to the previous hash.
+func chainDigest(prevHash []byte, topic lavender.Name, payload []byte) []byte {
+	hash := sha256.New()
+	hash.Write(prevHash)
+	hash.Write([]byte{0})
+	hash.Write([]byte(topic))
+	hash.Write([]byte{0})
+	hash.Write(payload)
+	return hash.Sum(nil)
+}
+
+// chainLink is a stored event as seen by the chain verification.
+type chainLink struct {
+	Sequence uint64
+	Topic    lavender.Name
+	Hash     []byte
+	PrevHash []byte
+	Event    any
+}
+
+// chainPin is the chain head a snapshot has been taken at.
+type chainPin struct {
+	Sequence  uint64
+	ChainHead []byte
+}
+
+// verifyChain walks the links of a stream in sequence order. Events stored before the chain was enabled
+// (without hash) are only allowed in front of the chain. The first link must either start the chain or
+// continue the chain head pinned by a snapshot, and every snapshot pin inside the stream must match.
+// If the store recorded the head of the chain, the chain must reach it: the last link or, if all events
+// have been removed, a pin must be the head, so removing or unhashing the last events breaks the chain.
+func verifyChain(stream lavender.Name, links []chainLink, pins []chainPin, head *chainPin) error {
+	var prev *chainLink
+	for i := range links {
+		link := &links[i]
+		if len(link.Hash) == 0 {
+			if prev != nil {
+				return &ChainError{Stream: stream, Sequence: link.Sequence, Reason: "event is not hashed"}
+			}
+			continue
+		}
+
+		if prev == nil && len(link.PrevHash) != 0 && !pinned(pins, link.Sequence-1, link.PrevHash) {
+			return &ChainError{Stream: stream, Sequence: link.Sequence, Reason: "chain doesn't start at a snapshot"}
+		}
+		if prev != nil && (prev.Sequence+1 != link.Sequence || !bytes.Equal(prev.Hash, link.PrevHash)) {
+			return &ChainError{Stream: stream, Sequence: link.Sequence, Reason: "previous hash doesn't match"}
+		}
+
+		hash, err := ChainHash(link.PrevHash, link.Topic, link.Event)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(hash, link.Hash) {
+			return &ChainError{Stream: stream, Sequence: link.Sequence, Reason: "hash doesn't match the event"}
+		}
+		for _, pin := range pins {
+			if pin.Sequence == link.Sequence && !bytes.Equal(pin.ChainHead, link.Hash) {
+				return &ChainError{Stream: stream, Sequence: link.Sequence, Reason: "snapshot pins another chain head"}
+			}
+		}
+		prev = link
+	}
+
+	switch {
+	case head == nil:
+	case prev != nil && (prev.Sequence != head.Sequence || !bytes.Equal(prev.Hash, head.ChainHead)):
+		return &ChainError{Stream: stream, Sequence: min(prev.Sequence, head.Sequence) + 1, Reason: "chain doesn't reach its head"}
+	case prev == nil && !pinned(pins, head.Sequence, head.ChainHead):
+		return &ChainError{Stream: stream, Sequence: head.Sequence, Reason: "chain doesn't reach its head"}
+	}
+	return nil
+}
+
+// pinned checks if a snapshot pinned the chain head at the sequence.
+func pinned(pins []chainPin, sequence uint64, head []byte) bool {
+	for _, pin := range pins {
+		if pin.Sequence == sequence && bytes.Equal(pin.ChainHead, head) {
+			return true
+		}
+	}
+	return false
+}
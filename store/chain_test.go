@@ -0,0 +1,123 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/repo"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashChain(t *testing.T) {
+	db, err := Sqlite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormStore := store.NewGormCustomStore[lavender.Event, lavender.Snapshot](db.DB, encoders.NewJsonEncoder()).UseHashChain()
+	gormStore.RegisterAggregates(example.New())
+
+	stores := map[string]interface {
+		store.EventStore[lavender.Event, lavender.Snapshot]
+		store.SnapshotStore[lavender.Event, lavender.Snapshot]
+		store.Verifier[lavender.Event, lavender.Snapshot]
+	}{
+		"memory": store.NewInMemoryStore().UseHashChain(),
+		"gorm":   gormStore,
+	}
+
+	for name, eventStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			repo := repo.NewRepositoryConstructor(false, eventStore, eventStore)
+			// Snapshot and clear the event log every third event.
+			repo.AutoSnapshotHook = func(aggregate lavender.Aggregate, items []lavender.Event) bool {
+				return len(items) >= 3
+			}
+
+			for i := 0; i < 10; i++ {
+				user := example.NewUser(accounts[i%len(accounts)], "secret")
+				if err := repo.AddEvent(example.New(), &example.Create{User: *user}); err != nil {
+					t.Fatal(err)
+				}
+				assert.NoError(t, eventStore.Verify(example.New()))
+			}
+		})
+	}
+
+	t.Run("tampered", func(t *testing.T) {
+		assert.NoError(t, db.Table(store.EventTableName("account")).
+			Where("sequence = (SELECT MAX(sequence) FROM event_account)").
+			Update("event", []byte(`{"Email":"evil@t.de"}`)).Error)
+
+		var chainErr *store.ChainError
+		err := gormStore.Verify(example.New())
+		assert.ErrorIs(t, err, store.ErrChainBroken)
+		if assert.ErrorAs(t, err, &chainErr) {
+			assert.Equal(t, uint64(10), chainErr.Sequence)
+		}
+	})
+}
+
+func TestHashChainHead(t *testing.T) {
+	// newChain stores five events with a snapshot after the third one, which clears the covered events.
+	newChain := func(t *testing.T) (*DatabaseService, *store.GormStore[lavender.Event, lavender.Snapshot]) {
+		db, err := Sqlite(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		gormStore := store.NewGormCustomStore[lavender.Event, lavender.Snapshot](db.DB, encoders.NewJsonEncoder()).UseHashChain()
+		gormStore.RegisterAggregates(example.New())
+		repo := repo.NewRepositoryConstructor(false, gormStore, gormStore)
+		repo.AutoSnapshotHook = func(aggregate lavender.Aggregate, items []lavender.Event) bool {
+			return len(items) >= 3
+		}
+		for i := 0; i < 5; i++ {
+			user := example.NewUser(accounts[i%len(accounts)], "secret")
+			if err := repo.AddEvent(example.New(), &example.Create{User: *user}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		assert.NoError(t, gormStore.Verify(example.New()))
+		return db, gormStore
+	}
+
+	t.Run("unhashed", func(t *testing.T) {
+		db, gormStore := newChain(t)
+		assert.NoError(t, db.Table(store.EventTableName("account")).Where("1 = 1").Updates(map[string]any{"hash": nil, "prev_hash": nil}).Error)
+		assert.ErrorIs(t, gormStore.Verify(example.New()), store.ErrChainBroken)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		db, gormStore := newChain(t)
+		assert.NoError(t, db.Table(store.EventTableName("account")).Where("sequence = (SELECT MAX(sequence) FROM event_account)").Delete(&store.Event{}).Error)
+
+		var chainErr *store.ChainError
+		err := gormStore.Verify(example.New())
+		assert.ErrorIs(t, err, store.ErrChainBroken)
+		if assert.ErrorAs(t, err, &chainErr) {
+			assert.Equal(t, uint64(5), chainErr.Sequence)
+		}
+
+		// Removing all events after the snapshot is detected as well.
+		assert.NoError(t, db.Table(store.EventTableName("account")).Where("1 = 1").Delete(&store.Event{}).Error)
+		assert.ErrorIs(t, gormStore.Verify(example.New()), store.ErrChainBroken)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		memStore := store.NewInMemoryStore().UseHashChain()
+		for i := 0; i < 3; i++ {
+			user := example.NewUser(accounts[i%len(accounts)], "secret")
+			assert.NoError(t, memStore.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *user}}))
+		}
+		assert.NoError(t, memStore.Verify(example.New()))
+
+		memStore.Events.Range(func(key, value any) bool {
+			events := value.([]store.MemoryEvent[lavender.Event])
+			memStore.Events.Store(key, events[:len(events)-1])
+			return true
+		})
+		assert.ErrorIs(t, memStore.Verify(example.New()), store.ErrChainBroken)
+	})
+}
@@ -0,0 +1,55 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/lavendertest"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	db, err := Sqlite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	gormStore := store.NewGormStore(db.DB).
+		UseClock(lavendertest.NewFakeClock(start)).
+		UseIDGenerator(new(lavendertest.SequentialIDs)).
+		RegisterAggregates(example.New())
+
+	if err := gormStore.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *example.NewUser("a@t.de", "secret")}}); err != nil {
+		t.Fatal(err)
+	}
+	var rows []store.Event
+	if err := db.Table(store.EventTableName("account")).Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, "00000000-0000-7000-8000-000000000001", rows[0].EventID)
+		assert.True(t, start.Equal(rows[0].CreatedAt))
+	}
+
+	// Snapshots taken in the same tick are ordered by their IDs.
+	for _, email := range []string{"b@t.de", "c@t.de"} {
+		snapshot := &example.AccountSnapshot{Users: []example.User{{Id: uuid.New(), Email: email}}}
+		if err := gormStore.SaveSnapshot(example.New(), snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshot, err := gormStore.LoadSnapshot(example.New())
+	if assert.NoError(t, err) && assert.NotNil(t, snapshot) {
+		assert.Equal(t, "c@t.de", (*snapshot).(*example.AccountSnapshot).Users[0].Email)
+	}
+
+	// IDs default to UUIDv7.
+	id, err := uuid.Parse(lavender.UUIDv7Generator{}.NewID())
+	if assert.NoError(t, err) {
+		assert.Equal(t, uuid.Version(7), id.Version())
+	}
+}
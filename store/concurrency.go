@@ -0,0 +1,32 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/FlauschigDings/lavender"
+)
+
+// ErrConflict is returned when events are appended to a stream that has been changed since it was loaded.
+var ErrConflict = errors.New("stream has been changed concurrently")
+
+// ConcurrentEventStore is implemented by event stores that support optimistic concurrency.
+type ConcurrentEventStore[E lavender.Event, S lavender.Snapshot] interface {
+	// Sequence returns the sequence of the last event of the aggregate's stream, 0 for an empty stream.
+	Sequence(aggregate lavender.CustomAggregate[E, S]) (uint64, error)
+
+	// SaveEventsAt stores new events and their signatures (nil for unsigned events) only if the sequence of
+	// the last event of the stream is still the expected one, otherwise it returns ErrConflict.
+	SaveEventsAt(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature, expected uint64) error
+
+	// ClearEventsUntil removes the events of the stream up to and including the sequence. Events appended
+	// after it are kept.
+	ClearEventsUntil(aggregate lavender.CustomAggregate[E, S], sequence uint64) error
+}
+
+// ConcurrentSnapshotStore is implemented by snapshot stores that keep the events of the stream as well, so
+// a snapshot can be bound to the stream head it has been taken at.
+type ConcurrentSnapshotStore[E lavender.Event, S lavender.Snapshot] interface {
+	// SaveSnapshotAt stores a snapshot only if the sequence of the last event of the stream is still the
+	// expected one, otherwise it returns ErrConflict.
+	SaveSnapshotAt(aggregate lavender.CustomAggregate[E, S], snapshot S, expected uint64) error
+}
@@ -0,0 +1,53 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/FlauschigDings/lavender/store/storetest"
+)
+
+// gormFactory creates GormStores on a new in-memory SQLite database. A single connection keeps concurrent
+// tests on the same database.
+func gormFactory(encoder encoders.Encoder, shared bool) storetest.Factory {
+	return func(t *testing.T) store.Store[lavender.Event, lavender.Snapshot] {
+		db, err := Sqlite(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDb, err := db.DB.DB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDb.SetMaxOpenConns(1)
+
+		gormStore := store.NewGormCustomStore[lavender.Event, lavender.Snapshot](db.DB, encoder)
+		if shared {
+			gormStore.UseSharedTable()
+		}
+		return gormStore.RegisterAggregates(storetest.Aggregates()...)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Store[lavender.Event, lavender.Snapshot] {
+			return store.NewInMemoryStore()
+		})
+	})
+	t.Run("memory-encoded", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Store[lavender.Event, lavender.Snapshot] {
+			return store.NewInMemoryEncodedStore[lavender.Event, lavender.Snapshot](encoders.NewCBorEncoder()).UseHashChain()
+		})
+	})
+	for _, encoder := range encoderList {
+		t.Run("gorm-"+encoders.ContentTypeOf(encoder), func(t *testing.T) {
+			storetest.Run(t, gormFactory(encoder, false))
+		})
+	}
+	t.Run("gorm-shared", func(t *testing.T) {
+		storetest.Run(t, gormFactory(encoders.NewCBorEncoder(), true))
+	})
+}
@@ -1,23 +1,40 @@
 package store
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/FlauschigDings/lavender"
 	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/instrument"
 	"github.com/thesyncim/go-clone"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Snapshot represents a stored snapshot of an aggregate.
 type Snapshot struct {
-	CreatedAt time.Time        // Timestamp when the snapshot was created.
-	Version   lavender.Version // Aggregate version at the time of snapshot
-	Name      lavender.Name    // Aggregate name
-	Snapshot  string           // Serialized snapshot data
+	SnapshotID  string           `gorm:"index"` // Unique ID of the snapshot
+	CreatedAt   time.Time        // Timestamp when the snapshot was created.
+	Tenant      string           `gorm:"index;not null;default:''"` // Tenant the stream belongs to
+	Version     lavender.Version // Aggregate version at the time of snapshot
+	Name        lavender.Name    // Aggregate name
+	Sequence    uint64           // Sequence of the last event covered by the snapshot
+	ChainHead   []byte           // Hash of the last event covered by the snapshot
+	Schema      int              `gorm:"not null;default:0"` // Schema version of the snapshot, see lavender.SchemaVersioned
+	ContentType string           // Content type of the encoder that produced the snapshot data
+	Snapshot    []byte           // Serialized snapshot data
 }
 
+// Orders of the snapshots of a stream. Snapshots are ordered by the sequence they have been taken at, the
+// timestamp and ID only order snapshots stored at the same sequence by older versions.
+const (
+	latestSnapshotFirst = "sequence DESC, created_at DESC, snapshot_id DESC"
+	oldestSnapshotFirst = "sequence, created_at, snapshot_id"
+)
+
 // Generate a table name for snapshot.
 func SnapshotTableName(name lavender.Name) string {
 	return fmt.Sprintf("snapshot_%s", name)
@@ -30,13 +47,29 @@ func (s Snapshot) TableName() string {
 
 // Event represents a stored event for an aggregate.
 type Event struct {
-	CreatedAt time.Time        // Timestamp when the event was created.
-	Name      lavender.Name    // Aggregate name
-	Version   lavender.Version // Aggregate version
-	Topic     lavender.Name    // Event name
-	Event     string           // Serialized event data
+	EventID     string           `gorm:"index"`                                  // Unique ID of the event
+	Position    uint64           `gorm:"uniqueIndex:,composite:global_position"` // Global position inside the table
+	CreatedAt   time.Time        // Timestamp when the event was created.
+	Tenant      string           `gorm:"uniqueIndex:,composite:stream_sequence,priority:1;not null;default:''"` // Tenant the stream belongs to
+	Category    lavender.Name    `gorm:"uniqueIndex:,composite:stream_sequence,priority:2"`                     // Stream category
+	Name        lavender.Name    `gorm:"uniqueIndex:,composite:stream_sequence,priority:3"`                     // Aggregate name (stream ID)
+	Sequence    uint64           `gorm:"uniqueIndex:,composite:stream_sequence,priority:4"`                     // Position inside the stream
+	Version     lavender.Version // Aggregate version
+	Topic       lavender.Name    // Event name
+	ContentType string           // Content type of the encoder that produced the event data
+	Event       []byte           // Serialized event data
+	Hash        []byte           // Hash chain link of the event
+	PrevHash    []byte           // Hash of the previous event of the stream
+	KeyID       string           // ID of the key that signed the event
+	Signature   []byte           // Signature of the event envelope
+
+	digest []byte // Canonical event data used for the hash chain, not stored
+	signed uint64 // Sequence the event has been signed for, not stored
 }
 
+// SharedEventTableName is the table used for all streams when the shared table is enabled.
+const SharedEventTableName = "events"
+
 // Generate a table name for events.
 func EventTableName(name lavender.Name) string {
 	return fmt.Sprintf("event_%s", name)
@@ -44,18 +77,63 @@ func EventTableName(name lavender.Name) string {
 
 // Assign the dynamic table name for events.
 func (e Event) TableName() string {
-	return EventTableName(e.Topic)
+	return EventTableName(e.Name)
+}
+
+// stream returns the stream the event belongs to.
+func (e Event) stream() streamID {
+	return streamID{Tenant: e.Tenant, Name: e.Name}
+}
+
+// ChainHead represents the last link of the hash chain of a stream. The chain must reach it, so removing the
+// last events of a stream is detected.
+type ChainHead struct {
+	Tenant   string        `gorm:"primaryKey"` // Tenant the stream belongs to
+	Name     lavender.Name `gorm:"primaryKey"` // Aggregate name (stream ID)
+	Sequence uint64        // Sequence of the last hashed event
+	Hash     []byte        // Hash chain link of the last hashed event
+}
+
+// ChainHeadTableName is the table holding the hash chain heads of all streams.
+const ChainHeadTableName = "chain_heads"
+
+// TableName assigns the table name for chain heads.
+func (ChainHead) TableName() string {
+	return ChainHeadTableName
 }
 
 // Ensure GormStore implements both EventStore and SnapshotStore interfaces.
 var _ SnapshotStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
 var _ EventStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
+var _ Verifier[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
+var _ SignedEventStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
+var _ ConcurrentEventStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
+var _ ConcurrentSnapshotStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
+var _ TailEventStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
 
 // GormStore provides database-backed event and snapshot storage.
 type GormStore[E lavender.Event, S lavender.Snapshot] struct {
-	Encoder          encoders.Encoder
-	Db               *gorm.DB
+	Encoder encoders.Encoder
+	Db      *gorm.DB
+	// SharedTable stores the events of all aggregates in the single SharedEventTableName table
+	// instead of one event_<aggregate> table per aggregate.
+	SharedTable bool
+	// HashChain links every stored event to the previous event of the stream by a hash over its canonical data.
+	// The hash is verified against the decoded event, so the Encoder must keep every field of the events:
+	// fields it drops, like `json:"-"` fields, break the chain.
+	HashChain bool
+	// Instrumentation receives the spans and metrics of the store.
+	Instrumentation instrument.Instrumentation
+	// Clock tells the time recorded for stored events and snapshots.
+	Clock lavender.Clock
+	// IDGenerator creates the IDs of stored events and snapshots.
+	IDGenerator lavender.IDGenerator
+	// ArchiveDir is the directory archived events are written to, archiving is disabled if it is empty.
+	ArchiveDir string
+	// SkipMigration registers aggregates without creating or changing their tables, Migrate does it on demand.
+	SkipMigration    bool
 	eventRegister    map[lavender.EventIdentifier]E
+	eventAggregates  []lavender.Name // Names of the aggregates with registered events
 	snapshotRegister map[lavender.Name]S
 }
 
@@ -69,11 +147,58 @@ func NewGormCustomStore[E lavender.Event, S lavender.Snapshot](db *gorm.DB, enco
 	return &GormStore[E, S]{
 		Encoder:          encoder,
 		Db:               db,
+		Instrumentation:  instrument.Noop{},
+		Clock:            lavender.SystemClock{},
+		IDGenerator:      lavender.UUIDv7Generator{},
 		eventRegister:    make(map[lavender.EventIdentifier]E),
 		snapshotRegister: make(map[lavender.Name]S),
 	}
 }
 
+// UseSharedTable enables the shared events table. It must be called before any aggregate is registered.
+func (store *GormStore[E, S]) UseSharedTable() *GormStore[E, S] {
+	store.SharedTable = true
+	return store
+}
+
+// UseHashChain enables the tamper-evident hash chain for all events stored from now on.
+func (store *GormStore[E, S]) UseHashChain() *GormStore[E, S] {
+	store.HashChain = true
+	return store
+}
+
+// UseExistingSchema makes the store work on the tables as they are: registering aggregates doesn't migrate
+// them. It must be called before any aggregate is registered.
+func (store *GormStore[E, S]) UseExistingSchema() *GormStore[E, S] {
+	store.SkipMigration = true
+	return store
+}
+
+// UseClock sets the clock that tells the time recorded for stored events and snapshots.
+func (store *GormStore[E, S]) UseClock(clock lavender.Clock) *GormStore[E, S] {
+	store.Clock = clock
+	return store
+}
+
+// UseIDGenerator sets the generator of the IDs of stored events and snapshots.
+func (store *GormStore[E, S]) UseIDGenerator(generator lavender.IDGenerator) *GormStore[E, S] {
+	store.IDGenerator = generator
+	return store
+}
+
+// instrumentation returns the instrumentation of the store, which defaults to instrument.Noop.
+func (store *GormStore[E, S]) instrumentation() instrument.Instrumentation {
+	return instrument.Or(store.Instrumentation)
+}
+
+// eventTable returns the table holding the events of the given aggregate.
+func (store *GormStore[E, S]) eventTable(name lavender.Name) string {
+	if store.SharedTable {
+		return SharedEventTableName
+	}
+	return EventTableName(name)
+}
+
 // RegisterAggregates registers multiple aggregates for event and snapshot tracking.
 func (store *GormStore[E, S]) RegisterAggregates(aggregates ...lavender.CustomAggregate[E, S]) *GormStore[E, S] {
 	for _, aggregate := range aggregates {
@@ -83,68 +208,393 @@ func (store *GormStore[E, S]) RegisterAggregates(aggregates ...lavender.CustomAg
 	return store
 }
 
-// RegisterEvent registers event types for an aggregate and auto-migrates the event table.
+// RegisterEvent registers event types for an aggregate and auto-migrates the event table unless
+// SkipMigration is set.
 func (store *GormStore[E, S]) RegisterEvent(aggregate lavender.CustomAggregate[E, S], events ...E) *GormStore[E, S] {
 	for _, event := range events {
 		store.eventRegister[lavender.EventId(aggregate.Name(), event.Name())] = event
-		store.Db.Table(EventTableName(aggregate.Name())).AutoMigrate(new(Event))
+		if !slices.Contains(store.eventAggregates, aggregate.Name()) {
+			store.eventAggregates = append(store.eventAggregates, aggregate.Name())
+		}
+		encoders.RegisterTypes(store.Encoder, event)
+		if !store.SkipMigration {
+			store.migrateEvents(store.eventTable(aggregate.Name()))
+		}
+	}
+	if !store.SkipMigration {
+		store.Db.AutoMigrate(new(Stream))
 	}
 	return store
 }
 
-// RegisterSnapshot registers snapshot types for an aggregate and auto-migrates the snapshot table.
+// migrateEvents auto-migrates the event table and numbers the events stored before global positions and
+// stream sequences have been recorded. They are appended to their streams in the order they have been
+// created, ties are broken by their data so the numbering is deterministic.
+func (store *GormStore[E, S]) migrateEvents(table string) error {
+	if err := store.Db.Table(table).AutoMigrate(new(Event)); err != nil {
+		return err
+	}
+	if err := store.Db.AutoMigrate(new(ChainHead)); err != nil {
+		return err
+	}
+	return store.Db.Transaction(func(tx *gorm.DB) error {
+		var rows []Event
+		if err := tx.Table(table).Where("position IS NULL OR position = 0").Order("created_at, tenant, name, version, topic, event").Find(&rows).Error; err != nil || len(rows) == 0 {
+			return err
+		}
+		if err := tx.Table(table).Where("position IS NULL OR position = 0").Delete(&Event{}).Error; err != nil {
+			return err
+		}
+		for i := range rows {
+			if rows[i].EventID == "" {
+				rows[i].EventID = lavender.IDGeneratorOr(store.IDGenerator).NewID()
+			}
+		}
+		return store.appendEvents(tx, table, rows)
+	})
+}
+
+// RegisterSnapshot registers snapshot types for an aggregate and auto-migrates the snapshot table unless
+// SkipMigration is set.
 func (store *GormStore[E, S]) RegisterSnapshot(snapshots ...S) *GormStore[E, S] {
 	for _, snapshot := range snapshots {
 		store.snapshotRegister[snapshot.AggregateID()] = snapshot
-		store.Db.Table(SnapshotTableName(snapshot.AggregateID())).AutoMigrate(new(Snapshot))
+		encoders.RegisterTypes(store.Encoder, snapshot)
+		if !store.SkipMigration {
+			store.Db.Table(SnapshotTableName(snapshot.AggregateID())).AutoMigrate(new(Snapshot))
+		}
 	}
 	return store
 }
 
 // ClearEvents removes all events for an aggregate from the database.
 func (store *GormStore[E, S]) ClearEvents(aggregate lavender.CustomAggregate[E, S]) error {
-	return store.Db.Table(EventTableName(aggregate.Name())).Where("name = ? AND version = ?", aggregate.Name(), aggregate.Version()).Delete(&Event{}).Error
+	return whereStream(store.Db.Table(store.eventTable(aggregate.Name())), streamOf(aggregate)).Where("version = ?", aggregate.Version()).Delete(&Event{}).Error
+}
+
+// ClearEventsUntil implements ConcurrentEventStore.
+func (store *GormStore[E, S]) ClearEventsUntil(aggregate lavender.CustomAggregate[E, S], sequence uint64) error {
+	return whereStream(store.Db.Table(store.eventTable(aggregate.Name())), streamOf(aggregate)).Where("version = ? AND sequence <= ?", aggregate.Version(), sequence).Delete(&Event{}).Error
 }
 
 // LoadEvents retrieves all events for an aggregate from the database.
 func (store *GormStore[E, S]) LoadEvents(aggregate lavender.CustomAggregate[E, S]) (events []E, err error) {
+	events, _, err = store.LoadSignedEvents(aggregate)
+	return events, err
+}
+
+// LoadSignedEvents retrieves all events for an aggregate and their signatures from the database. Events
+// hidden by the stream metadata are skipped.
+func (store *GormStore[E, S]) LoadSignedEvents(aggregate lavender.CustomAggregate[E, S]) ([]E, []Signature, error) {
+	return store.LoadEventsAfter(aggregate, 0)
+}
+
+// LoadEventsAfter implements TailEventStore. Events hidden by the stream metadata are skipped.
+func (store *GormStore[E, S]) LoadEventsAfter(aggregate lavender.CustomAggregate[E, S], sequence uint64) (events []E, signatures []Signature, err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanLoadEvents, aggregate.Name())(&err)
 	var readedEvents []Event
 
-	if err := store.Db.Table(EventTableName(aggregate.Name())).Where("name = ? AND version = ?", aggregate.Name(), aggregate.Version()).Find(&readedEvents).Error; err != nil {
-		return nil, err
+	// The visibility of an event only depends on the event and the head of the stream, so the covered events
+	// can be left out of the query
+	if err := whereStream(store.Db.Table(store.eventTable(aggregate.Name())), streamOf(aggregate)).Where("version = ? AND sequence > ?", aggregate.Version(), sequence).Order("sequence, position").Find(&readedEvents).Error; err != nil {
+		return nil, nil, err
+	}
+	stream, err := store.stream(store.Db, streamOf(aggregate))
+	if err != nil {
+		return nil, nil, err
 	}
+	readedEvents = readedEvents[stream.firstVisible(len(readedEvents), rowAt(readedEvents), lavender.ClockOr(store.Clock).Now()):]
 	for _, eventData := range readedEvents {
-		event, ok := store.eventRegister[lavender.EventId(aggregate.Name(), eventData.Topic)]
-		if !ok {
-			return nil, fmt.Errorf("invalid event type %s", eventData.Topic)
+		event, err := store.decodeEvent(aggregate, eventData)
+		if err != nil {
+			return nil, nil, err
 		}
+		events = append(events, event)
+		signatures = append(signatures, Signature{KeyID: eventData.KeyID, Signature: eventData.Signature, EventID: eventData.EventID, Sequence: eventData.Sequence})
+	}
+	return events, signatures, nil
+}
 
-		eventcp := clone.Clone(event).(E)
-		if err := store.Encoder.Unmarshal([]byte(eventData.Event), eventcp); err != nil {
-			return nil, err
-		}
-		events = append(events, eventcp)
+// decodeEvent decodes a stored event with the registered event type.
+func (store *GormStore[E, S]) decodeEvent(aggregate lavender.CustomAggregate[E, S], eventData Event) (E, error) {
+	event, ok := store.eventRegister[lavender.EventId(aggregate.Name(), eventData.Topic)]
+	if !ok {
+		return event, fmt.Errorf("invalid event type %s", eventData.Topic)
 	}
-	return events, nil
+
+	eventcp := clone.Clone(event).(E)
+	if err := decode(store.instrumentation(), store.Encoder, streamOf(aggregate).Stream(), eventData.ContentType, eventData.Event, eventcp); err != nil {
+		return eventcp, fmt.Errorf("decode event %s: %w", eventData.Topic, err)
+	}
+	return eventcp, nil
 }
 
 // SaveEvents stores multiple events for an aggregate within a database transaction.
 func (store *GormStore[E, S]) SaveEvents(aggregate lavender.CustomAggregate[E, S], events []E) error {
-	return store.Db.Transaction(func(tx *gorm.DB) error {
-		for _, event := range events {
-			encodedData, err := store.Encoder.Marshal(event)
+	return store.SaveSignedEvents(aggregate, events, nil)
+}
+
+// SaveSignedEvents stores multiple events for an aggregate and their signatures within a database transaction.
+func (store *GormStore[E, S]) SaveSignedEvents(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature) error {
+	return store.saveEvents(aggregate, events, signatures, nil)
+}
+
+// SaveEventsAt implements ConcurrentEventStore. The stream head is checked within the database transaction.
+func (store *GormStore[E, S]) SaveEventsAt(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature, expected uint64) error {
+	return store.saveEvents(aggregate, events, signatures, &expected)
+}
+
+// Sequence implements ConcurrentEventStore.
+func (store *GormStore[E, S]) Sequence(aggregate lavender.CustomAggregate[E, S]) (uint64, error) {
+	head, err := store.streamHead(store.Db, store.eventTable(aggregate.Name()), streamOf(aggregate))
+	return head.Sequence, err
+}
+
+// saveEvents stores the events and their signatures. If expected is set the events are only stored if
+// the stream head is still at the expected sequence.
+func (store *GormStore[E, S]) saveEvents(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature, expected *uint64) (err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanSaveEvents, aggregate.Name())(&err)
+	if signatures != nil && len(signatures) != len(events) {
+		return fmt.Errorf("got %d signatures for %d events", len(signatures), len(events))
+	}
+	rows := make([]Event, 0, len(events))
+	for i, event := range events {
+		encodedData, err := encode(store.instrumentation(), store.Encoder, streamOf(aggregate).Stream(), event)
+		if err != nil {
+			return err
+		}
+		row := Event{
+			EventID:     lavender.IDGeneratorOr(store.IDGenerator).NewID(),
+			CreatedAt:   lavender.ClockOr(store.Clock).Now(),
+			Tenant:      lavender.TenantOf(aggregate),
+			Name:        aggregate.Name(),
+			Version:     aggregate.Version(),
+			Topic:       event.Name(),
+			ContentType: encoders.ContentTypeOf(store.Encoder),
+			Event:       encodedData,
+		}
+		if signatures != nil {
+			if signatures[i].EventID != "" {
+				row.EventID = signatures[i].EventID
+			}
+			row.KeyID = signatures[i].KeyID
+			row.Signature = signatures[i].Signature
+			row.signed = signatures[i].Sequence
+		}
+		if store.HashChain {
+			if row.digest, err = canonicalEncoder.Marshal(event); err != nil {
+				return err
+			}
+		}
+		rows = append(rows, row)
+	}
+	return store.appendTransaction(func(tx *gorm.DB) error {
+		stream, err := store.stream(tx, streamOf(aggregate))
+		if err != nil {
+			return err
+		}
+		if err := stream.State.appendErr(); err != nil {
+			return err
+		}
+		table := store.eventTable(aggregate.Name())
+		if expected != nil {
+			head, err := store.streamHead(tx, table, streamOf(aggregate))
 			if err != nil {
 				return err
 			}
+			if head.Sequence != *expected {
+				return ErrConflict
+			}
+		}
+		return store.appendEvents(tx, table, rows)
+	})
+}
 
-			err = tx.Table(EventTableName(aggregate.Name())).Create(Event{
-				CreatedAt: time.Now(),
-				Name:      aggregate.Name(),
-				Version:   aggregate.Version(),
-				Topic:     event.Name(),
-				Event:     string(encodedData),
-			}).Error
-			if err != nil {
+// appendEvents assigns category, stream sequence, global position and the hash chain link to the rows and
+// inserts them into the table. Rows without canonical data are not linked into the hash chain.
+func (store *GormStore[E, S]) appendEvents(tx *gorm.DB, table string, rows []Event) error {
+	var position uint64
+	if err := tx.Table(table).Select("COALESCE(MAX(position), 0)").Scan(&position).Error; err != nil {
+		return err
+	}
+	heads := make(map[streamID]chainPin)
+	var chained []streamID
+	for _, row := range rows {
+		head, ok := heads[row.stream()]
+		if !ok {
+			var err error
+			if head, err = store.streamHead(tx, table, row.stream()); err != nil {
+				return err
+			}
+		}
+		position++
+		head.Sequence++
+
+		row.Category = row.Name.Category()
+		row.Sequence = head.Sequence
+		row.Position = position
+		if row.signed != 0 && row.signed != row.Sequence {
+			return ErrConflict
+		}
+		if row.digest != nil {
+			row.PrevHash = head.ChainHead
+			row.Hash = chainDigest(head.ChainHead, row.Topic, row.digest)
+		}
+		head.ChainHead = row.Hash
+		heads[row.stream()] = head
+		if row.Hash != nil && !slices.Contains(chained, row.stream()) {
+			chained = append(chained, row.stream())
+		}
+
+		if err := tx.Table(table).Create(&row).Error; err != nil {
+			return conflictErr(tx, err)
+		}
+	}
+	for _, id := range chained {
+		if err := store.saveChainHead(tx, id, heads[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveChainHead records the head of the hash chain of a stream.
+func (store *GormStore[E, S]) saveChainHead(tx *gorm.DB, id streamID, head chainPin) error {
+	return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&ChainHead{Tenant: id.Tenant, Name: id.Name, Sequence: head.Sequence, Hash: head.ChainHead}).Error
+}
+
+// appendAttempts is the number of times an append transaction is run before a concurrent append that
+// keeps taking its rows is reported as ErrConflict.
+const appendAttempts = 10
+
+// errTaken is returned for rows whose global position or stream sequence has been taken by a concurrent
+// transaction.
+var errTaken = fmt.Errorf("%w: rows have been taken by a concurrent append", ErrConflict)
+
+// appendTransaction runs a transaction appending events. Appends to unrelated streams of a table take the
+// same global positions, so the transaction is run again with fresh positions and stream heads if its rows
+// have been taken by a concurrent transaction. Conflicts with the expected head of a stream or the
+// sequences of signed events are detected within the transaction and not retried.
+func (store *GormStore[E, S]) appendTransaction(fn func(tx *gorm.DB) error) (err error) {
+	for attempt := 1; attempt <= appendAttempts; attempt++ {
+		if err = store.Db.Transaction(fn); !errors.Is(err, errTaken) {
+			return err
+		}
+	}
+	return err
+}
+
+// conflictErr turns the violation of a unique index into errTaken, the rows have been taken by a
+// concurrent transaction.
+func conflictErr(tx *gorm.DB, err error) error {
+	if translator, ok := tx.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return errTaken
+	}
+	return err
+}
+
+// streamHead returns the sequence and hash of the last event of the stream. If the stream has no events
+// (e.g. they have been cleared after a snapshot) the head pinned by the latest snapshot is returned.
+func (store *GormStore[E, S]) streamHead(tx *gorm.DB, table string, id streamID) (chainPin, error) {
+	var last []Event
+	if err := whereStream(tx.Table(table), id).Order("sequence DESC").Limit(1).Find(&last).Error; err != nil {
+		return chainPin{}, err
+	}
+	if len(last) > 0 {
+		return chainPin{Sequence: last[0].Sequence, ChainHead: last[0].Hash}, nil
+	}
+
+	snapshotTable := SnapshotTableName(id.Name)
+	if !tx.Migrator().HasTable(snapshotTable) {
+		return chainPin{}, nil
+	}
+	var snapshots []Snapshot
+	if err := whereStream(tx.Table(snapshotTable), id).Order(latestSnapshotFirst).Limit(1).Find(&snapshots).Error; err != nil {
+		return chainPin{}, err
+	}
+	if len(snapshots) > 0 {
+		return chainPin{Sequence: snapshots[0].Sequence, ChainHead: snapshots[0].ChainHead}, nil
+	}
+	return chainPin{}, nil
+}
+
+// Verify implements Verifier. It walks the hash chain of all events stored for the aggregate's stream,
+// including archived events.
+func (store *GormStore[E, S]) Verify(aggregate lavender.CustomAggregate[E, S]) error {
+	rows, err := store.history(streamOf(aggregate), time.Time{})
+	if err != nil {
+		return err
+	}
+	links := make([]chainLink, 0, len(rows))
+	for _, row := range rows {
+		event, err := store.decodeEvent(aggregate, row)
+		if err != nil {
+			return err
+		}
+		links = append(links, chainLink{Sequence: row.Sequence, Topic: row.Topic, Hash: row.Hash, PrevHash: row.PrevHash, Event: event})
+	}
+
+	stream, err := store.stream(store.Db, streamOf(aggregate))
+	if err != nil {
+		return err
+	}
+	var pins []chainPin
+	if snapshotTable := SnapshotTableName(aggregate.Name()); store.Db.Migrator().HasTable(snapshotTable) {
+		if err := whereStream(store.Db.Table(snapshotTable), streamOf(aggregate)).Where("chain_head IS NOT NULL").Find(&pins).Error; err != nil {
+			return err
+		}
+	}
+	if stream.ScavengedHash != nil {
+		pins = append(pins, chainPin{Sequence: stream.ScavengedSequence, ChainHead: stream.ScavengedHash})
+	}
+	var head *chainPin
+	if store.Db.Migrator().HasTable(ChainHeadTableName) {
+		var heads []ChainHead
+		if err := store.Db.Where("tenant = ? AND name = ?", stream.Tenant, stream.Name).Limit(1).Find(&heads).Error; err != nil {
+			return err
+		}
+		if len(heads) > 0 {
+			head = &chainPin{Sequence: heads[0].Sequence, ChainHead: heads[0].Hash}
+		}
+	}
+	return verifyChain(aggregate.Name(), links, pins, head)
+}
+
+// MigrateSharedTable moves the events of the given aggregates from their event_<aggregate> tables into the
+// shared events table and drops the old tables. Aggregates without an old table are skipped.
+func (store *GormStore[E, S]) MigrateSharedTable(aggregates ...lavender.CustomAggregate[E, S]) error {
+	if !store.SharedTable {
+		return fmt.Errorf("shared events table is not enabled")
+	}
+	if err := store.migrateEvents(SharedEventTableName); err != nil {
+		return err
+	}
+	// Events of old tables are numbered first, so they keep their order in the shared table
+	for _, aggregate := range aggregates {
+		if table := EventTableName(aggregate.Name()); store.Db.Migrator().HasTable(table) {
+			if err := store.migrateEvents(table); err != nil {
+				return err
+			}
+		}
+	}
+	return store.Db.Transaction(func(tx *gorm.DB) error {
+		for _, aggregate := range aggregates {
+			table := EventTableName(aggregate.Name())
+			if !tx.Migrator().HasTable(table) {
+				continue
+			}
+
+			var rows []Event
+			if err := tx.Table(table).Order("position").Find(&rows).Error; err != nil {
+				return err
+			}
+			if err := store.appendEvents(tx, SharedEventTableName, rows); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropTable(table); err != nil {
 				return err
 			}
 		}
@@ -153,10 +603,11 @@ func (store *GormStore[E, S]) SaveEvents(aggregate lavender.CustomAggregate[E, S
 }
 
 // LoadSnapshot retrieves the latest snapshot for an aggregate.
-func (store *GormStore[E, S]) LoadSnapshot(aggregate lavender.CustomAggregate[E, S]) (*S, error) {
+func (store *GormStore[E, S]) LoadSnapshot(aggregate lavender.CustomAggregate[E, S]) (_ *S, err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanLoadSnapshot, aggregate.Name())(&err)
 	var snapshotData Snapshot
 
-	tx := store.Db.Table(SnapshotTableName(aggregate.Name())).Where("name = ? AND version = ?", aggregate.Name(), aggregate.Version()).Order("created_at DESC").First(&snapshotData)
+	tx := whereStream(store.Db.Table(SnapshotTableName(aggregate.Name())), streamOf(aggregate)).Where("version = ?", aggregate.Version()).Order(latestSnapshotFirst).First(&snapshotData)
 
 	if tx.RowsAffected == 0 {
 		return nil, nil
@@ -164,28 +615,94 @@ func (store *GormStore[E, S]) LoadSnapshot(aggregate lavender.CustomAggregate[E,
 	if err := tx.Error; err != nil {
 		return nil, err
 	}
+	snapshot, err := store.decodeSnapshot(aggregate, snapshotData)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// decodeSnapshot decodes a stored snapshot with the registered snapshot type.
+func (store *GormStore[E, S]) decodeSnapshot(aggregate lavender.CustomAggregate[E, S], snapshotData Snapshot) (S, error) {
 	snapshot, ok := store.snapshotRegister[aggregate.Name()]
 	if !ok {
-		return nil, fmt.Errorf("invalid snapshot type %s", aggregate.Name())
+		return snapshot, fmt.Errorf("invalid snapshot type %s", aggregate.Name())
 	}
 
-	// copy := clone.Clone(snapshot).(*S)
-	if err := store.Encoder.Unmarshal([]byte(snapshotData.Snapshot), snapshot); err != nil {
-		return nil, err
+	// Decode into a copy, so snapshots loaded before stay unchanged
+	snapshot = clone.Clone(snapshot).(S)
+	if err := decode(store.instrumentation(), store.Encoder, streamOf(aggregate).Stream(), snapshotData.ContentType, snapshotData.Snapshot, snapshot); err != nil {
+		return snapshot, fmt.Errorf("decode snapshot %s: %w", aggregate.Name(), err)
 	}
-	return &snapshot, nil
+	return snapshot, nil
 }
 
 // SaveSnapshot stores a snapshot of an aggregate's state.
 func (store *GormStore[E, S]) SaveSnapshot(aggregate lavender.CustomAggregate[E, S], snapshot S) error {
-	encodedData, err := store.Encoder.Marshal(snapshot)
+	return store.saveSnapshot(aggregate, snapshot, nil)
+}
+
+// SaveSnapshotAt implements ConcurrentSnapshotStore. The stream head is checked within the database
+// transaction.
+func (store *GormStore[E, S]) SaveSnapshotAt(aggregate lavender.CustomAggregate[E, S], snapshot S, expected uint64) error {
+	return store.saveSnapshot(aggregate, snapshot, &expected)
+}
+
+// saveSnapshot stores a snapshot of an aggregate's state. If expected is set the snapshot is only stored if
+// the stream head is still at the expected sequence.
+func (store *GormStore[E, S]) saveSnapshot(aggregate lavender.CustomAggregate[E, S], snapshot S, expected *uint64) (err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanSaveSnapshot, aggregate.Name())(&err)
+	encodedData, err := encode(store.instrumentation(), store.Encoder, streamOf(aggregate).Stream(), snapshot)
 	if err != nil {
 		return err
 	}
-	return store.Db.Table(SnapshotTableName(aggregate.Name())).Create(&Snapshot{
-		CreatedAt: time.Now(),
-		Version:   aggregate.Version(),
-		Name:      aggregate.Name(),
-		Snapshot:  string(encodedData),
-	}).Error
+	return store.Db.Transaction(func(tx *gorm.DB) error {
+		stream, err := store.stream(tx, streamOf(aggregate))
+		if err != nil {
+			return err
+		}
+		if stream.State.Deleted() {
+			return ErrStreamDeleted
+		}
+
+		// Pin the chain head the snapshot has been taken at.
+		head, err := store.streamHead(tx, store.eventTable(aggregate.Name()), streamOf(aggregate))
+		if err != nil {
+			return err
+		}
+		if expected != nil && head.Sequence != *expected {
+			return ErrConflict
+		}
+		row := Snapshot{
+			SnapshotID:  lavender.IDGeneratorOr(store.IDGenerator).NewID(),
+			CreatedAt:   lavender.ClockOr(store.Clock).Now(),
+			Tenant:      lavender.TenantOf(aggregate),
+			Version:     aggregate.Version(),
+			Name:        aggregate.Name(),
+			Sequence:    head.Sequence,
+			ChainHead:   head.ChainHead,
+			Schema:      lavender.SchemaVersionOf(snapshot),
+			ContentType: encoders.ContentTypeOf(store.Encoder),
+			Snapshot:    encodedData,
+		}
+
+		// A snapshot taken at the same sequence is replaced, so there is a single latest snapshot
+		table := SnapshotTableName(aggregate.Name())
+		result := whereStream(tx.Table(table), streamOf(aggregate)).Where("version = ? AND sequence = ?", row.Version, row.Sequence).Updates(map[string]any{
+			"snapshot_id":  row.SnapshotID,
+			"created_at":   row.CreatedAt,
+			"schema":       row.Schema,
+			"content_type": row.ContentType,
+			"snapshot":     row.Snapshot,
+		})
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+		return tx.Table(table).Create(&row).Error
+	})
+}
+
+// whereStream restricts a query to the rows of the stream.
+func whereStream(tx *gorm.DB, id streamID) *gorm.DB {
+	return tx.Where("name = ? AND tenant = ?", id.Name, id.Tenant)
 }
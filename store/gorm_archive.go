@@ -0,0 +1,287 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/instrument"
+	"gorm.io/gorm"
+)
+
+// ErrArchiveCorrupted is returned when an archive file doesn't match the checksum it has been written with.
+var ErrArchiveCorrupted = errors.New("archive file is corrupted")
+
+// ArchiveSegment is the index entry of an archive file holding a range of events of a stream.
+type ArchiveSegment struct {
+	ID            uint          `gorm:"primaryKey"`
+	CreatedAt     time.Time     // Timestamp when the events were archived.
+	Tenant        string        `gorm:"index:,composite:stream,priority:1;not null;default:''"` // Tenant the stream belongs to
+	Name          lavender.Name `gorm:"index:,composite:stream,priority:2"`                     // Aggregate name (stream ID)
+	FirstSequence uint64        `gorm:"index:,composite:stream,priority:3"`                     // Sequence of the first archived event
+	LastSequence  uint64        // Sequence of the last archived event
+	Count         int           // Number of archived events
+	Path          string        // Path of the archive file relative to the archive directory
+	ContentType   string        // Content type of the encoder that produced the archive file
+	Checksum      []byte        // SHA-256 of the archive file
+}
+
+// ArchiveTableName is the table indexing the archive files.
+const ArchiveTableName = "archives"
+
+// TableName assigns the table name for archive segments.
+func (ArchiveSegment) TableName() string {
+	return ArchiveTableName
+}
+
+// archiveFile is the content of an archive file, it is encoded with the store's encoder and compressed.
+type archiveFile struct {
+	Events []Event
+}
+
+var _ HistoryStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
+
+// UseArchive sets the directory archive files are written to and migrates the archive index unless
+// SkipMigration is set.
+func (store *GormStore[E, S]) UseArchive(dir string) *GormStore[E, S] {
+	store.ArchiveDir = dir
+	if !store.SkipMigration {
+		store.Db.AutoMigrate(new(ArchiveSegment))
+	}
+	return store
+}
+
+// ArchiveEvents moves events that are older than the retention and covered by the latest snapshot of their
+// stream from the database into archive files. Every stream of a registered aggregate is archived within
+// its own database transaction. It returns the number of archived events.
+func (store *GormStore[E, S]) ArchiveEvents(retention time.Duration) (archived int, err error) {
+	span := store.instrumentation().StartSpan(instrument.SpanArchive)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	if store.ArchiveDir == "" {
+		return 0, fmt.Errorf("archive directory is not set")
+	}
+	cutoff := lavender.ClockOr(store.Clock).Now().Add(-retention)
+	for name := range store.snapshotRegister {
+		snapshotTable := SnapshotTableName(name)
+		if !store.Db.Migrator().HasTable(snapshotTable) || !store.Db.Migrator().HasTable(store.eventTable(name)) {
+			continue
+		}
+		var tenants []string
+		if err := store.Db.Table(snapshotTable).Distinct("tenant").Pluck("tenant", &tenants).Error; err != nil {
+			return archived, err
+		}
+		for _, tenant := range tenants {
+			count, err := store.archive(streamID{Tenant: tenant, Name: name}, cutoff)
+			if err != nil {
+				return archived, err
+			}
+			archived += count
+		}
+	}
+	return archived, nil
+}
+
+// archive moves the events of a stream stored before the cutoff and covered by its latest snapshot into an
+// archive file and returns how many have been moved.
+func (store *GormStore[E, S]) archive(id streamID, cutoff time.Time) (archived int, err error) {
+	table := store.eventTable(id.Name)
+	err = store.Db.Transaction(func(tx *gorm.DB) error {
+		var snapshots []Snapshot
+		if err := whereStream(tx.Table(SnapshotTableName(id.Name)), id).Order("sequence DESC").Limit(1).Find(&snapshots).Error; err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			return nil
+		}
+		var rows []Event
+		if err := whereStream(tx.Table(table), id).Where("sequence <= ?", snapshots[0].Sequence).Order("sequence").Find(&rows).Error; err != nil {
+			return err
+		}
+		// Only archive a prefix of the stream, so archived events always come before stored ones.
+		for archived < len(rows) && rows[archived].CreatedAt.Before(cutoff) {
+			archived++
+		}
+		if archived == 0 {
+			return nil
+		}
+		rows = rows[:archived]
+
+		segment, err := store.writeArchive(id, rows)
+		if err == nil {
+			err = tx.Create(&segment).Error
+		}
+		if err == nil {
+			err = whereStream(tx.Table(table), id).Where("sequence <= ?", segment.LastSequence).Delete(&Event{}).Error
+		}
+		if err != nil {
+			// The events stay in the database, drop the file
+			os.Remove(filepath.Join(store.ArchiveDir, segment.Path))
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if archived > 0 {
+		store.instrumentation().Count(instrument.MetricEventsArchived, int64(archived), instrument.Attr("aggregate", id.Name))
+	}
+	return archived, nil
+}
+
+// writeArchive writes the rows of a stream into a new archive file and returns its index entry.
+func (store *GormStore[E, S]) writeArchive(id streamID, rows []Event) (ArchiveSegment, error) {
+	first, last := rows[0].Sequence, rows[len(rows)-1].Sequence
+	segment := ArchiveSegment{
+		CreatedAt:     lavender.ClockOr(store.Clock).Now(),
+		Tenant:        id.Tenant,
+		Name:          id.Name,
+		FirstSequence: first,
+		LastSequence:  last,
+		Count:         len(rows),
+		Path:          filepath.Join(archiveDir(id), fmt.Sprintf("%020d-%020d.archive", first, last)),
+		ContentType:   encoders.ContentTypeOf(store.Encoder),
+	}
+	data, err := encode(store.instrumentation(), store.Encoder, id.Stream(), &archiveFile{Events: rows})
+	if err != nil {
+		return segment, err
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return segment, err
+	}
+	if err := writer.Close(); err != nil {
+		return segment, err
+	}
+	checksum := sha256.Sum256(buf.Bytes())
+	segment.Checksum = checksum[:]
+
+	path := filepath.Join(store.ArchiveDir, segment.Path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return segment, err
+	}
+	return segment, os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// archivedEvents returns the archived rows of a stream stored at or before until, in sequence order.
+func (store *GormStore[E, S]) archivedEvents(tx *gorm.DB, id streamID, until time.Time) ([]Event, error) {
+	if store.ArchiveDir == "" || !tx.Migrator().HasTable(ArchiveTableName) {
+		return nil, nil
+	}
+	var segments []ArchiveSegment
+	if err := whereStream(tx, id).Order("first_sequence").Find(&segments).Error; err != nil {
+		return nil, err
+	}
+	var rows []Event
+	for _, segment := range segments {
+		file, err := store.readArchive(id, segment)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range file.Events {
+			if !storedBy(row.CreatedAt, until) {
+				return rows, nil
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// readArchive reads and checks the archive file of a segment.
+func (store *GormStore[E, S]) readArchive(id streamID, segment ArchiveSegment) (*archiveFile, error) {
+	data, err := os.ReadFile(filepath.Join(store.ArchiveDir, segment.Path))
+	if err != nil {
+		return nil, err
+	}
+	if checksum := sha256.Sum256(data); !bytes.Equal(checksum[:], segment.Checksum) {
+		return nil, fmt.Errorf("%w: %s", ErrArchiveCorrupted, segment.Path)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrArchiveCorrupted, segment.Path, err)
+	}
+	defer reader.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(reader); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrArchiveCorrupted, segment.Path, err)
+	}
+	file := new(archiveFile)
+	if err := decode(store.instrumentation(), store.Encoder, id.Stream(), segment.ContentType, buf.Bytes(), file); err != nil {
+		return nil, fmt.Errorf("decode archive %s: %w", segment.Path, err)
+	}
+	return file, nil
+}
+
+// deleteArchive removes the archive files and index entries of a stream.
+func (store *GormStore[E, S]) deleteArchive(tx *gorm.DB, id streamID) error {
+	if store.ArchiveDir == "" || !tx.Migrator().HasTable(ArchiveTableName) {
+		return nil
+	}
+	var segments []ArchiveSegment
+	if err := whereStream(tx, id).Find(&segments).Error; err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		if err := os.Remove(filepath.Join(store.ArchiveDir, segment.Path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return whereStream(tx, id).Delete(&ArchiveSegment{}).Error
+}
+
+// LoadHistory implements HistoryStore. Events that have been moved to archive files are read from there.
+func (store *GormStore[E, S]) LoadHistory(aggregate lavender.CustomAggregate[E, S], until time.Time) (events []E, err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanLoadEvents, aggregate.Name())(&err)
+	rows, err := store.history(streamOf(aggregate), until)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if row.Version != aggregate.Version() {
+			continue
+		}
+		event, err := store.decodeEvent(aggregate, row)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// history returns the archived and stored rows of a stream stored at or before until, in sequence order.
+func (store *GormStore[E, S]) history(id streamID, until time.Time) ([]Event, error) {
+	rows, err := store.archivedEvents(store.Db, id, until)
+	if err != nil {
+		return nil, err
+	}
+	var stored []Event
+	query := whereStream(store.Db.Table(store.eventTable(id.Name)), id)
+	if !until.IsZero() {
+		query = query.Where("created_at <= ?", until)
+	}
+	if err := query.Order("sequence, position").Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	return append(rows, stored...), nil
+}
+
+// archiveDir returns the directory of the archive files of a stream relative to the archive directory.
+func archiveDir(id streamID) string {
+	if id.Tenant == "" {
+		return url.PathEscape(string(id.Name))
+	}
+	return filepath.Join(url.PathEscape(string(id.Name)), "tenants", url.PathEscape(id.Tenant))
+}
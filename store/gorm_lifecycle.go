@@ -0,0 +1,90 @@
+package store
+
+import (
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Stream represents the stored state of a stream. Streams without a row are active.
+type Stream struct {
+	Tenant            string            `gorm:"primaryKey"` // Tenant the stream belongs to
+	Name              lavender.Name     `gorm:"primaryKey"` // Aggregate name (stream ID)
+	State             StreamState       // Lifecycle state of the stream
+	StreamMetadata    `gorm:"embedded"` // Limits of the visible events
+	ScavengedSequence uint64            // Sequence of the last scavenged event
+	ScavengedHash     []byte            // Hash chain link of the last scavenged event
+	UpdatedAt         time.Time         // Timestamp when the stream was changed.
+}
+
+// StreamTableName is the table holding the state of all streams.
+const StreamTableName = "streams"
+
+// TableName assigns the table name for streams.
+func (Stream) TableName() string {
+	return StreamTableName
+}
+
+var _ LifecycleStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
+
+// StreamState implements LifecycleStore.
+func (store *GormStore[E, S]) StreamState(aggregate lavender.CustomAggregate[E, S]) (StreamState, error) {
+	stream, err := store.stream(store.Db, streamOf(aggregate))
+	return stream.State, err
+}
+
+// SetStreamState implements LifecycleStore. The state change and the removal of a tombstoned stream's
+// events and snapshots happen within a database transaction.
+func (store *GormStore[E, S]) SetStreamState(aggregate lavender.CustomAggregate[E, S], state StreamState) error {
+	id := streamOf(aggregate)
+	return store.Db.Transaction(func(tx *gorm.DB) error {
+		stream, err := store.stream(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := transition(stream.State, state); err != nil {
+			return err
+		}
+		if state == StreamTombstoned {
+			if err := whereStream(tx.Table(store.eventTable(id.Name)), id).Delete(&Event{}).Error; err != nil {
+				return err
+			}
+			if snapshotTable := SnapshotTableName(id.Name); tx.Migrator().HasTable(snapshotTable) {
+				if err := whereStream(tx.Table(snapshotTable), id).Delete(&Snapshot{}).Error; err != nil {
+					return err
+				}
+			}
+			if err := store.deleteArchive(tx, id); err != nil {
+				return err
+			}
+			if tx.Migrator().HasTable(ChainHeadTableName) {
+				if err := tx.Where("tenant = ? AND name = ?", id.Tenant, id.Name).Delete(&ChainHead{}).Error; err != nil {
+					return err
+				}
+			}
+			// Nothing of the stream is kept but its state
+			stream = Stream{Tenant: id.Tenant, Name: id.Name}
+		}
+		stream.State = state
+		stream.UpdatedAt = lavender.ClockOr(store.Clock).Now()
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&stream).Error
+	})
+}
+
+// stream returns the stored state of a stream, an active stream if there is none.
+func (store *GormStore[E, S]) stream(tx *gorm.DB, id streamID) (Stream, error) {
+	stream := Stream{Tenant: id.Tenant, Name: id.Name, State: StreamActive}
+	if !tx.Migrator().HasTable(StreamTableName) {
+		return stream, nil
+	}
+	var streams []Stream
+	if err := tx.Where("tenant = ? AND name = ?", id.Tenant, id.Name).Limit(1).Find(&streams).Error; err != nil {
+		return stream, err
+	}
+	if len(streams) > 0 {
+		return streams[0], nil
+	}
+	return stream, nil
+}
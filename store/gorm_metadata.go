@@ -0,0 +1,102 @@
+package store
+
+import (
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/instrument"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ MetadataStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
+var _ Scavenger = new(GormStore[lavender.Event, lavender.Snapshot])
+
+// StreamMetadata implements MetadataStore.
+func (store *GormStore[E, S]) StreamMetadata(aggregate lavender.CustomAggregate[E, S]) (StreamMetadata, error) {
+	stream, err := store.stream(store.Db, streamOf(aggregate))
+	return stream.StreamMetadata, err
+}
+
+// SetStreamMetadata implements MetadataStore.
+func (store *GormStore[E, S]) SetStreamMetadata(aggregate lavender.CustomAggregate[E, S], metadata StreamMetadata) error {
+	return store.Db.Transaction(func(tx *gorm.DB) error {
+		stream, err := store.stream(tx, streamOf(aggregate))
+		if err != nil {
+			return err
+		}
+		if stream.State == StreamTombstoned {
+			return ErrStreamDeleted
+		}
+		stream.StreamMetadata = metadata
+		stream.UpdatedAt = lavender.ClockOr(store.Clock).Now()
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&stream).Error
+	})
+}
+
+// Scavenge implements Scavenger. Every stream is scavenged within its own database transaction.
+func (store *GormStore[E, S]) Scavenge() (removed int, err error) {
+	span := store.instrumentation().StartSpan(instrument.SpanScavenge)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	if !store.Db.Migrator().HasTable(StreamTableName) {
+		return 0, nil
+	}
+	var streams []Stream
+	if err := store.Db.Where("max_count > 0 OR max_age > 0 OR truncate_before > 0").Find(&streams).Error; err != nil {
+		return removed, err
+	}
+	now := lavender.ClockOr(store.Clock).Now()
+	for _, stream := range streams {
+		count, err := store.scavenge(streamID{Tenant: stream.Tenant, Name: stream.Name}, now)
+		if err != nil {
+			return removed, err
+		}
+		removed += count
+	}
+	return removed, nil
+}
+
+// scavenge removes the hidden events of a stream and returns how many have been removed.
+func (store *GormStore[E, S]) scavenge(id streamID, now time.Time) (removed int, err error) {
+	table := store.eventTable(id.Name)
+	if !store.Db.Migrator().HasTable(table) {
+		return 0, nil
+	}
+	err = store.Db.Transaction(func(tx *gorm.DB) error {
+		stream, err := store.stream(tx, id)
+		if err != nil {
+			return err
+		}
+		var rows []Event
+		if err := whereStream(tx.Table(table), id).Select("sequence", "created_at", "hash").Order("sequence").Find(&rows).Error; err != nil {
+			return err
+		}
+		removed = stream.scavengeable(len(rows), rowAt(rows), now)
+		if removed == 0 {
+			return nil
+		}
+		// Pin the last removed event, so the hash chain of the remaining events can still be verified.
+		last := rows[removed-1]
+		if err := whereStream(tx.Table(table), id).Where("sequence <= ?", last.Sequence).Delete(&Event{}).Error; err != nil {
+			return err
+		}
+		stream.ScavengedSequence = last.Sequence
+		stream.ScavengedHash = last.Hash
+		stream.UpdatedAt = now
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&stream).Error
+	})
+	if err == nil && removed > 0 {
+		store.instrumentation().Count(instrument.MetricEventsScavenged, int64(removed), instrument.Attr("aggregate", id.Name))
+	}
+	return removed, err
+}
+
+// rowAt returns the sequence and creation time of the rows for StreamMetadata.firstVisible.
+func rowAt(rows []Event) func(i int) (uint64, time.Time) {
+	return func(i int) (uint64, time.Time) {
+		return rows[i].Sequence, rows[i].CreatedAt
+	}
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/FlauschigDings/lavender"
+	"gorm.io/gorm"
+)
+
+// ErrSchemaOutdated is returned by CheckSchema when a table of the store is missing or lacks a column.
+var ErrSchemaOutdated = errors.New("database schema is missing or outdated")
+
+// Migrate creates or updates the tables of the registered aggregates, the streams table and the archive
+// index if archiving is enabled. Events stored before global positions and stream sequences have been
+// recorded are numbered. It is what registering does unless SkipMigration is set.
+func (store *GormStore[E, S]) Migrate() error {
+	for _, table := range store.eventTables() {
+		if err := store.migrateEvents(table); err != nil {
+			return err
+		}
+	}
+	for _, name := range store.snapshotNames() {
+		if err := store.Db.Table(SnapshotTableName(name)).AutoMigrate(new(Snapshot)); err != nil {
+			return err
+		}
+	}
+	if len(store.eventAggregates) > 0 {
+		if err := store.Db.AutoMigrate(new(Stream)); err != nil {
+			return err
+		}
+	}
+	if store.ArchiveDir != "" {
+		return store.Db.AutoMigrate(new(ArchiveSegment))
+	}
+	return nil
+}
+
+// CheckSchema reports the tables and columns of the registered aggregates, the streams table and the
+// archive index that are missing in the database, so a store that skips the migration can tell whether it
+// works on the tables it expects. The errors wrap ErrSchemaOutdated.
+func (store *GormStore[E, S]) CheckSchema() error {
+	var errs []error
+	for _, table := range store.eventTables() {
+		errs = append(errs, store.checkTable(table, new(Event)))
+	}
+	for _, name := range store.snapshotNames() {
+		errs = append(errs, store.checkTable(SnapshotTableName(name), new(Snapshot)))
+	}
+	if len(store.eventAggregates) > 0 {
+		errs = append(errs, store.checkTable(StreamTableName, new(Stream)), store.checkTable(ChainHeadTableName, new(ChainHead)))
+	}
+	if store.ArchiveDir != "" {
+		errs = append(errs, store.checkTable(ArchiveTableName, new(ArchiveSegment)))
+	}
+	return errors.Join(errs...)
+}
+
+// checkTable makes sure the table exists and has the columns of the model.
+func (store *GormStore[E, S]) checkTable(table string, model any) error {
+	migrator := store.Db.Table(table).Migrator()
+	if !migrator.HasTable(table) {
+		return fmt.Errorf("%w: table %s doesn't exist", ErrSchemaOutdated, table)
+	}
+	stmt := &gorm.Statement{DB: store.Db}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName != "" && !migrator.HasColumn(model, field.DBName) {
+			return fmt.Errorf("%w: table %s has no column %s", ErrSchemaOutdated, table, field.DBName)
+		}
+	}
+	return nil
+}
+
+// eventTables returns the sorted event tables of the registered aggregates.
+func (store *GormStore[E, S]) eventTables() []string {
+	var tables []string
+	for _, name := range store.eventAggregates {
+		if table := store.eventTable(name); !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+	slices.Sort(tables)
+	return tables
+}
+
+// snapshotNames returns the sorted names of the aggregates with registered snapshots.
+func (store *GormStore[E, S]) snapshotNames() []lavender.Name {
+	names := make([]lavender.Name, 0, len(store.snapshotRegister))
+	for name := range store.snapshotRegister {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
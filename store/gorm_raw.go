@@ -0,0 +1,79 @@
+package store
+
+import (
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+)
+
+var _ RawStore = new(GormStore[lavender.Event, lavender.Snapshot])
+
+// Raw returns the event row as RawEvent.
+func (e Event) Raw() RawEvent {
+	return RawEvent{
+		ID:          e.EventID,
+		Position:    e.Position,
+		Tenant:      e.Tenant,
+		Name:        e.Name,
+		Sequence:    e.Sequence,
+		CreatedAt:   e.CreatedAt,
+		Version:     e.Version,
+		Topic:       e.Topic,
+		ContentType: e.ContentType,
+		Data:        e.Event,
+		Hash:        e.Hash,
+		PrevHash:    e.PrevHash,
+		Signature:   Signature{KeyID: e.KeyID, Signature: e.Signature},
+	}
+}
+
+// Raw returns the snapshot row as RawSnapshot.
+func (s Snapshot) Raw() RawSnapshot {
+	return RawSnapshot{
+		ID:          s.SnapshotID,
+		Tenant:      s.Tenant,
+		Name:        s.Name,
+		CreatedAt:   s.CreatedAt,
+		Version:     s.Version,
+		Sequence:    s.Sequence,
+		ChainHead:   s.ChainHead,
+		Schema:      s.Schema,
+		ContentType: s.ContentType,
+		Data:        s.Snapshot,
+	}
+}
+
+// LoadRawEvents implements RawStore.
+func (store *GormStore[E, S]) LoadRawEvents(stream StreamRef) ([]RawEvent, error) {
+	id := streamID(stream)
+	if !store.Db.Migrator().HasTable(store.eventTable(id.Name)) {
+		return nil, nil
+	}
+	rows, err := store.history(id, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]RawEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, row.Raw())
+	}
+	return events, nil
+}
+
+// LoadRawSnapshots implements RawStore.
+func (store *GormStore[E, S]) LoadRawSnapshots(stream StreamRef) ([]RawSnapshot, error) {
+	id := streamID(stream)
+	table := SnapshotTableName(id.Name)
+	if !store.Db.Migrator().HasTable(table) {
+		return nil, nil
+	}
+	var rows []Snapshot
+	if err := whereStream(store.Db.Table(table), id).Order(oldestSnapshotFirst).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	snapshots := make([]RawSnapshot, 0, len(rows))
+	for _, row := range rows {
+		snapshots = append(snapshots, row.Raw())
+	}
+	return snapshots, nil
+}
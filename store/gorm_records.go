@@ -0,0 +1,206 @@
+package store
+
+import (
+	"strings"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ RecordStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
+var _ StreamRecordStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
+
+// Streams implements RecordStore. It looks at every event, snapshot and archive table of the database,
+// also the ones of aggregates that haven't been registered, and at the stored stream states.
+func (store *GormStore[E, S]) Streams() ([]StreamRef, error) {
+	tables, err := store.Db.Migrator().GetTables()
+	if err != nil {
+		return nil, err
+	}
+	var streams []StreamRef
+	for _, table := range tables {
+		if !strings.HasPrefix(table, "event_") && !strings.HasPrefix(table, "snapshot_") && table != SharedEventTableName && table != ArchiveTableName && table != StreamTableName {
+			continue
+		}
+		var refs []StreamRef
+		if err := store.Db.Table(table).Distinct("tenant", "name").Find(&refs).Error; err != nil {
+			return nil, err
+		}
+		streams = append(streams, refs...)
+	}
+	return sortStreams(streams), nil
+}
+
+// LoadRecords implements RecordStore.
+func (store *GormStore[E, S]) LoadRecords(aggregate lavender.CustomAggregate[E, S]) ([]EventRecord[E], []SnapshotRecord[S], error) {
+	id := streamOf(aggregate)
+	rows, err := store.history(id, time.Time{})
+	if err != nil {
+		return nil, nil, err
+	}
+	events := make([]EventRecord[E], 0, len(rows))
+	for _, row := range rows {
+		event, err := store.decodeEvent(aggregate, row)
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, EventRecord[E]{
+			Event:     event,
+			ID:        row.EventID,
+			Sequence:  row.Sequence,
+			CreatedAt: row.CreatedAt,
+			Version:   row.Version,
+			Hash:      row.Hash,
+			PrevHash:  row.PrevHash,
+			Signature: Signature{KeyID: row.KeyID, Signature: row.Signature},
+		})
+	}
+
+	var snapshotRows []Snapshot
+	if snapshotTable := SnapshotTableName(id.Name); store.Db.Migrator().HasTable(snapshotTable) {
+		if err := whereStream(store.Db.Table(snapshotTable), id).Order(oldestSnapshotFirst).Find(&snapshotRows).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	snapshots := make([]SnapshotRecord[S], 0, len(snapshotRows))
+	for _, row := range snapshotRows {
+		snapshot, err := store.decodeSnapshot(aggregate, row)
+		if err != nil {
+			return nil, nil, err
+		}
+		snapshots = append(snapshots, SnapshotRecord[S]{
+			Snapshot:  snapshot,
+			ID:        row.SnapshotID,
+			CreatedAt: row.CreatedAt,
+			Version:   row.Version,
+			Sequence:  row.Sequence,
+			ChainHead: row.ChainHead,
+			Schema:    row.Schema,
+		})
+	}
+	return events, snapshots, nil
+}
+
+// ImportRecords implements RecordStore. The records are stored within a database transaction.
+func (store *GormStore[E, S]) ImportRecords(aggregate lavender.CustomAggregate[E, S], events []EventRecord[E], snapshots []SnapshotRecord[S]) error {
+	id := streamOf(aggregate)
+	table := store.eventTable(id.Name)
+	snapshotTable := SnapshotTableName(id.Name)
+	return store.appendTransaction(func(tx *gorm.DB) error {
+		stream, err := store.stream(tx, id)
+		if err != nil {
+			return err
+		}
+		if stream.State.Deleted() {
+			return ErrStreamDeleted
+		}
+		head, err := store.streamHead(tx, table, id)
+		if err != nil {
+			return err
+		}
+		archived, err := store.archivedEvents(tx, id, time.Time{})
+		if err != nil {
+			return err
+		}
+		var snapshotCount int64
+		if tx.Migrator().HasTable(snapshotTable) {
+			if err := whereStream(tx.Table(snapshotTable), id).Count(&snapshotCount).Error; err != nil {
+				return err
+			}
+		}
+		if head.Sequence > 0 || len(archived) > 0 || snapshotCount > 0 {
+			return ErrStreamNotEmpty
+		}
+
+		var position uint64
+		if err := tx.Table(table).Select("COALESCE(MAX(position), 0)").Scan(&position).Error; err != nil {
+			return err
+		}
+		for _, record := range events {
+			data, err := encode(store.instrumentation(), store.Encoder, id.Stream(), record.Event)
+			if err != nil {
+				return err
+			}
+			position++
+			if err := tx.Table(table).Create(&Event{
+				EventID:     record.ID,
+				Position:    position,
+				CreatedAt:   record.CreatedAt,
+				Tenant:      id.Tenant,
+				Category:    id.Name.Category(),
+				Name:        id.Name,
+				Sequence:    record.Sequence,
+				Version:     record.Version,
+				Topic:       record.Event.Name(),
+				ContentType: encoders.ContentTypeOf(store.Encoder),
+				Event:       data,
+				Hash:        record.Hash,
+				PrevHash:    record.PrevHash,
+				KeyID:       record.Signature.KeyID,
+				Signature:   record.Signature.Signature,
+			}).Error; err != nil {
+				return conflictErr(tx, err)
+			}
+			if record.Hash != nil {
+				head = chainPin{Sequence: record.Sequence, ChainHead: record.Hash}
+			}
+		}
+		if head.ChainHead != nil {
+			if err := store.saveChainHead(tx, id, head); err != nil {
+				return err
+			}
+		}
+		for _, record := range snapshots {
+			data, err := encode(store.instrumentation(), store.Encoder, id.Stream(), record.Snapshot)
+			if err != nil {
+				return err
+			}
+			if err := tx.Table(snapshotTable).Create(&Snapshot{
+				SnapshotID:  record.ID,
+				CreatedAt:   record.CreatedAt,
+				Tenant:      id.Tenant,
+				Version:     record.Version,
+				Name:        id.Name,
+				Sequence:    record.Sequence,
+				ChainHead:   record.ChainHead,
+				Schema:      record.Schema,
+				ContentType: encoders.ContentTypeOf(store.Encoder),
+				Snapshot:    data,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LoadStreamRecord implements StreamRecordStore.
+func (store *GormStore[E, S]) LoadStreamRecord(aggregate lavender.CustomAggregate[E, S]) (StreamRecord, error) {
+	stream, err := store.stream(store.Db, streamOf(aggregate))
+	return StreamRecord{
+		State:             stream.State,
+		Metadata:          stream.StreamMetadata,
+		ScavengedSequence: stream.ScavengedSequence,
+		ScavengedHash:     stream.ScavengedHash,
+	}, err
+}
+
+// ImportStreamRecord implements StreamRecordStore.
+func (store *GormStore[E, S]) ImportStreamRecord(aggregate lavender.CustomAggregate[E, S], record StreamRecord) error {
+	id := streamOf(aggregate)
+	if record.State == "" {
+		record.State = StreamActive
+	}
+	return store.Db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&Stream{
+		Tenant:            id.Tenant,
+		Name:              id.Name,
+		State:             record.State,
+		StreamMetadata:    record.Metadata,
+		ScavengedSequence: record.ScavengedSequence,
+		ScavengedHash:     record.ScavengedHash,
+		UpdatedAt:         lavender.ClockOr(store.Clock).Now(),
+	}).Error
+}
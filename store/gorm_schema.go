@@ -0,0 +1,64 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/instrument"
+	"gorm.io/gorm"
+)
+
+var _ SchemaStore[lavender.Event, lavender.Snapshot] = new(GormStore[lavender.Event, lavender.Snapshot])
+
+// LoadStoredSnapshot implements SchemaStore.
+func (store *GormStore[E, S]) LoadStoredSnapshot(aggregate lavender.CustomAggregate[E, S]) (_ *S, _ *RawSnapshot, err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanLoadSnapshot, aggregate.Name())(&err)
+	var row Snapshot
+	tx := whereStream(store.Db.Table(SnapshotTableName(aggregate.Name())), streamOf(aggregate)).Where("version = ?", aggregate.Version()).Order(latestSnapshotFirst).First(&row)
+	if tx.RowsAffected == 0 {
+		return nil, nil, nil
+	}
+	if err := tx.Error; err != nil {
+		return nil, nil, err
+	}
+	if _, ok := store.snapshotRegister[aggregate.Name()]; !ok {
+		return nil, nil, fmt.Errorf("invalid snapshot type %s", aggregate.Name())
+	}
+	raw := row.Raw()
+	snapshot, err := store.decodeSnapshot(aggregate, row)
+	if err != nil {
+		return nil, &raw, fmt.Errorf("%w: %w", ErrSnapshotUndecodable, err)
+	}
+	return &snapshot, &raw, nil
+}
+
+// ReplaceSnapshot implements SchemaStore.
+func (store *GormStore[E, S]) ReplaceSnapshot(aggregate lavender.CustomAggregate[E, S], id string, snapshot S) (err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanSaveSnapshot, aggregate.Name())(&err)
+	data, err := encode(store.instrumentation(), store.Encoder, streamOf(aggregate).Stream(), snapshot)
+	if err != nil {
+		return err
+	}
+	return store.Db.Transaction(func(tx *gorm.DB) error {
+		stream, err := store.stream(tx, streamOf(aggregate))
+		if err != nil {
+			return err
+		}
+		if stream.State.Deleted() {
+			return ErrStreamDeleted
+		}
+		result := whereStream(tx.Table(SnapshotTableName(aggregate.Name())), streamOf(aggregate)).Where("snapshot_id = ?", id).Updates(map[string]any{
+			"schema":       lavender.SchemaVersionOf(snapshot),
+			"content_type": encoders.ContentTypeOf(store.Encoder),
+			"snapshot":     data,
+		})
+		if err := result.Error; err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			return ErrSnapshotNotFound
+		}
+		return nil
+	})
+}
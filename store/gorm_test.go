@@ -3,10 +3,12 @@ package store_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/FlauschigDings/lavender"
 	encoders "github.com/FlauschigDings/lavender/encoder"
 	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/lavendertest"
 	"github.com/FlauschigDings/lavender/repo"
 	"github.com/FlauschigDings/lavender/store"
 	"github.com/google/uuid"
@@ -120,3 +122,269 @@ func TestEncoder(t *testing.T) {
 
 	}
 }
+
+func TestSharedTable(t *testing.T) {
+	db, err := Sqlite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormStore := store.NewGormStore(db.DB).UseSharedTable()
+	gormStore.RegisterAggregates(example.New())
+	repo := repo.NewRepositoryConstructor(false, gormStore, gormStore)
+
+	for _, user := range accounts {
+		if err := repo.AddEvent(example.New(), &example.Create{User: *example.NewUser(user, user)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.True(t, db.Migrator().HasTable(store.SharedEventTableName))
+	assert.False(t, db.Migrator().HasTable(store.EventTableName("account")))
+
+	var rows []store.Event
+	if err := db.Table(store.SharedEventTableName).Order("position").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, rows, len(accounts))
+	for i, row := range rows {
+		assert.Equal(t, uint64(i+1), row.Position)
+		assert.Equal(t, uint64(i+1), row.Sequence)
+		assert.Equal(t, lavender.Name("account"), row.Category)
+	}
+
+	data, err := example.Load(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, data.Emails, len(accounts))
+}
+
+func TestMigrateSharedTable(t *testing.T) {
+	db, err := Sqlite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyStore := store.NewGormStore(db.DB).UseClock(lavendertest.NewFakeClock(time.Now()))
+	legacyStore.RegisterAggregates(example.New())
+	for _, email := range accounts[:3] {
+		if err := legacyStore.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *example.NewUser(email, email)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sharedStore := store.NewGormStore(db.DB).UseSharedTable()
+	sharedStore.RegisterAggregates(example.New())
+	if err := sharedStore.MigrateSharedTable(example.New()); err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, db.Migrator().HasTable(store.EventTableName("account")))
+
+	events, err := sharedStore.LoadEvents(example.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Events stored at the same time keep their order.
+	if assert.Len(t, events, 3) {
+		for i, event := range events {
+			assert.Equal(t, accounts[i], event.(*example.Create).Email)
+		}
+	}
+
+	// Running the migration again is a no-op.
+	assert.NoError(t, sharedStore.MigrateSharedTable(example.New()))
+}
+
+func TestSwitchEncoder(t *testing.T) {
+	db, err := Sqlite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Write history with every built-in encoder into the same table.
+	for i, encoder := range encoderList {
+		gormStore := store.NewGormCustomStore[lavender.Event, lavender.Snapshot](db.DB, encoder)
+		gormStore.RegisterAggregates(example.New())
+		if err := gormStore.SaveEvents(example.New(), []lavender.Event{
+			&example.Create{User: *example.NewUser(accounts[i], accounts[i])},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var rows []store.Event
+	if err := db.Table(store.EventTableName("account")).Order("sequence").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range rows {
+		assert.Equal(t, encoders.ContentTypeOf(encoderList[i]), row.ContentType)
+	}
+
+	multiStore := store.NewGormCustomStore[lavender.Event, lavender.Snapshot](db.DB, encoders.NewMultiEncoder(encoders.NewCBorEncoder()))
+	multiStore.RegisterAggregates(example.New())
+	events, err := multiStore.LoadEvents(example.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, events, len(encoderList)) {
+		for i, event := range events {
+			assert.Equal(t, accounts[i], event.(*example.Create).Email)
+		}
+	}
+}
+
+func TestConcurrentAppend(t *testing.T) {
+	// A concurrent writer takes the next event between the read of the stream head and the insert. The
+	// append is run again with fresh positions and sequences, until it gives up after racing every time.
+	races := map[string]struct {
+		race  func(row *store.Event)
+		races int
+		err   error
+	}{
+		"position":       {race: func(row *store.Event) { row.Name = "other" }, races: 1},
+		"sequence":       {race: func(row *store.Event) { row.Position += 100 }, races: 1},
+		"every position": {race: func(row *store.Event) { row.Name = "other" }, races: 100, err: store.ErrConflict},
+	}
+	for name, test := range races {
+		t.Run(name, func(t *testing.T) {
+			db, err := Sqlite(":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gormStore := store.NewGormStore(db.DB)
+			gormStore.RegisterAggregates(example.New())
+
+			racing, inRace := test.races, false
+			db.Callback().Create().Before("gorm:create").Register("test:race", func(tx *gorm.DB) {
+				row, ok := tx.Statement.Dest.(*store.Event)
+				if !ok || racing == 0 || inRace {
+					return
+				}
+				racing--
+				other := *row
+				other.EventID = uuid.NewString()
+				test.race(&other)
+				inRace = true
+				tx.Session(&gorm.Session{NewDB: true}).Table(tx.Statement.Table).Create(&other)
+				inRace = false
+			})
+
+			err = gormStore.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *example.NewUser(accounts[0], accounts[0])}})
+			events, loadErr := gormStore.LoadEvents(example.New())
+			assert.NoError(t, loadErr)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				assert.Empty(t, events)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, events, 1)
+			}
+		})
+	}
+}
+
+// legacyEvent is an event row of the schema without event IDs, global positions and stream sequences.
+type legacyEvent struct {
+	CreatedAt time.Time
+	Name      lavender.Name
+	Version   lavender.Version
+	Topic     lavender.Name
+	Event     string
+}
+
+func TestMigrateLegacyEvents(t *testing.T) {
+	db, err := Sqlite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder := encoders.NewCBorEncoder()
+	table := store.EventTableName(example.New().Name())
+	if err := db.Table(table).AutoMigrate(new(legacyEvent)); err != nil {
+		t.Fatal(err)
+	}
+	createdAt := time.Now()
+	for _, email := range accounts[:3] {
+		data, err := encoder.Marshal(&example.Create{User: *example.NewUser(email, "secret")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		row := legacyEvent{CreatedAt: createdAt, Name: example.New().Name(), Version: example.New().Version(), Topic: "create", Event: string(data)}
+		if err := db.Table(table).Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+		createdAt = createdAt.Add(time.Millisecond)
+	}
+
+	// A store on the existing schema leaves the legacy table alone and reports it as outdated.
+	existing := store.NewGormStore(db.DB).UseExistingSchema().RegisterAggregates(example.New())
+	assert.ErrorIs(t, existing.CheckSchema(), store.ErrSchemaOutdated)
+	assert.False(t, db.Table(table).Migrator().HasColumn(new(store.Event), "position"))
+	assert.False(t, db.Migrator().HasTable(store.StreamTableName))
+
+	// Registering the aggregate numbers the events in the order they have been created.
+	gormStore := store.NewGormStore(db.DB).RegisterAggregates(example.New())
+	assert.NoError(t, existing.CheckSchema())
+	events, err := gormStore.LoadEvents(example.New())
+	if assert.NoError(t, err) {
+		assert.Equal(t, accounts[:3], emails(events))
+	}
+	assert.NoError(t, gormStore.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *example.NewUser(accounts[3], "secret")}}))
+	head, err := store.StreamSequence(gormStore, example.New())
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 4, head)
+	}
+
+	// Registering it again leaves the numbered events alone.
+	gormStore.RegisterAggregates(example.New())
+	events, err = gormStore.LoadEvents(example.New())
+	if assert.NoError(t, err) {
+		assert.Equal(t, accounts, emails(events))
+	}
+}
+
+// descendingIDs creates IDs that sort in reverse creation order, like random UUIDs may.
+type descendingIDs struct{ next int }
+
+func (g *descendingIDs) NewID() string {
+	g.next++
+	return fmt.Sprintf("%08d", 100000000-g.next)
+}
+
+func TestSnapshotOrder(t *testing.T) {
+	db, err := Sqlite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormStore := store.NewGormStore(db.DB).UseClock(lavendertest.NewFakeClock(time.Now())).UseIDGenerator(new(descendingIDs))
+	gormStore.RegisterAggregates(example.New())
+
+	// snapshot stores a snapshot holding the user.
+	snapshot := func(email string) {
+		aggregate := example.New()
+		aggregate.ApplyEvent(&example.Create{User: *example.NewUser(email, email)})
+		if err := gormStore.SaveSnapshot(example.New(), aggregate.TakeSnapshot()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// latest returns the user of the latest snapshot.
+	latest := func() string {
+		loaded, err := gormStore.LoadSnapshot(example.New())
+		if err != nil || loaded == nil {
+			t.Fatal(loaded, err)
+		}
+		return (*loaded).(*example.AccountSnapshot).Users[0].Email
+	}
+
+	assert.NoError(t, gormStore.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *example.NewUser(accounts[0], accounts[0])}}))
+	snapshot(accounts[0])
+	assert.NoError(t, gormStore.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *example.NewUser(accounts[1], accounts[1])}}))
+	snapshot(accounts[1])
+	assert.Equal(t, accounts[1], latest())
+
+	// Another snapshot at the same sequence replaces the one before.
+	snapshot(accounts[2])
+	assert.Equal(t, accounts[2], latest())
+	_, snapshots, err := gormStore.LoadRecords(example.New())
+	if assert.NoError(t, err) && assert.Len(t, snapshots, 2) {
+		assert.Equal(t, []uint64{1, 2}, []uint64{snapshots[0].Sequence, snapshots[1].Sequence})
+	}
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+)
+
+// HistoryStore is implemented by event stores that can replay the full history of a stream.
+type HistoryStore[E lavender.Event, S lavender.Snapshot] interface {
+	// LoadHistory returns the events of the aggregate's stream stored at or before until, a zero until
+	// returns all of them. Unlike LoadEvents it includes archived events and events hidden by the stream
+	// metadata that haven't been scavenged yet.
+	LoadHistory(aggregate lavender.CustomAggregate[E, S], until time.Time) ([]E, error)
+}
+
+// TailEventStore is implemented by event stores that can load the events following a sequence, e.g. the
+// ones that aren't covered by a snapshot yet.
+type TailEventStore[E lavender.Event, S lavender.Snapshot] interface {
+	// LoadEventsAfter retrieves the events of the aggregate's stream with a sequence after the given one
+	// and their signatures, like LoadSignedEvents.
+	LoadEventsAfter(aggregate lavender.CustomAggregate[E, S], sequence uint64) ([]E, []Signature, error)
+}
+
+// LoadEventsAfter loads the events with a sequence after the given one and their signatures. Stores that
+// don't implement TailEventStore load all events, the ones covered by the sequence are dropped if the store
+// returns the sequences of its events in their signatures.
+func LoadEventsAfter[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S], sequence uint64) ([]E, []Signature, error) {
+	if tailStore, ok := store.(TailEventStore[E, S]); ok {
+		events, signatures, err := tailStore.LoadEventsAfter(aggregate, sequence)
+		if !errors.Is(err, ErrUnsupported) {
+			return events, signatures, err
+		}
+	}
+	events, signatures, err := LoadSigned(store, aggregate)
+	if err != nil || sequence == 0 {
+		return events, signatures, err
+	}
+	start := 0
+	for start < len(events) && signatures[start].Sequence != 0 && signatures[start].Sequence <= sequence {
+		start++
+	}
+	return events[start:], signatures[start:], nil
+}
+
+// LoadHistory returns the history of the aggregate's stream up to until if the store supports it.
+func LoadHistory[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S], until time.Time) ([]E, error) {
+	if historyStore, ok := store.(HistoryStore[E, S]); ok {
+		return historyStore.LoadHistory(aggregate, until)
+	}
+	return nil, ErrUnsupported
+}
+
+// storedBy reports if something created at the given time has been stored at or before until.
+func storedBy(createdAt, until time.Time) bool {
+	return until.IsZero() || !createdAt.After(until)
+}
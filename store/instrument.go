@@ -0,0 +1,32 @@
+package store
+
+import (
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/instrument"
+)
+
+// startSpan starts a span for an aggregate. Call the returned function with the error of the operation to end it.
+func startSpan(instrumentation instrument.Instrumentation, name string, aggregate lavender.Name) func(err *error) {
+	span := instrumentation.StartSpan(name, instrument.Attr("aggregate", aggregate))
+	return func(err *error) {
+		span.SetError(*err)
+		span.End()
+	}
+}
+
+// encode encodes a value of a stream and counts the encoded bytes.
+func encode(instrumentation instrument.Instrumentation, encoder encoders.Encoder, stream lavender.Name, value any) ([]byte, error) {
+	data, err := encoders.MarshalStream(encoder, string(stream), value)
+	if err != nil {
+		return nil, err
+	}
+	instrumentation.Count(instrument.MetricEncodedBytes, int64(len(data)), instrument.Attr("aggregate", stream))
+	return data, nil
+}
+
+// decode decodes data of a stream and counts the decoded bytes.
+func decode(instrumentation instrument.Instrumentation, encoder encoders.Encoder, stream lavender.Name, contentType string, data []byte, value any) error {
+	instrumentation.Count(instrument.MetricDecodedBytes, int64(len(data)), instrument.Attr("aggregate", stream))
+	return encoders.UnmarshalStream(encoder, string(stream), contentType, data, value)
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EncryptionKey represents a stored encryption key of a stream.
+type EncryptionKey struct {
+	Stream      string     `gorm:"primaryKey"` // Stream the key belongs to
+	CreatedAt   time.Time  // Timestamp when the key was created.
+	Key         []byte     // AES key, nil once it has been deleted
+	ForgottenAt *time.Time // Timestamp when the key was deleted, nil while it exists
+}
+
+// TableName assigns the table name for encryption keys.
+func (EncryptionKey) TableName() string {
+	return "encryption_keys"
+}
+
+// Ensure GormKeyStore implements the KeyStore interface.
+var _ encoders.KeyStore = new(GormKeyStore)
+
+// GormKeyStore provides database-backed storage of the per stream encryption keys. Deleted keys are kept as
+// tombstones, so shredded streams don't get a new key.
+// Keep the keys in a different database than the events if a backup of the events must be shreddable too.
+type GormKeyStore struct {
+	Db *gorm.DB
+	// Clock tells the time recorded for created and deleted keys.
+	Clock lavender.Clock
+}
+
+// NewGormKeyStore initializes a GormKeyStore and auto-migrates the key table.
+func NewGormKeyStore(db *gorm.DB) (*GormKeyStore, error) {
+	if err := db.AutoMigrate(new(EncryptionKey)); err != nil {
+		return nil, err
+	}
+	return &GormKeyStore{Db: db, Clock: lavender.SystemClock{}}, nil
+}
+
+// Key implements encoders.KeyStore. Keys created concurrently for the same stream are resolved by the
+// database, all callers get the key stored first.
+func (store *GormKeyStore) Key(stream string) ([]byte, error) {
+	key, err := store.load(stream)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		data, err := encoders.NewKey()
+		if err != nil {
+			return nil, err
+		}
+		created := EncryptionKey{Stream: stream, CreatedAt: lavender.ClockOr(store.Clock).Now(), Key: data}
+		if err := store.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&created).Error; err != nil {
+			return nil, err
+		}
+		if key, err = store.load(stream); err != nil {
+			return nil, err
+		}
+	}
+	if key == nil || key.ForgottenAt != nil {
+		return nil, encoders.ErrForgotten
+	}
+	return key.Key, nil
+}
+
+// LookupKey implements encoders.KeyStore.
+func (store *GormKeyStore) LookupKey(stream string) ([]byte, error) {
+	key, err := store.load(stream)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.ForgottenAt != nil {
+		return nil, encoders.ErrForgotten
+	}
+	return key.Key, nil
+}
+
+// DeleteKey implements encoders.KeyStore. The key is replaced by a tombstone.
+func (store *GormKeyStore) DeleteKey(stream string) error {
+	now := lavender.ClockOr(store.Clock).Now()
+	return store.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "stream"}},
+		DoUpdates: clause.AssignmentColumns([]string{"key", "forgotten_at"}),
+	}).Create(&EncryptionKey{Stream: stream, CreatedAt: now, ForgottenAt: &now}).Error
+}
+
+// load returns the stored key or tombstone of the stream, nil if there is none.
+func (store *GormKeyStore) load(stream string) (*EncryptionKey, error) {
+	var keys []EncryptionKey
+	if err := store.Db.Where("stream = ?", stream).Limit(1).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return &keys[0], nil
+}
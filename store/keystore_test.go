@@ -0,0 +1,99 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCryptoShredding(t *testing.T) {
+	db, err := Sqlite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormKeys, err := store.NewGormKeyStore(db.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormStore := store.NewGormCustomStore[lavender.Event, lavender.Snapshot](db.DB, encoders.NewEncryptionEncoder(encoders.NewCBorEncoder(), gormKeys))
+	gormStore.RegisterAggregates(example.New())
+
+	memoryKeys := encoders.NewInMemoryKeyStore()
+	stores := map[string]struct {
+		keyStore encoders.KeyStore
+		store    interface {
+			store.EventStore[lavender.Event, lavender.Snapshot]
+			store.SnapshotStore[lavender.Event, lavender.Snapshot]
+		}
+	}{
+		"memory": {memoryKeys, store.NewInMemoryEncodedStore[lavender.Event, lavender.Snapshot](encoders.NewEncryptionEncoder(encoders.NewCBorEncoder(), memoryKeys))},
+		"gorm":   {gormKeys, gormStore},
+	}
+
+	for name, test := range stores {
+		t.Run(name, func(t *testing.T) {
+			eventStore := test.store
+			user := *example.NewUser("duck@ducky.com", "iL0v3Duc7s")
+			aggregate := example.New()
+			aggregate.Users[user.Id] = &user
+
+			assert.NoError(t, eventStore.SaveEvents(aggregate, []lavender.Event{&example.Create{User: user}}))
+			assert.NoError(t, eventStore.SaveSnapshot(aggregate, aggregate.TakeSnapshot()))
+
+			events, err := eventStore.LoadEvents(example.New())
+			if assert.NoError(t, err) && assert.Len(t, events, 1) {
+				assert.Equal(t, user.Email, events[0].(*example.Create).Email)
+			}
+			snapshot, err := eventStore.LoadSnapshot(example.New())
+			if assert.NoError(t, err) {
+				assert.Equal(t, user.Email, (*snapshot).(*example.AccountSnapshot).Users[0].Email)
+			}
+
+			assert.NoError(t, test.keyStore.DeleteKey(string(aggregate.Name())))
+
+			_, err = eventStore.LoadEvents(example.New())
+			assert.ErrorIs(t, err, encoders.ErrForgotten)
+			_, err = eventStore.LoadSnapshot(example.New())
+			assert.ErrorIs(t, err, encoders.ErrForgotten)
+
+			// The shredded stream doesn't come back to life.
+			assert.ErrorIs(t, eventStore.SaveEvents(aggregate, []lavender.Event{&example.Create{User: user}}), encoders.ErrForgotten)
+			_, err = test.keyStore.Key(string(aggregate.Name()))
+			assert.ErrorIs(t, err, encoders.ErrForgotten)
+		})
+	}
+}
+
+func TestGormKeyStoreConcurrentKeys(t *testing.T) {
+	db, err := Sqlite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyStore, err := store.NewGormKeyStore(db.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A concurrent writer creates the key of the stream between the lookup and the insert.
+	racing := []byte("racing key of exactly 32 bytes..")
+	inRace := false
+	db.Callback().Create().Before("gorm:create").Register("test:race", func(tx *gorm.DB) {
+		key, ok := tx.Statement.Dest.(*store.EncryptionKey)
+		if !ok || inRace {
+			return
+		}
+		inRace = true
+		tx.Session(&gorm.Session{NewDB: true}).Create(&store.EncryptionKey{Stream: key.Stream, CreatedAt: key.CreatedAt, Key: racing})
+		inRace = false
+	})
+
+	key, err := keyStore.Key("account")
+	if assert.NoError(t, err) {
+		assert.Equal(t, racing, key, "the key stored first is used")
+	}
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/FlauschigDings/lavender"
+)
+
+var (
+	// ErrStreamClosed is returned when events are appended to a closed stream.
+	ErrStreamClosed = errors.New("stream is closed")
+
+	// ErrStreamDeleted is returned when a deleted or tombstoned stream is used.
+	ErrStreamDeleted = errors.New("stream is deleted")
+
+	// ErrInvalidTransition is returned when a stream can't change to the requested state.
+	ErrInvalidTransition = errors.New("invalid stream state transition")
+)
+
+// StreamState is the lifecycle state of a stream.
+type StreamState string
+
+const (
+	// StreamActive streams accept new events. Streams without a stored state are active.
+	StreamActive StreamState = "active"
+	// StreamClosed streams can still be loaded but reject new events.
+	StreamClosed StreamState = "closed"
+	// StreamDeleted streams are soft-deleted. Their events are kept and they can be undeleted.
+	StreamDeleted StreamState = "deleted"
+	// StreamTombstoned streams are hard-deleted. Their events and snapshots are removed for good.
+	StreamTombstoned StreamState = "tombstoned"
+)
+
+// Deleted reports if the stream is soft-deleted or tombstoned.
+func (state StreamState) Deleted() bool {
+	return state == StreamDeleted || state == StreamTombstoned
+}
+
+// appendErr returns the error for appending to a stream in the state, nil if appends are allowed.
+func (state StreamState) appendErr() error {
+	switch {
+	case state.Deleted():
+		return ErrStreamDeleted
+	case state == StreamClosed:
+		return ErrStreamClosed
+	}
+	return nil
+}
+
+// transitions lists the states a stream can change to from each state.
+var transitions = map[StreamState][]StreamState{
+	StreamActive:  {StreamClosed, StreamDeleted, StreamTombstoned},
+	StreamClosed:  {StreamDeleted, StreamTombstoned},
+	StreamDeleted: {StreamActive, StreamTombstoned},
+}
+
+// transition checks if a stream can change from one state to another.
+func transition(from, to StreamState) error {
+	if from == to {
+		return nil
+	}
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+}
+
+// LifecycleStore is implemented by event stores that keep the lifecycle state of their streams.
+// Appends to closed streams fail with ErrStreamClosed, appends to deleted streams with ErrStreamDeleted.
+type LifecycleStore[E lavender.Event, S lavender.Snapshot] interface {
+	// StreamState returns the state of the aggregate's stream.
+	StreamState(aggregate lavender.CustomAggregate[E, S]) (StreamState, error)
+
+	// SetStreamState changes the state of the aggregate's stream. Tombstoning removes all events and
+	// snapshots of the stream. Only soft-deleted streams can become active again.
+	SetStreamState(aggregate lavender.CustomAggregate[E, S], state StreamState) error
+}
+
+// StateOf returns the state of the aggregate's stream if the store supports it, otherwise every stream
+// is active.
+func StateOf[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S]) (StreamState, error) {
+	if lifecycleStore, ok := store.(LifecycleStore[E, S]); ok {
+		state, err := lifecycleStore.StreamState(aggregate)
+		if !errors.Is(err, ErrUnsupported) {
+			return state, err
+		}
+	}
+	return StreamActive, nil
+}
+
+// SetState changes the state of the aggregate's stream if the store supports it.
+func SetState[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S], state StreamState) error {
+	if lifecycleStore, ok := store.(LifecycleStore[E, S]); ok {
+		return lifecycleStore.SetStreamState(aggregate, state)
+	}
+	return ErrUnsupported
+}
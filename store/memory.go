@@ -1,20 +1,90 @@
 package store
 
 import (
+	"fmt"
+	"reflect"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/instrument"
 )
 
 // InMemoryEventStore is a thread-safe, in-memory store for events and snapshots.
 type InMemoryEventStore[E lavender.Event, S lavender.Snapshot] struct {
-	Events    sync.Map // Store events as map[lavender.Name][]E
-	Snapshots sync.Map // Store snapshots as map[lavender.Name]S
+	Events    sync.Map // Store events as map[streamID][]MemoryEvent[E]
+	Snapshots sync.Map // Store snapshots as map[streamID][]MemorySnapshot[S]
+	States    sync.Map // Store stream states as map[streamID]StreamState
+	Metadata  sync.Map // Store stream metadata as map[streamID]StreamMetadata
+	Scavenged sync.Map // Store the head of the last scavenged event as map[streamID]chainPin
+	Chained   sync.Map // Store the head of the hash chain as map[streamID]chainPin
+
+	// Encoder encodes events and snapshots before they are stored. Without an encoder the values are stored as they are.
+	Encoder encoders.Encoder
+
+	// HashChain links every stored event to the previous event of the stream by a hash over its canonical data.
+	// The hash is verified against the decoded event, so the Encoder must keep every field of the events:
+	// fields it drops, like `json:"-"` fields, break the chain.
+	HashChain bool
+
+	// SnapshotLimit is the number of snapshots kept per stream, older ones are dropped. 0 keeps the latest one.
+	SnapshotLimit int
+
+	// Instrumentation receives the spans and metrics of the store.
+	Instrumentation instrument.Instrumentation
+
+	// Clock tells the time recorded for stored events and snapshots.
+	Clock lavender.Clock
+
+	// IDGenerator creates the IDs of stored events and snapshots.
+	IDGenerator lavender.IDGenerator
+
+	mu sync.Mutex // Serializes appends so sequences and hash chain links stay consistent
+}
+
+// MemoryEvent is an event kept by the InMemoryEventStore.
+type MemoryEvent[E lavender.Event] struct {
+	Event       E         // The event or, if it is encoded, a zero value of the event type
+	ID          string    // Unique ID of the event
+	CreatedAt   time.Time // Timestamp when the event was stored
+	Sequence    uint64    // Position inside the stream
+	ContentType string    // Content type of the encoder that produced the payload
+	Payload     []byte    // Serialized event data if an encoder is set
+	Hash        []byte    // Hash chain link of the event
+	PrevHash    []byte    // Hash of the previous event of the stream
+	KeyID       string    // ID of the key that signed the event
+	Signature   []byte    // Signature of the event envelope
+}
+
+// MemorySnapshot is a snapshot kept by the InMemoryEventStore.
+type MemorySnapshot[S lavender.Snapshot] struct {
+	Snapshot    S         // The snapshot or, if it is encoded, a zero value of the snapshot type
+	ID          string    // Unique ID of the snapshot
+	CreatedAt   time.Time // Timestamp when the snapshot was stored
+	Sequence    uint64    // Sequence of the last event covered by the snapshot
+	ChainHead   []byte    // Hash of the last event covered by the snapshot
+	Schema      int       // Schema version of the snapshot
+	ContentType string    // Content type of the encoder that produced the payload
+	Payload     []byte    // Serialized snapshot data if an encoder is set
 }
 
 // Ensure InMemoryEventStore implements both EventStore and SnapshotStore interfaces.
 var _ SnapshotStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
 var _ EventStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ Verifier[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ SignedEventStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ ConcurrentEventStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ ConcurrentSnapshotStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ LifecycleStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ MetadataStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ Scavenger = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ HistoryStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ TailEventStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ RecordStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ SchemaStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
+var _ StreamRecordStore[lavender.Event, lavender.Snapshot] = new(InMemoryEventStore[lavender.Event, lavender.Snapshot])
 
 // NewInMemoryStore initalizes a new event and snapshot store with default types.
 func NewInMemoryStore() *InMemoryEventStore[lavender.Event, lavender.Snapshot] {
@@ -23,50 +93,685 @@ func NewInMemoryStore() *InMemoryEventStore[lavender.Event, lavender.Snapshot] {
 
 // NewInMemoryCustomStore initializes a new custom generic event and snapshot store.
 func NewInMemoryCustomStore[E lavender.Event, S lavender.Snapshot]() *InMemoryEventStore[E, S] {
-	return &InMemoryEventStore[E, S]{}
+	return &InMemoryEventStore[E, S]{
+		Instrumentation: instrument.Noop{},
+		Clock:           lavender.SystemClock{},
+		IDGenerator:     lavender.UUIDv7Generator{},
+	}
+}
+
+// NewInMemoryEncodedStore initializes a new custom generic event and snapshot store that keeps
+// events and snapshots encoded with the given encoder.
+func NewInMemoryEncodedStore[E lavender.Event, S lavender.Snapshot](encoder encoders.Encoder) *InMemoryEventStore[E, S] {
+	return &InMemoryEventStore[E, S]{
+		Encoder:         encoder,
+		Instrumentation: instrument.Noop{},
+		Clock:           lavender.SystemClock{},
+		IDGenerator:     lavender.UUIDv7Generator{},
+	}
+}
+
+// instrumentation returns the instrumentation of the store, which defaults to instrument.Noop.
+func (store *InMemoryEventStore[E, S]) instrumentation() instrument.Instrumentation {
+	return instrument.Or(store.Instrumentation)
+}
+
+// UseHashChain enables the tamper-evident hash chain for all events stored from now on.
+func (store *InMemoryEventStore[E, S]) UseHashChain() *InMemoryEventStore[E, S] {
+	store.HashChain = true
+	return store
+}
+
+// UseSnapshotLimit sets the number of snapshots kept per stream.
+func (store *InMemoryEventStore[E, S]) UseSnapshotLimit(limit int) *InMemoryEventStore[E, S] {
+	store.SnapshotLimit = limit
+	return store
+}
+
+// UseClock sets the clock that tells the time recorded for stored events and snapshots.
+func (store *InMemoryEventStore[E, S]) UseClock(clock lavender.Clock) *InMemoryEventStore[E, S] {
+	store.Clock = clock
+	return store
+}
+
+// UseIDGenerator sets the generator of the IDs of stored events and snapshots.
+func (store *InMemoryEventStore[E, S]) UseIDGenerator(generator lavender.IDGenerator) *InMemoryEventStore[E, S] {
+	store.IDGenerator = generator
+	return store
 }
 
 // SaveEvents appends new events to the aggreagate's event store.
 func (store *InMemoryEventStore[E, S]) SaveEvents(aggregate lavender.CustomAggregate[E, S], events []E) error {
-	existing, _ := store.Events.Load(aggregate.Name())
+	return store.SaveSignedEvents(aggregate, events, nil)
+}
+
+// SaveSignedEvents appends new events and their signatures to the aggreagate's event store.
+func (store *InMemoryEventStore[E, S]) SaveSignedEvents(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature) error {
+	return store.saveEvents(aggregate, events, signatures, nil)
+}
+
+// SaveEventsAt implements ConcurrentEventStore.
+func (store *InMemoryEventStore[E, S]) SaveEventsAt(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature, expected uint64) error {
+	return store.saveEvents(aggregate, events, signatures, &expected)
+}
+
+// Sequence implements ConcurrentEventStore.
+func (store *InMemoryEventStore[E, S]) Sequence(aggregate lavender.CustomAggregate[E, S]) (uint64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	id := streamOf(aggregate)
+	return store.head(id, store.loadEvents(id)).Sequence, nil
+}
+
+// saveEvents appends the events and their signatures. If expected is set the events are only appended if
+// the stream head is still at the expected sequence.
+func (store *InMemoryEventStore[E, S]) saveEvents(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature, expected *uint64) (err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanSaveEvents, aggregate.Name())(&err)
+	if signatures != nil && len(signatures) != len(events) {
+		return fmt.Errorf("got %d signatures for %d events", len(signatures), len(events))
+	}
 
-	var eventList []E
-	if existing != nil {
-		eventList = existing.([]E) // Type assertion
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	id := streamOf(aggregate)
+	eventList := store.loadEvents(id)
+	if err := store.state(id).appendErr(); err != nil {
+		return err
+	}
+	head := store.head(id, eventList)
+	if expected != nil && head.Sequence != *expected {
+		return ErrConflict
+	}
+	for i, event := range events {
+		head.Sequence++
+		item := MemoryEvent[E]{
+			Event:     event,
+			ID:        lavender.IDGeneratorOr(store.IDGenerator).NewID(),
+			CreatedAt: lavender.ClockOr(store.Clock).Now(),
+			Sequence:  head.Sequence,
+		}
+		if signatures != nil {
+			if signatures[i].Sequence != 0 && signatures[i].Sequence != item.Sequence {
+				return ErrConflict
+			}
+			if signatures[i].EventID != "" {
+				item.ID = signatures[i].EventID
+			}
+			item.KeyID = signatures[i].KeyID
+			item.Signature = signatures[i].Signature
+		}
+		if store.Encoder != nil {
+			payload, err := encode(store.instrumentation(), store.Encoder, id.Stream(), event)
+			if err != nil {
+				return err
+			}
+			item.Event = lavender.NewOf(event)
+			item.ContentType = encoders.ContentTypeOf(store.Encoder)
+			item.Payload = payload
+		}
+		if store.HashChain {
+			hash, err := ChainHash(head.ChainHead, event.Name(), event)
+			if err != nil {
+				return err
+			}
+			item.PrevHash = head.ChainHead
+			item.Hash = hash
+		}
+		head.ChainHead = item.Hash
+		eventList = append(eventList, item)
 	}
-	eventList = append(eventList, events...)
 
-	store.Events.Store(aggregate.Name(), eventList)
+	store.Events.Store(id, eventList)
+	if head.ChainHead != nil {
+		store.Chained.Store(id, head)
+	}
 	return nil
 }
 
 // LoadEvents retrieves all stored events from a aggregate.
 func (store *InMemoryEventStore[E, S]) LoadEvents(aggregate lavender.CustomAggregate[E, S]) ([]E, error) {
-	existing, ok := store.Events.Load(aggregate.Name())
-	if !ok {
-		return nil, nil
+	events, _, err := store.LoadSignedEvents(aggregate)
+	return events, err
+}
+
+// LoadSignedEvents retrieves all stored events from a aggregate and their signatures. Events hidden by the
+// stream metadata are skipped.
+func (store *InMemoryEventStore[E, S]) LoadSignedEvents(aggregate lavender.CustomAggregate[E, S]) ([]E, []Signature, error) {
+	return store.LoadEventsAfter(aggregate, 0)
+}
+
+// LoadEventsAfter implements TailEventStore. Events hidden by the stream metadata are skipped.
+func (store *InMemoryEventStore[E, S]) LoadEventsAfter(aggregate lavender.CustomAggregate[E, S], sequence uint64) (_ []E, _ []Signature, err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanLoadEvents, aggregate.Name())(&err)
+	id := streamOf(aggregate)
+	eventList := store.loadEvents(id)
+	eventList = eventList[store.metadata(id).firstVisible(len(eventList), eventAt(eventList), lavender.ClockOr(store.Clock).Now()):]
+	events := make([]E, 0, len(eventList))
+	signatures := make([]Signature, 0, len(eventList))
+	for _, item := range eventList {
+		if item.Sequence <= sequence {
+			continue
+		}
+		event, err := store.decodeEvent(aggregate, item)
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, event)
+		signatures = append(signatures, Signature{KeyID: item.KeyID, Signature: item.Signature, EventID: item.ID, Sequence: item.Sequence})
+	}
+	return events, signatures, nil
+}
+
+// LoadHistory implements HistoryStore.
+func (store *InMemoryEventStore[E, S]) LoadHistory(aggregate lavender.CustomAggregate[E, S], until time.Time) (_ []E, err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanLoadEvents, aggregate.Name())(&err)
+	var events []E
+	for _, item := range store.loadEvents(streamOf(aggregate)) {
+		if !storedBy(item.CreatedAt, until) {
+			break
+		}
+		event, err := store.decodeEvent(aggregate, item)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Streams implements RecordStore. Streams with a state or metadata are included even if they have no events.
+func (store *InMemoryEventStore[E, S]) Streams() ([]StreamRef, error) {
+	var streams []StreamRef
+	collect := func(key, value any) bool {
+		if reflect.ValueOf(value).Len() > 0 {
+			streams = append(streams, StreamRef(key.(streamID)))
+		}
+		return true
+	}
+	store.Events.Range(collect)
+	store.Snapshots.Range(collect)
+	// Streams without events, like tombstoned ones, still have a state
+	for _, records := range []*sync.Map{&store.States, &store.Metadata, &store.Scavenged} {
+		records.Range(func(key, _ any) bool {
+			streams = append(streams, StreamRef(key.(streamID)))
+			return true
+		})
+	}
+	return sortStreams(streams), nil
+}
+
+// LoadRecords implements RecordStore. The in-memory store doesn't keep versions, all records get the
+// version of the aggregate.
+func (store *InMemoryEventStore[E, S]) LoadRecords(aggregate lavender.CustomAggregate[E, S]) ([]EventRecord[E], []SnapshotRecord[S], error) {
+	id := streamOf(aggregate)
+	var events []EventRecord[E]
+	for _, item := range store.loadEvents(id) {
+		event, err := store.decodeEvent(aggregate, item)
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, EventRecord[E]{
+			Event:     event,
+			ID:        item.ID,
+			Sequence:  item.Sequence,
+			CreatedAt: item.CreatedAt,
+			Version:   aggregate.Version(),
+			Hash:      item.Hash,
+			PrevHash:  item.PrevHash,
+			Signature: Signature{KeyID: item.KeyID, Signature: item.Signature},
+		})
+	}
+	var snapshots []SnapshotRecord[S]
+	for _, item := range store.loadSnapshots(id) {
+		snapshot, err := store.decodeSnapshot(id, item)
+		if err != nil {
+			return nil, nil, err
+		}
+		snapshots = append(snapshots, SnapshotRecord[S]{
+			Snapshot:  snapshot,
+			ID:        item.ID,
+			CreatedAt: item.CreatedAt,
+			Version:   aggregate.Version(),
+			Sequence:  item.Sequence,
+			ChainHead: item.ChainHead,
+			Schema:    item.Schema,
+		})
+	}
+	return events, snapshots, nil
+}
+
+// ImportRecords implements RecordStore.
+func (store *InMemoryEventStore[E, S]) ImportRecords(aggregate lavender.CustomAggregate[E, S], events []EventRecord[E], snapshots []SnapshotRecord[S]) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	id := streamOf(aggregate)
+	if len(store.loadEvents(id)) > 0 || len(store.loadSnapshots(id)) > 0 {
+		return ErrStreamNotEmpty
+	}
+	if store.state(id).Deleted() {
+		return ErrStreamDeleted
+	}
+	eventList := make([]MemoryEvent[E], 0, len(events))
+	for _, record := range events {
+		item := MemoryEvent[E]{
+			Event:     record.Event,
+			ID:        record.ID,
+			CreatedAt: record.CreatedAt,
+			Sequence:  record.Sequence,
+			Hash:      record.Hash,
+			PrevHash:  record.PrevHash,
+			KeyID:     record.Signature.KeyID,
+			Signature: record.Signature.Signature,
+		}
+		if store.Encoder != nil {
+			payload, err := encode(store.instrumentation(), store.Encoder, id.Stream(), record.Event)
+			if err != nil {
+				return err
+			}
+			item.Event = lavender.NewOf(record.Event)
+			item.ContentType = encoders.ContentTypeOf(store.Encoder)
+			item.Payload = payload
+		}
+		eventList = append(eventList, item)
+		if record.Hash != nil {
+			store.Chained.Store(id, chainPin{Sequence: record.Sequence, ChainHead: record.Hash})
+		}
 	}
-	return existing.([]E), nil
+	snapshotList := make([]MemorySnapshot[S], 0, len(snapshots))
+	for _, record := range snapshots {
+		item := MemorySnapshot[S]{
+			Snapshot:  record.Snapshot,
+			ID:        record.ID,
+			CreatedAt: record.CreatedAt,
+			Sequence:  record.Sequence,
+			ChainHead: record.ChainHead,
+			Schema:    record.Schema,
+		}
+		if store.Encoder != nil {
+			payload, err := encode(store.instrumentation(), store.Encoder, id.Stream(), record.Snapshot)
+			if err != nil {
+				return err
+			}
+			item.Snapshot = lavender.NewOf(record.Snapshot)
+			item.ContentType = encoders.ContentTypeOf(store.Encoder)
+			item.Payload = payload
+		}
+		snapshotList = append(snapshotList, item)
+	}
+	store.Events.Store(id, eventList)
+	store.Snapshots.Store(id, store.retainSnapshots(snapshotList))
+	return nil
+}
+
+// decodeEvent returns the stored event, decoding it if it has been encoded.
+func (store *InMemoryEventStore[E, S]) decodeEvent(aggregate lavender.CustomAggregate[E, S], item MemoryEvent[E]) (E, error) {
+	if item.Payload == nil {
+		return item.Event, nil
+	}
+	event := lavender.NewOf(item.Event)
+	if err := decode(store.instrumentation(), store.Encoder, streamOf(aggregate).Stream(), item.ContentType, item.Payload, event); err != nil {
+		return event, fmt.Errorf("decode event %s: %w", item.Event.Name(), err)
+	}
+	return event, nil
 }
 
 // SaveSnapshot stores a snapshot of the aggregate.
 func (store *InMemoryEventStore[E, S]) SaveSnapshot(aggregate lavender.CustomAggregate[E, S], snapshot S) error {
-	store.Snapshots.Store(aggregate.Name(), snapshot)
+	return store.saveSnapshot(aggregate, snapshot, nil)
+}
+
+// SaveSnapshotAt implements ConcurrentSnapshotStore.
+func (store *InMemoryEventStore[E, S]) SaveSnapshotAt(aggregate lavender.CustomAggregate[E, S], snapshot S, expected uint64) error {
+	return store.saveSnapshot(aggregate, snapshot, &expected)
+}
+
+// saveSnapshot stores a snapshot of the aggregate. If expected is set the snapshot is only stored if the
+// stream head is still at the expected sequence.
+func (store *InMemoryEventStore[E, S]) saveSnapshot(aggregate lavender.CustomAggregate[E, S], snapshot S, expected *uint64) (err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanSaveSnapshot, aggregate.Name())(&err)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	id := streamOf(aggregate)
+	if store.state(id).Deleted() {
+		return ErrStreamDeleted
+	}
+
+	// Pin the chain head the snapshot has been taken at.
+	head := store.head(id, store.loadEvents(id))
+	if expected != nil && head.Sequence != *expected {
+		return ErrConflict
+	}
+	item := MemorySnapshot[S]{
+		Snapshot:  snapshot,
+		ID:        lavender.IDGeneratorOr(store.IDGenerator).NewID(),
+		CreatedAt: lavender.ClockOr(store.Clock).Now(),
+		Sequence:  head.Sequence,
+		ChainHead: head.ChainHead,
+		Schema:    lavender.SchemaVersionOf(snapshot),
+	}
+	if store.Encoder != nil {
+		payload, err := encode(store.instrumentation(), store.Encoder, id.Stream(), snapshot)
+		if err != nil {
+			return err
+		}
+		item.Snapshot = lavender.NewOf(snapshot)
+		item.ContentType = encoders.ContentTypeOf(store.Encoder)
+		item.Payload = payload
+	}
+	store.Snapshots.Store(id, store.retainSnapshots(append(store.loadSnapshots(id), item)))
 	return nil
 }
 
 // LoadSnapshot retrieves the last snapshot of the aggregate.
-func (store *InMemoryEventStore[E, S]) LoadSnapshot(aggregate lavender.CustomAggregate[E, S]) (*S, error) {
-	existing, ok := store.Snapshots.Load(aggregate.Name())
-	if !ok {
+func (store *InMemoryEventStore[E, S]) LoadSnapshot(aggregate lavender.CustomAggregate[E, S]) (_ *S, err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanLoadSnapshot, aggregate.Name())(&err)
+	id := streamOf(aggregate)
+	snapshots := store.loadSnapshots(id)
+	if len(snapshots) == 0 {
 		return nil, nil
 	}
-	snapshot := existing.(S) // Type assertion
+	snapshot, err := store.decodeSnapshot(id, snapshots[len(snapshots)-1])
+	if err != nil {
+		return nil, err
+	}
 	return &snapshot, nil
 }
 
+// decodeSnapshot returns the stored snapshot, decoding it if it has been encoded.
+func (store *InMemoryEventStore[E, S]) decodeSnapshot(id streamID, item MemorySnapshot[S]) (S, error) {
+	if item.Payload == nil {
+		return item.Snapshot, nil
+	}
+	snapshot := lavender.NewOf(item.Snapshot)
+	if err := decode(store.instrumentation(), store.Encoder, id.Stream(), item.ContentType, item.Payload, snapshot); err != nil {
+		return snapshot, fmt.Errorf("decode snapshot %s: %w", id.Name, err)
+	}
+	return snapshot, nil
+}
+
+// LoadStoredSnapshot implements SchemaStore.
+func (store *InMemoryEventStore[E, S]) LoadStoredSnapshot(aggregate lavender.CustomAggregate[E, S]) (_ *S, _ *RawSnapshot, err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanLoadSnapshot, aggregate.Name())(&err)
+	id := streamOf(aggregate)
+	snapshots := store.loadSnapshots(id)
+	if len(snapshots) == 0 {
+		return nil, nil, nil
+	}
+	item := snapshots[len(snapshots)-1]
+	raw := RawSnapshot{
+		ID:          item.ID,
+		Tenant:      id.Tenant,
+		Name:        id.Name,
+		CreatedAt:   item.CreatedAt,
+		Version:     aggregate.Version(),
+		Sequence:    item.Sequence,
+		ChainHead:   item.ChainHead,
+		Schema:      item.Schema,
+		ContentType: item.ContentType,
+		Data:        item.Payload,
+	}
+	snapshot, err := store.decodeSnapshot(id, item)
+	if err != nil {
+		return nil, &raw, fmt.Errorf("%w: %w", ErrSnapshotUndecodable, err)
+	}
+	return &snapshot, &raw, nil
+}
+
+// ReplaceSnapshot implements SchemaStore.
+func (store *InMemoryEventStore[E, S]) ReplaceSnapshot(aggregate lavender.CustomAggregate[E, S], snapshotID string, snapshot S) (err error) {
+	defer startSpan(store.instrumentation(), instrument.SpanSaveSnapshot, aggregate.Name())(&err)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	id := streamOf(aggregate)
+	if store.state(id).Deleted() {
+		return ErrStreamDeleted
+	}
+	snapshots := slices.Clone(store.loadSnapshots(id))
+	index := slices.IndexFunc(snapshots, func(item MemorySnapshot[S]) bool { return item.ID == snapshotID })
+	if index < 0 {
+		return ErrSnapshotNotFound
+	}
+	item := &snapshots[index]
+	item.Snapshot = snapshot
+	item.Schema = lavender.SchemaVersionOf(snapshot)
+	item.ContentType, item.Payload = "", nil
+	if store.Encoder != nil {
+		payload, err := encode(store.instrumentation(), store.Encoder, id.Stream(), snapshot)
+		if err != nil {
+			return err
+		}
+		item.Snapshot = lavender.NewOf(snapshot)
+		item.ContentType = encoders.ContentTypeOf(store.Encoder)
+		item.Payload = payload
+	}
+	store.Snapshots.Store(id, snapshots)
+	return nil
+}
+
 // ClearEvents removes all stored events from a aggregate.
 func (store *InMemoryEventStore[E, S]) ClearEvents(aggregate lavender.CustomAggregate[E, S]) error {
-	store.Events.Store(aggregate.Name(), []E{})
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.Events.Store(streamOf(aggregate), []MemoryEvent[E]{})
+	return nil
+}
+
+// ClearEventsUntil implements ConcurrentEventStore.
+func (store *InMemoryEventStore[E, S]) ClearEventsUntil(aggregate lavender.CustomAggregate[E, S], sequence uint64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	id := streamOf(aggregate)
+	eventList := store.loadEvents(id)
+	kept := make([]MemoryEvent[E], 0, len(eventList))
+	for _, item := range eventList {
+		if item.Sequence > sequence {
+			kept = append(kept, item)
+		}
+	}
+	store.Events.Store(id, kept)
+	return nil
+}
+
+// Verify implements Verifier. It walks the hash chain of all events stored for the aggregate's stream.
+func (store *InMemoryEventStore[E, S]) Verify(aggregate lavender.CustomAggregate[E, S]) error {
+	id := streamOf(aggregate)
+	eventList := store.loadEvents(id)
+	links := make([]chainLink, 0, len(eventList))
+	for _, item := range eventList {
+		event, err := store.decodeEvent(aggregate, item)
+		if err != nil {
+			return err
+		}
+		links = append(links, chainLink{Sequence: item.Sequence, Topic: item.Event.Name(), Hash: item.Hash, PrevHash: item.PrevHash, Event: event})
+	}
+
+	var pins []chainPin
+	if scavenged, ok := store.Scavenged.Load(id); ok {
+		pins = append(pins, scavenged.(chainPin))
+	}
+	for _, snapshot := range store.loadSnapshots(id) {
+		if snapshot.ChainHead != nil {
+			pins = append(pins, chainPin{Sequence: snapshot.Sequence, ChainHead: snapshot.ChainHead})
+		}
+	}
+	var head *chainPin
+	if chained, ok := store.Chained.Load(id); ok {
+		pin := chained.(chainPin)
+		head = &pin
+	}
+	return verifyChain(aggregate.Name(), links, pins, head)
+}
+
+// StreamState implements LifecycleStore.
+func (store *InMemoryEventStore[E, S]) StreamState(aggregate lavender.CustomAggregate[E, S]) (StreamState, error) {
+	return store.state(streamOf(aggregate)), nil
+}
+
+// SetStreamState implements LifecycleStore.
+func (store *InMemoryEventStore[E, S]) SetStreamState(aggregate lavender.CustomAggregate[E, S], state StreamState) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	id := streamOf(aggregate)
+	if err := transition(store.state(id), state); err != nil {
+		return err
+	}
+	if state == StreamTombstoned {
+		store.Events.Delete(id)
+		store.Snapshots.Delete(id)
+		store.Metadata.Delete(id)
+		store.Scavenged.Delete(id)
+		store.Chained.Delete(id)
+	}
+	store.States.Store(id, state)
+	return nil
+}
+
+// StreamMetadata implements MetadataStore.
+func (store *InMemoryEventStore[E, S]) StreamMetadata(aggregate lavender.CustomAggregate[E, S]) (StreamMetadata, error) {
+	return store.metadata(streamOf(aggregate)), nil
+}
+
+// SetStreamMetadata implements MetadataStore.
+func (store *InMemoryEventStore[E, S]) SetStreamMetadata(aggregate lavender.CustomAggregate[E, S], metadata StreamMetadata) error {
+	id := streamOf(aggregate)
+	if store.state(id) == StreamTombstoned {
+		return ErrStreamDeleted
+	}
+	store.Metadata.Store(id, metadata)
+	return nil
+}
+
+// LoadStreamRecord implements StreamRecordStore.
+func (store *InMemoryEventStore[E, S]) LoadStreamRecord(aggregate lavender.CustomAggregate[E, S]) (StreamRecord, error) {
+	id := streamOf(aggregate)
+	record := StreamRecord{State: store.state(id), Metadata: store.metadata(id)}
+	if scavenged, ok := store.Scavenged.Load(id); ok {
+		record.ScavengedSequence = scavenged.(chainPin).Sequence
+		record.ScavengedHash = scavenged.(chainPin).ChainHead
+	}
+	return record, nil
+}
+
+// ImportStreamRecord implements StreamRecordStore.
+func (store *InMemoryEventStore[E, S]) ImportStreamRecord(aggregate lavender.CustomAggregate[E, S], record StreamRecord) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	id := streamOf(aggregate)
+	if record.State == "" {
+		record.State = StreamActive
+	}
+	store.States.Store(id, record.State)
+	if record.Metadata.IsZero() {
+		store.Metadata.Delete(id)
+	} else {
+		store.Metadata.Store(id, record.Metadata)
+	}
+	if record.ScavengedHash == nil && record.ScavengedSequence == 0 {
+		store.Scavenged.Delete(id)
+	} else {
+		store.Scavenged.Store(id, chainPin{Sequence: record.ScavengedSequence, ChainHead: record.ScavengedHash})
+	}
 	return nil
 }
+
+// Scavenge implements Scavenger.
+func (store *InMemoryEventStore[E, S]) Scavenge() (removed int, err error) {
+	span := store.instrumentation().StartSpan(instrument.SpanScavenge)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := lavender.ClockOr(store.Clock).Now()
+	store.Metadata.Range(func(key, value any) bool {
+		id := key.(streamID)
+		eventList := store.loadEvents(id)
+		count := value.(StreamMetadata).scavengeable(len(eventList), eventAt(eventList), now)
+		if count == 0 {
+			return true
+		}
+		// Pin the last removed event, so the hash chain of the remaining events can still be verified.
+		last := eventList[count-1]
+		store.Scavenged.Store(id, chainPin{Sequence: last.Sequence, ChainHead: last.Hash})
+		store.Events.Store(id, slices.Clone(eventList[count:]))
+		store.instrumentation().Count(instrument.MetricEventsScavenged, int64(count), instrument.Attr("aggregate", id.Name))
+		removed += count
+		return true
+	})
+	return removed, nil
+}
+
+// metadata returns the metadata of a stream.
+func (store *InMemoryEventStore[E, S]) metadata(id streamID) StreamMetadata {
+	metadata, ok := store.Metadata.Load(id)
+	if !ok {
+		return StreamMetadata{}
+	}
+	return metadata.(StreamMetadata)
+}
+
+// eventAt returns the sequence and creation time of the events for StreamMetadata.firstVisible.
+func eventAt[E lavender.Event](eventList []MemoryEvent[E]) func(i int) (uint64, time.Time) {
+	return func(i int) (uint64, time.Time) {
+		return eventList[i].Sequence, eventList[i].CreatedAt
+	}
+}
+
+// state returns the state of a stream.
+func (store *InMemoryEventStore[E, S]) state(id streamID) StreamState {
+	state, ok := store.States.Load(id)
+	if !ok {
+		return StreamActive
+	}
+	return state.(StreamState)
+}
+
+// loadEvents returns the stored events of a stream.
+func (store *InMemoryEventStore[E, S]) loadEvents(id streamID) []MemoryEvent[E] {
+	existing, ok := store.Events.Load(id)
+	if !ok {
+		return nil
+	}
+	return existing.([]MemoryEvent[E]) // Type assertion
+}
+
+// retainSnapshots drops the oldest snapshots beyond the SnapshotLimit.
+func (store *InMemoryEventStore[E, S]) retainSnapshots(snapshots []MemorySnapshot[S]) []MemorySnapshot[S] {
+	if drop := len(snapshots) - max(store.SnapshotLimit, 1); drop > 0 {
+		return slices.Clone(snapshots[drop:])
+	}
+	return snapshots
+}
+
+// loadSnapshots returns the stored snapshots of a stream, the latest one last.
+func (store *InMemoryEventStore[E, S]) loadSnapshots(id streamID) []MemorySnapshot[S] {
+	existing, ok := store.Snapshots.Load(id)
+	if !ok {
+		return nil
+	}
+	return existing.([]MemorySnapshot[S]) // Type assertion
+}
+
+// head returns the sequence and hash of the last event of the stream. If the stream has no events
+// (e.g. they have been cleared after a snapshot) the head pinned by the latest snapshot is returned.
+func (store *InMemoryEventStore[E, S]) head(id streamID, eventList []MemoryEvent[E]) chainPin {
+	if len(eventList) > 0 {
+		last := eventList[len(eventList)-1]
+		return chainPin{Sequence: last.Sequence, ChainHead: last.Hash}
+	}
+	if snapshots := store.loadSnapshots(id); len(snapshots) > 0 {
+		last := snapshots[len(snapshots)-1]
+		return chainPin{Sequence: last.Sequence, ChainHead: last.ChainHead}
+	}
+	return chainPin{}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/FlauschigDings/lavender"
 	"github.com/FlauschigDings/lavender/example"
 	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestSaveEvent(t *testing.T) {
@@ -102,6 +103,17 @@ func TestSaveSnapshot(t *testing.T) {
 	})
 
 	t.Run("multi", func(t *testing.T) {
+		for limit, kept := range map[int]int{0: 1, 2: 2} {
+			memStore := store.NewInMemoryStore().UseSnapshotLimit(limit)
+			for range 3 {
+				if err := memStore.SaveSnapshot(example.New(), example.New().TakeSnapshot()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, snapshots, err := memStore.LoadRecords(example.New())
+			assert.NoError(t, err)
+			assert.Len(t, snapshots, kept)
+		}
 	})
 
 	t.Run("multi-thread", func(t *testing.T) {
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+)
+
+// StreamMetadata limits the events of a stream that are visible to LoadEvents. Hidden events are removed
+// for good by a Scavenger. Zero values don't limit the stream.
+type StreamMetadata struct {
+	MaxCount       uint64        // Number of latest events that are kept
+	MaxAge         time.Duration // Age after which events are hidden
+	TruncateBefore uint64        // Sequence of the first event that is kept
+}
+
+// IsZero reports if the metadata doesn't limit the stream.
+func (metadata StreamMetadata) IsZero() bool {
+	return metadata == StreamMetadata{}
+}
+
+// firstVisible returns the index of the first of n events, in sequence order, that is visible at the given
+// time. at returns the sequence and creation time of the ith event. Events before the first visible one
+// are hidden.
+func (metadata StreamMetadata) firstVisible(n int, at func(i int) (uint64, time.Time), now time.Time) int {
+	if n == 0 || metadata.IsZero() {
+		return 0
+	}
+	head, _ := at(n - 1)
+	for i := 0; i < n; i++ {
+		sequence, createdAt := at(i)
+		switch {
+		case sequence < metadata.TruncateBefore:
+		case metadata.MaxCount > 0 && head-sequence >= metadata.MaxCount:
+		case metadata.MaxAge > 0 && now.Sub(createdAt) > metadata.MaxAge:
+		default:
+			return i
+		}
+	}
+	return n
+}
+
+// scavengeable returns the number of leading events that a scavenger removes. The last event of a stream is
+// always kept, so new events continue its sequence.
+func (metadata StreamMetadata) scavengeable(n int, at func(i int) (uint64, time.Time), now time.Time) int {
+	return min(metadata.firstVisible(n, at, now), max(n-1, 0))
+}
+
+// MetadataStore is implemented by event stores that keep metadata per stream.
+type MetadataStore[E lavender.Event, S lavender.Snapshot] interface {
+	// StreamMetadata returns the metadata of the aggregate's stream.
+	StreamMetadata(aggregate lavender.CustomAggregate[E, S]) (StreamMetadata, error)
+
+	// SetStreamMetadata replaces the metadata of the aggregate's stream. It takes effect on the next load.
+	SetStreamMetadata(aggregate lavender.CustomAggregate[E, S], metadata StreamMetadata) error
+}
+
+// Scavenger is implemented by stores that can remove the events hidden by the stream metadata.
+type Scavenger interface {
+	// Scavenge removes the hidden events of all streams and returns how many have been removed.
+	Scavenge() (int, error)
+}
+
+// Scavenge removes the hidden events of all streams if the store supports it, otherwise it returns
+// ErrUnsupported.
+func Scavenge[E lavender.Event, S lavender.Snapshot](store EventStore[E, S]) (int, error) {
+	if scavenger, ok := store.(Scavenger); ok {
+		return scavenger.Scavenge()
+	}
+	return 0, ErrUnsupported
+}
+
+// MetadataOf returns the metadata of the aggregate's stream if the store supports it, otherwise streams are
+// not limited.
+func MetadataOf[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S]) (StreamMetadata, error) {
+	if metadataStore, ok := store.(MetadataStore[E, S]); ok {
+		return metadataStore.StreamMetadata(aggregate)
+	}
+	return StreamMetadata{}, nil
+}
+
+// SetMetadata replaces the metadata of the aggregate's stream if the store supports it.
+func SetMetadata[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S], metadata StreamMetadata) error {
+	if metadataStore, ok := store.(MetadataStore[E, S]); ok {
+		return metadataStore.SetStreamMetadata(aggregate, metadata)
+	}
+	return ErrUnsupported
+}
+
+// RunScavenger scavenges the store every interval until the context is done. Errors are passed to onError,
+// if set, and don't stop the scavenger.
+func RunScavenger(ctx context.Context, scavenger Scavenger, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := scavenger.Scavenge(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
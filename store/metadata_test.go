@@ -0,0 +1,75 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/lavendertest"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxAge(t *testing.T) {
+	clock := lavendertest.NewFakeClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	db, err := Sqlite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]store.Store[lavender.Event, lavender.Snapshot]{
+		"memory": store.NewInMemoryStore().UseHashChain().UseClock(clock),
+		"gorm":   store.NewGormStore(db.DB).UseHashChain().UseClock(clock).RegisterAggregates(example.New()),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			clock.Set(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+			for _, email := range []string{"a@t.de", "b@t.de", "c@t.de"} {
+				if err := s.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *example.NewUser(email, "secret")}}); err != nil {
+					t.Fatal(err)
+				}
+				clock.Advance(time.Hour)
+			}
+			if err := store.SetMetadata(s, example.New(), store.StreamMetadata{MaxAge: 90 * time.Minute}); err != nil {
+				t.Fatal(err)
+			}
+
+			events, err := s.LoadEvents(example.New())
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{"c@t.de"}, emails(events))
+			}
+
+			// The last event is kept, even if it is too old, so the stream keeps its sequence.
+			clock.Advance(time.Hour)
+			removed, err := s.(store.Scavenger).Scavenge()
+			if assert.NoError(t, err) {
+				assert.Equal(t, 2, removed)
+			}
+			events, err = s.LoadEvents(example.New())
+			if assert.NoError(t, err) {
+				assert.Empty(t, events)
+			}
+
+			// The chain of the remaining events starts at the scavenged head.
+			if err := s.SaveEvents(example.New(), []lavender.Event{&example.Create{User: *example.NewUser("d@t.de", "secret")}}); err != nil {
+				t.Fatal(err)
+			}
+			assert.NoError(t, store.VerifyStream(s, example.New()))
+			head, err := store.StreamSequence(s, example.New())
+			if assert.NoError(t, err) {
+				assert.EqualValues(t, 4, head)
+			}
+		})
+	}
+}
+
+// emails returns the emails of the created users.
+func emails(events []lavender.Event) []string {
+	result := make([]string, 0, len(events))
+	for _, event := range events {
+		if create, ok := event.(*example.Create); ok {
+			result = append(result, create.Email)
+		}
+	}
+	return result
+}
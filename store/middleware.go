@@ -0,0 +1,482 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+)
+
+// ErrUnsupported is returned when a wrapped store doesn't support an optional operation.
+var ErrUnsupported = errors.New("operation is not supported by the store")
+
+// Store combines an EventStore and a SnapshotStore.
+type Store[E lavender.Event, S lavender.Snapshot] interface {
+	EventStore[E, S]
+	SnapshotStore[E, S]
+}
+
+// Middleware wraps a store to add cross-cutting behavior like logging or retries.
+type Middleware[E lavender.Event, S lavender.Snapshot] func(next Store[E, S]) Store[E, S]
+
+// Chain wraps the store with the middlewares. The first middleware is the outermost one.
+func Chain[E lavender.Event, S lavender.Snapshot](store Store[E, S], middlewares ...Middleware[E, S]) Store[E, S] {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		store = middlewares[i](store)
+	}
+	return store
+}
+
+// Wrap combines a separate event and snapshot store into a Store.
+func Wrap[E lavender.Event, S lavender.Snapshot](eventStore EventStore[E, S], snapshotStore SnapshotStore[E, S]) Store[E, S] {
+	return &pair[E, S]{eventStore, snapshotStore}
+}
+
+// pair is a Store made of an EventStore and a SnapshotStore.
+type pair[E lavender.Event, S lavender.Snapshot] struct {
+	EventStore[E, S]
+	SnapshotStore[E, S]
+}
+
+// Operation names a store operation passed through a middleware.
+type Operation string
+
+const (
+	OpSaveEvents   Operation = "SaveEvents"
+	OpSaveEventsAt Operation = "SaveEventsAt"
+	OpLoadEvents   Operation = "LoadEvents"
+	OpClearEvents  Operation = "ClearEvents"
+	OpSaveSnapshot Operation = "SaveSnapshot"
+	OpLoadSnapshot Operation = "LoadSnapshot"
+	OpVerify       Operation = "Verify"
+	OpSetState     Operation = "SetStreamState"
+	OpSetMetadata  Operation = "SetStreamMetadata"
+	OpImport       Operation = "ImportRecords"
+	OpScavenge     Operation = "Scavenge"
+)
+
+// Writes reports if the operation modifies the store.
+func (op Operation) Writes() bool {
+	return op == OpSaveEvents || op == OpSaveEventsAt || op == OpClearEvents || op == OpSaveSnapshot || op == OpSetState || op == OpSetMetadata || op == OpImport || op == OpScavenge
+}
+
+// StoreWrapper is a Store that delegates to the next store unless a hook overrides the operation.
+// Signed events, conflict-checked appends and snapshots, partial clears, tail reads, stream states and metadata, scavenging, history
+// reads, record and stream record exports and imports, stored snapshot schemas and hash chain verification
+// are forwarded if the next store supports them.
+type StoreWrapper[E lavender.Event, S lavender.Snapshot] struct {
+	Next             Store[E, S]
+	HookSaveEvents   func(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature) error
+	HookSaveEventsAt func(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature, expected uint64) error
+	HookLoadEvents   func(aggregate lavender.CustomAggregate[E, S]) ([]E, []Signature, error)
+	HookLoadAfter    func(aggregate lavender.CustomAggregate[E, S], sequence uint64) ([]E, []Signature, error)
+	HookClearEvents  func(aggregate lavender.CustomAggregate[E, S]) error
+	HookClearUntil   func(aggregate lavender.CustomAggregate[E, S], sequence uint64) error
+	HookSaveSnapshot func(aggregate lavender.CustomAggregate[E, S], snapshot S) error
+	HookSnapshotAt   func(aggregate lavender.CustomAggregate[E, S], snapshot S, expected uint64) error
+	HookLoadSnapshot func(aggregate lavender.CustomAggregate[E, S]) (*S, error)
+	HookLoadStored   func(aggregate lavender.CustomAggregate[E, S]) (*S, *RawSnapshot, error)
+	HookReplace      func(aggregate lavender.CustomAggregate[E, S], id string, snapshot S) error
+	HookVerify       func(aggregate lavender.CustomAggregate[E, S]) error
+	HookSetState     func(aggregate lavender.CustomAggregate[E, S], state StreamState) error
+	HookSetMetadata  func(aggregate lavender.CustomAggregate[E, S], metadata StreamMetadata) error
+	HookImport       func(aggregate lavender.CustomAggregate[E, S], events []EventRecord[E], snapshots []SnapshotRecord[S]) error
+	HookImportStream func(aggregate lavender.CustomAggregate[E, S], record StreamRecord) error
+	HookScavenge     func() (int, error)
+}
+
+var _ Store[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ SignedEventStore[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ Verifier[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ ConcurrentEventStore[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ ConcurrentSnapshotStore[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ LifecycleStore[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ MetadataStore[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ Scavenger = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ HistoryStore[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ TailEventStore[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ RecordStore[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ StreamRecordStore[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ RawStore = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+var _ SchemaStore[lavender.Event, lavender.Snapshot] = new(StoreWrapper[lavender.Event, lavender.Snapshot])
+
+// SaveEvents implements EventStore.
+func (w *StoreWrapper[E, S]) SaveEvents(aggregate lavender.CustomAggregate[E, S], events []E) error {
+	return w.SaveSignedEvents(aggregate, events, nil)
+}
+
+// SaveSignedEvents implements SignedEventStore.
+func (w *StoreWrapper[E, S]) SaveSignedEvents(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature) error {
+	if w.HookSaveEvents != nil {
+		return w.HookSaveEvents(aggregate, events, signatures)
+	}
+	return SaveSigned(w.Next, aggregate, events, signatures)
+}
+
+// SaveEventsAt implements ConcurrentEventStore.
+func (w *StoreWrapper[E, S]) SaveEventsAt(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature, expected uint64) error {
+	if w.HookSaveEventsAt != nil {
+		return w.HookSaveEventsAt(aggregate, events, signatures, expected)
+	}
+	return SaveAt(w.Next, aggregate, events, signatures, expected)
+}
+
+// Sequence implements ConcurrentEventStore.
+func (w *StoreWrapper[E, S]) Sequence(aggregate lavender.CustomAggregate[E, S]) (uint64, error) {
+	return StreamSequence(w.Next, aggregate)
+}
+
+// LoadEvents implements EventStore.
+func (w *StoreWrapper[E, S]) LoadEvents(aggregate lavender.CustomAggregate[E, S]) ([]E, error) {
+	events, _, err := w.LoadSignedEvents(aggregate)
+	return events, err
+}
+
+// LoadSignedEvents implements SignedEventStore.
+func (w *StoreWrapper[E, S]) LoadSignedEvents(aggregate lavender.CustomAggregate[E, S]) ([]E, []Signature, error) {
+	if w.HookLoadEvents != nil {
+		return w.HookLoadEvents(aggregate)
+	}
+	return LoadSigned(w.Next, aggregate)
+}
+
+// LoadEventsAfter implements TailEventStore. If only HookLoadEvents is set, ErrUnsupported is returned, so
+// LoadEventsAfter falls back to the hook.
+func (w *StoreWrapper[E, S]) LoadEventsAfter(aggregate lavender.CustomAggregate[E, S], sequence uint64) ([]E, []Signature, error) {
+	if w.HookLoadAfter != nil {
+		return w.HookLoadAfter(aggregate, sequence)
+	}
+	if w.HookLoadEvents != nil {
+		return nil, nil, ErrUnsupported
+	}
+	return LoadEventsAfter(w.Next, aggregate, sequence)
+}
+
+// LoadHistory implements HistoryStore.
+func (w *StoreWrapper[E, S]) LoadHistory(aggregate lavender.CustomAggregate[E, S], until time.Time) ([]E, error) {
+	return LoadHistory(w.Next, aggregate, until)
+}
+
+// ClearEvents implements EventStore.
+func (w *StoreWrapper[E, S]) ClearEvents(aggregate lavender.CustomAggregate[E, S]) error {
+	if w.HookClearEvents != nil {
+		return w.HookClearEvents(aggregate)
+	}
+	return w.Next.ClearEvents(aggregate)
+}
+
+// ClearEventsUntil implements ConcurrentEventStore.
+func (w *StoreWrapper[E, S]) ClearEventsUntil(aggregate lavender.CustomAggregate[E, S], sequence uint64) error {
+	if w.HookClearUntil != nil {
+		return w.HookClearUntil(aggregate, sequence)
+	}
+	return ClearUntil(w.Next, aggregate, sequence)
+}
+
+// SaveSnapshot implements SnapshotStore.
+func (w *StoreWrapper[E, S]) SaveSnapshot(aggregate lavender.CustomAggregate[E, S], snapshot S) error {
+	if w.HookSaveSnapshot != nil {
+		return w.HookSaveSnapshot(aggregate, snapshot)
+	}
+	return w.Next.SaveSnapshot(aggregate, snapshot)
+}
+
+// SaveSnapshotAt implements ConcurrentSnapshotStore.
+func (w *StoreWrapper[E, S]) SaveSnapshotAt(aggregate lavender.CustomAggregate[E, S], snapshot S, expected uint64) error {
+	if w.HookSnapshotAt != nil {
+		return w.HookSnapshotAt(aggregate, snapshot, expected)
+	}
+	return SaveSnapshotAt(w.Next, aggregate, snapshot, expected)
+}
+
+// LoadSnapshot implements SnapshotStore.
+func (w *StoreWrapper[E, S]) LoadSnapshot(aggregate lavender.CustomAggregate[E, S]) (*S, error) {
+	if w.HookLoadSnapshot != nil {
+		return w.HookLoadSnapshot(aggregate)
+	}
+	return w.Next.LoadSnapshot(aggregate)
+}
+
+// LoadStoredSnapshot implements SchemaStore. If only HookLoadSnapshot is set, ErrUnsupported is returned,
+// so LoadStoredSnapshot falls back to the hook.
+func (w *StoreWrapper[E, S]) LoadStoredSnapshot(aggregate lavender.CustomAggregate[E, S]) (*S, *RawSnapshot, error) {
+	if w.HookLoadStored != nil {
+		return w.HookLoadStored(aggregate)
+	}
+	if w.HookLoadSnapshot != nil {
+		return nil, nil, ErrUnsupported
+	}
+	return LoadStoredSnapshot(w.Next, aggregate)
+}
+
+// ReplaceSnapshot implements SchemaStore. If only HookSaveSnapshot is set, ErrUnsupported is returned, so
+// ReplaceSnapshot falls back to the hook.
+func (w *StoreWrapper[E, S]) ReplaceSnapshot(aggregate lavender.CustomAggregate[E, S], id string, snapshot S) error {
+	if w.HookReplace != nil {
+		return w.HookReplace(aggregate, id, snapshot)
+	}
+	if w.HookSaveSnapshot != nil {
+		return ErrUnsupported
+	}
+	return ReplaceSnapshot(w.Next, aggregate, id, snapshot)
+}
+
+// Verify implements Verifier.
+func (w *StoreWrapper[E, S]) Verify(aggregate lavender.CustomAggregate[E, S]) error {
+	if w.HookVerify != nil {
+		return w.HookVerify(aggregate)
+	}
+	return VerifyStream(w.Next, aggregate)
+}
+
+// StreamState implements LifecycleStore.
+func (w *StoreWrapper[E, S]) StreamState(aggregate lavender.CustomAggregate[E, S]) (StreamState, error) {
+	if lifecycleStore, ok := w.Next.(LifecycleStore[E, S]); ok {
+		return lifecycleStore.StreamState(aggregate)
+	}
+	return StreamActive, ErrUnsupported
+}
+
+// SetStreamState implements LifecycleStore.
+func (w *StoreWrapper[E, S]) SetStreamState(aggregate lavender.CustomAggregate[E, S], state StreamState) error {
+	if w.HookSetState != nil {
+		return w.HookSetState(aggregate, state)
+	}
+	return SetState(w.Next, aggregate, state)
+}
+
+// StreamMetadata implements MetadataStore.
+func (w *StoreWrapper[E, S]) StreamMetadata(aggregate lavender.CustomAggregate[E, S]) (StreamMetadata, error) {
+	return MetadataOf(w.Next, aggregate)
+}
+
+// SetStreamMetadata implements MetadataStore.
+func (w *StoreWrapper[E, S]) SetStreamMetadata(aggregate lavender.CustomAggregate[E, S], metadata StreamMetadata) error {
+	if w.HookSetMetadata != nil {
+		return w.HookSetMetadata(aggregate, metadata)
+	}
+	return SetMetadata(w.Next, aggregate, metadata)
+}
+
+// Scavenge implements Scavenger.
+func (w *StoreWrapper[E, S]) Scavenge() (int, error) {
+	if w.HookScavenge != nil {
+		return w.HookScavenge()
+	}
+	return Scavenge[E, S](w.Next)
+}
+
+// Streams implements RecordStore.
+func (w *StoreWrapper[E, S]) Streams() ([]StreamRef, error) {
+	if recordStore, ok := w.Next.(RecordStore[E, S]); ok {
+		return recordStore.Streams()
+	}
+	return nil, ErrUnsupported
+}
+
+// LoadRecords implements RecordStore.
+func (w *StoreWrapper[E, S]) LoadRecords(aggregate lavender.CustomAggregate[E, S]) ([]EventRecord[E], []SnapshotRecord[S], error) {
+	if recordStore, ok := w.Next.(RecordStore[E, S]); ok {
+		return recordStore.LoadRecords(aggregate)
+	}
+	return nil, nil, ErrUnsupported
+}
+
+// LoadRawEvents implements RawStore.
+func (w *StoreWrapper[E, S]) LoadRawEvents(stream StreamRef) ([]RawEvent, error) {
+	return LoadRawEvents(w.Next, stream)
+}
+
+// LoadRawSnapshots implements RawStore.
+func (w *StoreWrapper[E, S]) LoadRawSnapshots(stream StreamRef) ([]RawSnapshot, error) {
+	return LoadRawSnapshots(w.Next, stream)
+}
+
+// ImportRecords implements RecordStore.
+func (w *StoreWrapper[E, S]) ImportRecords(aggregate lavender.CustomAggregate[E, S], events []EventRecord[E], snapshots []SnapshotRecord[S]) error {
+	if w.HookImport != nil {
+		return w.HookImport(aggregate, events, snapshots)
+	}
+	return ImportRecords(w.Next, w.Next, aggregate, events, snapshots)
+}
+
+// LoadStreamRecord implements StreamRecordStore.
+func (w *StoreWrapper[E, S]) LoadStreamRecord(aggregate lavender.CustomAggregate[E, S]) (StreamRecord, error) {
+	return ExportStream(w.Next, aggregate)
+}
+
+// ImportStreamRecord implements StreamRecordStore.
+func (w *StoreWrapper[E, S]) ImportStreamRecord(aggregate lavender.CustomAggregate[E, S], record StreamRecord) error {
+	if w.HookImportStream != nil {
+		return w.HookImportStream(aggregate, record)
+	}
+	return ImportStream(w.Next, aggregate, record)
+}
+
+// SaveSigned stores events with signatures if the store supports it. Stores without signature support
+// can only store unsigned events.
+func SaveSigned[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature) error {
+	if signedStore, ok := store.(SignedEventStore[E, S]); ok {
+		return signedStore.SaveSignedEvents(aggregate, events, signatures)
+	}
+	if signatures != nil {
+		return ErrUnsupported
+	}
+	return store.SaveEvents(aggregate, events)
+}
+
+// SaveAt stores events only if the stream is still at the expected sequence if the store supports it.
+func SaveAt[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature, expected uint64) error {
+	if concurrentStore, ok := store.(ConcurrentEventStore[E, S]); ok {
+		return concurrentStore.SaveEventsAt(aggregate, events, signatures, expected)
+	}
+	return ErrUnsupported
+}
+
+// StreamSequence returns the sequence of the last event of the aggregate's stream if the store supports it.
+func StreamSequence[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S]) (uint64, error) {
+	if concurrentStore, ok := store.(ConcurrentEventStore[E, S]); ok {
+		return concurrentStore.Sequence(aggregate)
+	}
+	return 0, ErrUnsupported
+}
+
+// ClearUntil removes the events of the stream up to and including the sequence if the store supports it.
+func ClearUntil[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S], sequence uint64) error {
+	if concurrentStore, ok := store.(ConcurrentEventStore[E, S]); ok {
+		return concurrentStore.ClearEventsUntil(aggregate, sequence)
+	}
+	return ErrUnsupported
+}
+
+// SaveSnapshotAt stores a snapshot only if the stream is still at the expected sequence if the store
+// supports it.
+func SaveSnapshotAt[E lavender.Event, S lavender.Snapshot](store SnapshotStore[E, S], aggregate lavender.CustomAggregate[E, S], snapshot S, expected uint64) error {
+	if concurrentStore, ok := store.(ConcurrentSnapshotStore[E, S]); ok {
+		return concurrentStore.SaveSnapshotAt(aggregate, snapshot, expected)
+	}
+	return ErrUnsupported
+}
+
+// LoadSigned loads events with signatures if the store supports it, otherwise all events are unsigned.
+func LoadSigned[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S]) ([]E, []Signature, error) {
+	if signedStore, ok := store.(SignedEventStore[E, S]); ok {
+		return signedStore.LoadSignedEvents(aggregate)
+	}
+	events, err := store.LoadEvents(aggregate)
+	return events, make([]Signature, len(events)), err
+}
+
+// VerifyStream verifies the hash chain of the aggregate's stream if the store supports it.
+func VerifyStream[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S]) error {
+	if verifier, ok := store.(Verifier[E, S]); ok {
+		return verifier.Verify(aggregate)
+	}
+	return ErrUnsupported
+}
+
+// Around creates a middleware that passes every operation through the function. The function must call
+// next to run the operation on the wrapped store and may inspect or replace its error.
+func Around[E lavender.Event, S lavender.Snapshot](around func(op Operation, aggregate lavender.Name, next func() error) error) Middleware[E, S] {
+	return func(next Store[E, S]) Store[E, S] {
+		return &StoreWrapper[E, S]{
+			Next: next,
+			HookSaveEvents: func(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature) error {
+				return around(OpSaveEvents, aggregate.Name(), func() error {
+					return SaveSigned(next, aggregate, events, signatures)
+				})
+			},
+			HookSaveEventsAt: func(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature, expected uint64) error {
+				return around(OpSaveEventsAt, aggregate.Name(), func() error {
+					return SaveAt(next, aggregate, events, signatures, expected)
+				})
+			},
+			HookLoadEvents: func(aggregate lavender.CustomAggregate[E, S]) (events []E, signatures []Signature, err error) {
+				err = around(OpLoadEvents, aggregate.Name(), func() (err error) {
+					events, signatures, err = LoadSigned(next, aggregate)
+					return err
+				})
+				return events, signatures, err
+			},
+			HookLoadAfter: func(aggregate lavender.CustomAggregate[E, S], sequence uint64) (events []E, signatures []Signature, err error) {
+				err = around(OpLoadEvents, aggregate.Name(), func() (err error) {
+					events, signatures, err = LoadEventsAfter(next, aggregate, sequence)
+					return err
+				})
+				return events, signatures, err
+			},
+			HookClearEvents: func(aggregate lavender.CustomAggregate[E, S]) error {
+				return around(OpClearEvents, aggregate.Name(), func() error {
+					return next.ClearEvents(aggregate)
+				})
+			},
+			HookClearUntil: func(aggregate lavender.CustomAggregate[E, S], sequence uint64) error {
+				return around(OpClearEvents, aggregate.Name(), func() error {
+					return ClearUntil(next, aggregate, sequence)
+				})
+			},
+			HookSaveSnapshot: func(aggregate lavender.CustomAggregate[E, S], snapshot S) error {
+				return around(OpSaveSnapshot, aggregate.Name(), func() error {
+					return next.SaveSnapshot(aggregate, snapshot)
+				})
+			},
+			HookSnapshotAt: func(aggregate lavender.CustomAggregate[E, S], snapshot S, expected uint64) error {
+				return around(OpSaveSnapshot, aggregate.Name(), func() error {
+					return SaveSnapshotAt(next, aggregate, snapshot, expected)
+				})
+			},
+			HookLoadSnapshot: func(aggregate lavender.CustomAggregate[E, S]) (snapshot *S, err error) {
+				err = around(OpLoadSnapshot, aggregate.Name(), func() (err error) {
+					snapshot, err = next.LoadSnapshot(aggregate)
+					return err
+				})
+				return snapshot, err
+			},
+			HookLoadStored: func(aggregate lavender.CustomAggregate[E, S]) (snapshot *S, raw *RawSnapshot, err error) {
+				err = around(OpLoadSnapshot, aggregate.Name(), func() (err error) {
+					snapshot, raw, err = LoadStoredSnapshot(next, aggregate)
+					return err
+				})
+				return snapshot, raw, err
+			},
+			HookReplace: func(aggregate lavender.CustomAggregate[E, S], id string, snapshot S) error {
+				return around(OpSaveSnapshot, aggregate.Name(), func() error {
+					return ReplaceSnapshot(next, aggregate, id, snapshot)
+				})
+			},
+			HookVerify: func(aggregate lavender.CustomAggregate[E, S]) error {
+				return around(OpVerify, aggregate.Name(), func() error {
+					return VerifyStream(next, aggregate)
+				})
+			},
+			HookSetState: func(aggregate lavender.CustomAggregate[E, S], state StreamState) error {
+				return around(OpSetState, aggregate.Name(), func() error {
+					return SetState(next, aggregate, state)
+				})
+			},
+			HookSetMetadata: func(aggregate lavender.CustomAggregate[E, S], metadata StreamMetadata) error {
+				return around(OpSetMetadata, aggregate.Name(), func() error {
+					return SetMetadata(next, aggregate, metadata)
+				})
+			},
+			HookImport: func(aggregate lavender.CustomAggregate[E, S], events []EventRecord[E], snapshots []SnapshotRecord[S]) error {
+				return around(OpImport, aggregate.Name(), func() error {
+					return ImportRecords(next, next, aggregate, events, snapshots)
+				})
+			},
+			HookImportStream: func(aggregate lavender.CustomAggregate[E, S], record StreamRecord) error {
+				return around(OpImport, aggregate.Name(), func() error {
+					return ImportStream(next, aggregate, record)
+				})
+			},
+			HookScavenge: func() (removed int, err error) {
+				// Scavenging covers all streams, so it isn't passed an aggregate name
+				err = around(OpScavenge, "", func() (err error) {
+					removed, err = Scavenge[E, S](next)
+					return err
+				})
+				return removed, err
+			},
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package store_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/instrument"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var errFlaky = errors.New("flaky")
+
+// flaky fails the first n save operations.
+func flaky(n int) store.Middleware[lavender.Event, lavender.Snapshot] {
+	return func(next store.Store[lavender.Event, lavender.Snapshot]) store.Store[lavender.Event, lavender.Snapshot] {
+		return &store.StoreWrapper[lavender.Event, lavender.Snapshot]{
+			Next: next,
+			HookSaveEvents: func(aggregate lavender.Aggregate, events []lavender.Event, signatures []store.Signature) error {
+				if n > 0 {
+					n--
+					return errFlaky
+				}
+				return store.SaveSigned(next, aggregate, events, signatures)
+			},
+			HookSaveEventsAt: func(aggregate lavender.Aggregate, events []lavender.Event, signatures []store.Signature, expected uint64) error {
+				if n > 0 {
+					n--
+					return errFlaky
+				}
+				return store.SaveAt(next, aggregate, events, signatures, expected)
+			},
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	event := []lavender.Event{&example.Create{User: *example.NewUser("duck@ducky.com", "secret")}}
+
+	t.Run("order", func(t *testing.T) {
+		var calls []string
+		trace := func(name string) store.Middleware[lavender.Event, lavender.Snapshot] {
+			return store.Around[lavender.Event, lavender.Snapshot](func(op store.Operation, aggregate lavender.Name, next func() error) error {
+				calls = append(calls, name+":"+string(op))
+				return next()
+			})
+		}
+		chained := store.Chain(store.NewInMemoryStore(), trace("outer"), trace("inner"))
+		_, err := chained.LoadSnapshot(example.New())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"outer:LoadSnapshot", "inner:LoadSnapshot"}, calls)
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		memStore := store.NewInMemoryStore()
+		assert.NoError(t, memStore.SaveEvents(example.New(), event))
+
+		chained := store.Chain[lavender.Event, lavender.Snapshot](memStore, store.ReadOnly[lavender.Event, lavender.Snapshot]())
+		assert.ErrorIs(t, chained.SaveEvents(example.New(), event), store.ErrReadOnly)
+		assert.ErrorIs(t, chained.ClearEvents(example.New()), store.ErrReadOnly)
+		assert.ErrorIs(t, store.SaveAt(chained, example.New(), event, nil, 1), store.ErrReadOnly)
+		assert.ErrorIs(t, chained.SaveSnapshot(example.New(), example.New().TakeSnapshot()), store.ErrReadOnly)
+
+		// Scavenging deletes hidden events, so it is rejected as well.
+		assert.NoError(t, store.SetMetadata(memStore, example.New(), store.StreamMetadata{MaxCount: 1}))
+		assert.NoError(t, memStore.SaveEvents(example.New(), event))
+		_, err := store.Scavenge[lavender.Event, lavender.Snapshot](chained)
+		assert.ErrorIs(t, err, store.ErrReadOnly)
+		removed, err := store.Scavenge[lavender.Event, lavender.Snapshot](memStore)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, removed)
+
+		events, err := chained.LoadEvents(example.New())
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		memStore := store.NewInMemoryStore()
+		chained := store.Chain[lavender.Event, lavender.Snapshot](memStore, store.Retry[lavender.Event, lavender.Snapshot](3, 0), flaky(2))
+		assert.NoError(t, store.SaveAt(chained, example.New(), event, nil, 0))
+
+		chained = store.Chain[lavender.Event, lavender.Snapshot](memStore, store.Retry[lavender.Event, lavender.Snapshot](3, 0), flaky(3))
+		assert.ErrorIs(t, store.SaveAt(chained, example.New(), event, nil, 1), errFlaky)
+
+		// Appends without an expected sequence could be stored twice, they are not retried.
+		chained = store.Chain[lavender.Event, lavender.Snapshot](memStore, store.Retry[lavender.Event, lavender.Snapshot](3, 0), flaky(1))
+		assert.ErrorIs(t, chained.SaveEvents(example.New(), event), errFlaky)
+
+		events, _ := memStore.LoadEvents(example.New())
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("logging and timing", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		recorder := instrument.NewRecorder()
+
+		chained := store.Chain[lavender.Event, lavender.Snapshot](store.NewInMemoryStore(),
+			store.Logging[lavender.Event, lavender.Snapshot](logger),
+			store.Timing[lavender.Event, lavender.Snapshot](recorder),
+			flaky(1),
+		)
+		assert.ErrorIs(t, chained.SaveEvents(example.New(), event), errFlaky)
+		assert.NoError(t, chained.SaveEvents(example.New(), event))
+
+		assert.Contains(t, buf.String(), "store operation failed")
+		assert.Contains(t, buf.String(), "operation=SaveEvents")
+		assert.Len(t, recorder.Histogram(instrument.MetricStoreDuration), 2)
+	})
+
+	t.Run("signatures", func(t *testing.T) {
+		chained := store.Chain[lavender.Event, lavender.Snapshot](store.NewInMemoryStore(), store.Logging[lavender.Event, lavender.Snapshot](slog.Default()))
+		signed := chained.(store.SignedEventStore[lavender.Event, lavender.Snapshot])
+		signature := store.Signature{KeyID: "key", Signature: []byte{1}, EventID: "event", Sequence: 1}
+		assert.NoError(t, signed.SaveSignedEvents(example.New(), event, []store.Signature{signature}))
+
+		_, signatures, err := signed.LoadSignedEvents(example.New())
+		assert.NoError(t, err)
+		assert.Equal(t, []store.Signature{signature}, signatures)
+		head, err := store.StreamSequence(chained, example.New())
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, head, "the stream head is read through the chain to sign events")
+
+		// Signed events can't be stored at another sequence.
+		signature.Sequence = 1
+		assert.ErrorIs(t, signed.SaveSignedEvents(example.New(), event, []store.Signature{signature}), store.ErrConflict)
+	})
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/instrument"
+)
+
+// ErrReadOnly is returned by a store wrapped with ReadOnly for every write operation.
+var ErrReadOnly = errors.New("store is read-only")
+
+// Logging logs every store operation with its duration and error.
+func Logging[E lavender.Event, S lavender.Snapshot](logger *slog.Logger) Middleware[E, S] {
+	return Around[E, S](func(op Operation, aggregate lavender.Name, next func() error) error {
+		start := time.Now()
+		err := next()
+		attrs := []any{
+			slog.String("operation", string(op)),
+			slog.String("aggregate", string(aggregate)),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if err != nil {
+			logger.Error("store operation failed", append(attrs, slog.Any("error", err))...)
+		} else {
+			logger.Debug("store operation", attrs...)
+		}
+		return err
+	})
+}
+
+// Timing records the duration of every store operation as instrument.MetricStoreDuration.
+func Timing[E lavender.Event, S lavender.Snapshot](instrumentation instrument.Instrumentation) Middleware[E, S] {
+	return Around[E, S](func(op Operation, aggregate lavender.Name, next func() error) error {
+		defer instrument.Since(instrumentation, instrument.MetricStoreDuration, time.Now(),
+			instrument.Attr("operation", op), instrument.Attr("aggregate", aggregate))
+		return next()
+	})
+}
+
+// Retry retries failed reads and conflict-checked appends up to attempts times in total, waiting backoff
+// times the number of failed attempts between them. Other writes are not retried: an append whose commit
+// got lost would be stored twice. Concurrency conflicts are not retried either, they fail again until the
+// aggregate is reloaded.
+func Retry[E lavender.Event, S lavender.Snapshot](attempts int, backoff time.Duration) Middleware[E, S] {
+	return RetryIf[E, S](attempts, backoff, func(err error) bool { return !errors.Is(err, ErrConflict) })
+}
+
+// RetryIf retries failed reads and conflict-checked appends that failed with a retryable error up to
+// attempts times in total, waiting backoff times the number of failed attempts between them.
+func RetryIf[E lavender.Event, S lavender.Snapshot](attempts int, backoff time.Duration, retryable func(err error) bool) Middleware[E, S] {
+	return Around[E, S](func(op Operation, aggregate lavender.Name, next func() error) error {
+		if op.Writes() && op != OpSaveEventsAt {
+			return next()
+		}
+		var err error
+		for attempt := 1; ; attempt++ {
+			if err = next(); err == nil || attempt >= attempts || !retryable(err) {
+				return err
+			}
+			time.Sleep(backoff * time.Duration(attempt))
+		}
+	})
+}
+
+// ReadOnly rejects every write operation with ErrReadOnly.
+func ReadOnly[E lavender.Event, S lavender.Snapshot]() Middleware[E, S] {
+	return Around[E, S](func(op Operation, aggregate lavender.Name, next func() error) error {
+		if op.Writes() {
+			return ErrReadOnly
+		}
+		return next()
+	})
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+)
+
+// RawEvent is a stored event with its encoded data, it can be read without the Go types of the aggregate.
+type RawEvent struct {
+	ID          string           // Unique ID of the event
+	Position    uint64           // Global position inside the table, 0 for archived events
+	Tenant      string           // Tenant the stream belongs to
+	Name        lavender.Name    // Aggregate name (stream ID)
+	Sequence    uint64           // Position inside the stream
+	CreatedAt   time.Time        // Timestamp when the event was stored
+	Version     lavender.Version // Aggregate version the event has been stored with
+	Topic       lavender.Name    // Event name
+	ContentType string           // Content type of the encoder that produced the data
+	Data        []byte           // Encoded event data
+	Hash        []byte           // Hash chain link of the event
+	PrevHash    []byte           // Hash of the previous event of the stream
+	Signature   Signature        // Signature of the event envelope
+}
+
+// Tree decodes the data of the event without its Go type, see encoder.DecodeTree.
+func (e RawEvent) Tree() (any, error) {
+	return encoders.DecodeTree(e.ContentType, e.Data)
+}
+
+// RawSnapshot is a stored snapshot with its encoded data, it can be read without the Go types of the
+// aggregate.
+type RawSnapshot struct {
+	ID          string           // Unique ID of the snapshot
+	Tenant      string           // Tenant the stream belongs to
+	Name        lavender.Name    // Aggregate name
+	CreatedAt   time.Time        // Timestamp when the snapshot was stored
+	Version     lavender.Version // Aggregate version the snapshot has been taken at
+	Sequence    uint64           // Sequence of the last event covered by the snapshot
+	ChainHead   []byte           // Hash of the last event covered by the snapshot
+	Schema      int              // Schema version of the snapshot, see lavender.SchemaVersioned
+	ContentType string           // Content type of the encoder that produced the data
+	Data        []byte           // Encoded snapshot data
+}
+
+// Tree decodes the data of the snapshot without its Go type, see encoder.DecodeTree.
+func (s RawSnapshot) Tree() (any, error) {
+	return encoders.DecodeTree(s.ContentType, s.Data)
+}
+
+// RawStore is implemented by stores that keep encoded events and snapshots and can return them without
+// decoding, also for streams whose aggregates haven't been registered.
+type RawStore interface {
+	// LoadRawEvents returns all stored events of the stream in sequence order, including archived events
+	// and events of other aggregate versions.
+	LoadRawEvents(stream StreamRef) ([]RawEvent, error)
+
+	// LoadRawSnapshots returns all snapshots of the stream of all versions in the order they have been stored.
+	LoadRawSnapshots(stream StreamRef) ([]RawSnapshot, error)
+}
+
+// LoadRawEvents returns the encoded events of the stream if the store supports it.
+func LoadRawEvents[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], stream StreamRef) ([]RawEvent, error) {
+	if rawStore, ok := store.(RawStore); ok {
+		return rawStore.LoadRawEvents(stream)
+	}
+	return nil, ErrUnsupported
+}
+
+// LoadRawSnapshots returns the encoded snapshots of the stream if the store supports it.
+func LoadRawSnapshots[E lavender.Event, S lavender.Snapshot](store SnapshotStore[E, S], stream StreamRef) ([]RawSnapshot, error) {
+	if rawStore, ok := store.(RawStore); ok {
+		return rawStore.LoadRawSnapshots(stream)
+	}
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,83 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	encoders "github.com/FlauschigDings/lavender/encoder"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRaw(t *testing.T) {
+	for name, encoder := range map[string]encoders.Encoder{
+		"cbor": encoders.NewCBorEncoder(),
+		"gob":  encoders.NewGobEncoder(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			db, err := Sqlite(":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gormStore := store.NewGormCustomStore[lavender.Event, lavender.Snapshot](db.DB, encoders.NewMultiEncoder(encoder)).RegisterAggregates(example.New())
+			fillStore(t, gormStore)
+
+			// A store without the aggregate's types reads the stored payloads.
+			rawStore := store.NewGormStore(db.DB)
+			ref := store.StreamRef{Tenant: "ducks", Name: example.New().Name()}
+			events, err := store.LoadRawEvents(rawStore, ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			records, snapshots, err := gormStore.LoadRecords(lavender.WithTenant(example.New(), "ducks"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if assert.Len(t, events, len(records)) {
+				for i, event := range events {
+					assert.Equal(t, records[i].ID, event.ID)
+					assert.Equal(t, records[i].Sequence, event.Sequence)
+					assert.Equal(t, records[i].Hash, event.Hash)
+					assert.Equal(t, "ducks", event.Tenant)
+					assert.Equal(t, lavender.Name("create"), event.Topic)
+					assert.Equal(t, encoder.(encoders.ContentTyper).ContentType(), event.ContentType)
+
+					tree, err := event.Tree()
+					if !assert.NoError(t, err) {
+						continue
+					}
+					create := records[i].Event.(*example.Create)
+					user, ok := tree.(map[string]any)["User"].(map[string]any)
+					if name == "cbor" {
+						// CBOR flattens embedded structs.
+						user, ok = tree.(map[string]any), true
+					}
+					if assert.True(t, ok, "%v", tree) {
+						assert.Equal(t, create.Email, user["Email"])
+						assert.Equal(t, create.Password, user["Password"])
+					}
+				}
+			}
+
+			rawSnapshots, err := store.LoadRawSnapshots(rawStore, ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if assert.Len(t, rawSnapshots, len(snapshots)) {
+				assert.Equal(t, snapshots[0].ID, rawSnapshots[0].ID)
+				assert.Equal(t, snapshots[0].Sequence, rawSnapshots[0].Sequence)
+				_, err := rawSnapshots[0].Tree()
+				assert.NoError(t, err)
+			}
+
+			// Streams without tables have no events.
+			events, err = rawStore.LoadRawEvents(store.StreamRef{Name: "missing"})
+			assert.NoError(t, err)
+			assert.Empty(t, events)
+		})
+	}
+
+	_, err := store.LoadRawEvents(store.NewInMemoryStore(), store.StreamRef{Name: "account"})
+	assert.ErrorIs(t, err, store.ErrUnsupported)
+}
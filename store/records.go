@@ -0,0 +1,269 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+)
+
+// ErrStreamNotEmpty is returned when records are imported into a stream that already has events or snapshots.
+var ErrStreamNotEmpty = errors.New("stream is not empty")
+
+// StreamRef identifies a stream of a store.
+type StreamRef struct {
+	Tenant string        // Tenant the stream belongs to
+	Name   lavender.Name // Aggregate name (stream ID)
+}
+
+// EventRecord is a stored event together with everything the store keeps about it.
+type EventRecord[E lavender.Event] struct {
+	Event     E                // The event
+	ID        string           // Unique ID of the event
+	Sequence  uint64           // Position inside the stream
+	CreatedAt time.Time        // Timestamp when the event was stored
+	Version   lavender.Version // Aggregate version the event has been stored with
+	Hash      []byte           // Hash chain link of the event
+	PrevHash  []byte           // Hash of the previous event of the stream
+	Signature Signature        // Signature of the event envelope
+}
+
+// SnapshotRecord is a stored snapshot together with everything the store keeps about it.
+type SnapshotRecord[S lavender.Snapshot] struct {
+	Snapshot  S                // The snapshot
+	ID        string           // Unique ID of the snapshot
+	CreatedAt time.Time        // Timestamp when the snapshot was stored
+	Version   lavender.Version // Aggregate version the snapshot has been taken at
+	Sequence  uint64           // Sequence of the last event covered by the snapshot
+	ChainHead []byte           // Hash of the last event covered by the snapshot
+	Schema    int              // Schema version of the snapshot, see lavender.SchemaVersioned
+}
+
+// StreamRecord is what a store keeps about a stream besides its events and snapshots.
+type StreamRecord struct {
+	State             StreamState    // Lifecycle state of the stream
+	Metadata          StreamMetadata // Limits of the visible events
+	ScavengedSequence uint64         // Sequence of the last scavenged event
+	ScavengedHash     []byte         // Hash chain link of the last scavenged event
+}
+
+// IsZero reports if the record holds nothing but the defaults of an active stream.
+func (record StreamRecord) IsZero() bool {
+	return (record.State == "" || record.State == StreamActive) && record.Metadata.IsZero() &&
+		record.ScavengedSequence == 0 && record.ScavengedHash == nil
+}
+
+// RecordStore is implemented by stores that can export and import their streams as they are stored,
+// e.g. to move them into another store.
+type RecordStore[E lavender.Event, S lavender.Snapshot] interface {
+	// Streams returns all streams that have events or snapshots.
+	Streams() ([]StreamRef, error)
+
+	// LoadRecords returns all stored events and snapshots of the aggregate's stream in the order they have
+	// been stored, including archived events and events of other aggregate versions.
+	LoadRecords(aggregate lavender.CustomAggregate[E, S]) ([]EventRecord[E], []SnapshotRecord[S], error)
+
+	// ImportRecords stores events and snapshots in the aggregate's stream, keeping their IDs, sequences,
+	// timestamps, versions, hash chain links and signatures. The stream must be empty, otherwise
+	// ErrStreamNotEmpty is returned.
+	ImportRecords(aggregate lavender.CustomAggregate[E, S], events []EventRecord[E], snapshots []SnapshotRecord[S]) error
+}
+
+// StreamRecordStore is implemented by record stores that can export and import the state, metadata and
+// scavenged chain head of their streams.
+type StreamRecordStore[E lavender.Event, S lavender.Snapshot] interface {
+	// LoadStreamRecord returns the record of the aggregate's stream.
+	LoadStreamRecord(aggregate lavender.CustomAggregate[E, S]) (StreamRecord, error)
+
+	// ImportStreamRecord replaces the record of the aggregate's stream. It is called after the events and
+	// snapshots of the stream have been imported, so the state is set without checking the transition.
+	ImportStreamRecord(aggregate lavender.CustomAggregate[E, S], record StreamRecord) error
+}
+
+// ListStreams returns the streams of the store if it supports it. Otherwise the streams of the aggregates
+// without a tenant are returned.
+func ListStreams[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregates ...lavender.CustomAggregate[E, S]) ([]StreamRef, error) {
+	if recordStore, ok := store.(RecordStore[E, S]); ok {
+		streams, err := recordStore.Streams()
+		if !errors.Is(err, ErrUnsupported) {
+			return streams, err
+		}
+	}
+	streams := make([]StreamRef, 0, len(aggregates))
+	for _, aggregate := range aggregates {
+		streams = append(streams, StreamRef{Name: aggregate.Name()})
+	}
+	return streams, nil
+}
+
+// ExportRecords returns the records of the aggregate's stream. Stores that don't implement RecordStore
+// only provide the events, their signatures and the latest snapshot, without IDs or timestamps.
+func ExportRecords[E lavender.Event, S lavender.Snapshot](eventStore EventStore[E, S], snapshotStore SnapshotStore[E, S], aggregate lavender.CustomAggregate[E, S]) ([]EventRecord[E], []SnapshotRecord[S], error) {
+	if recordStore, ok := eventStore.(RecordStore[E, S]); ok {
+		events, snapshots, err := recordStore.LoadRecords(aggregate)
+		if !errors.Is(err, ErrUnsupported) {
+			return events, snapshots, err
+		}
+	}
+	events, signatures, err := LoadSigned(eventStore, aggregate)
+	if err != nil {
+		return nil, nil, err
+	}
+	eventRecords := make([]EventRecord[E], 0, len(events))
+	for i, event := range events {
+		eventRecords = append(eventRecords, EventRecord[E]{
+			Event:     event,
+			Sequence:  uint64(i + 1),
+			Version:   aggregate.Version(),
+			Signature: signatures[i],
+		})
+	}
+	snapshot, err := snapshotStore.LoadSnapshot(aggregate)
+	if err != nil || snapshot == nil {
+		return eventRecords, nil, err
+	}
+	return eventRecords, []SnapshotRecord[S]{{Snapshot: *snapshot, Version: aggregate.Version(), Schema: lavender.SchemaVersionOf(*snapshot)}}, nil
+}
+
+// ImportRecords stores the records in the aggregate's stream. Stores that don't implement RecordStore
+// store the events and snapshots as new ones.
+func ImportRecords[E lavender.Event, S lavender.Snapshot](eventStore EventStore[E, S], snapshotStore SnapshotStore[E, S], aggregate lavender.CustomAggregate[E, S], events []EventRecord[E], snapshots []SnapshotRecord[S]) error {
+	if recordStore, ok := eventStore.(RecordStore[E, S]); ok {
+		err := recordStore.ImportRecords(aggregate, events, snapshots)
+		if !errors.Is(err, ErrUnsupported) {
+			return err
+		}
+	}
+	if len(events) > 0 {
+		items := make([]E, 0, len(events))
+		signatures := make([]Signature, 0, len(events))
+		signed := false
+		for _, record := range events {
+			items = append(items, record.Event)
+			signatures = append(signatures, record.Signature)
+			signed = signed || record.Signature.Signature != nil
+		}
+		if !signed {
+			signatures = nil
+		}
+		if err := SaveSigned(eventStore, aggregate, items, signatures); err != nil {
+			return err
+		}
+	}
+	for _, record := range snapshots {
+		if err := snapshotStore.SaveSnapshot(aggregate, record.Snapshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportStream returns the record of the aggregate's stream. Stores that don't implement StreamRecordStore
+// only provide the state and metadata of the stream.
+func ExportStream[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S]) (StreamRecord, error) {
+	if recordStore, ok := store.(StreamRecordStore[E, S]); ok {
+		record, err := recordStore.LoadStreamRecord(aggregate)
+		if !errors.Is(err, ErrUnsupported) {
+			return record, err
+		}
+	}
+	state, err := StateOf(store, aggregate)
+	if err != nil {
+		return StreamRecord{}, err
+	}
+	metadata, err := MetadataOf(store, aggregate)
+	return StreamRecord{State: state, Metadata: metadata}, err
+}
+
+// ImportStream restores the record of the aggregate's stream after its events and snapshots have been
+// imported. Stores that don't implement StreamRecordStore get the metadata and the state set, which fails
+// with ErrUnsupported if they don't keep them. They don't keep the scavenged chain head.
+func ImportStream[E lavender.Event, S lavender.Snapshot](store EventStore[E, S], aggregate lavender.CustomAggregate[E, S], record StreamRecord) error {
+	if recordStore, ok := store.(StreamRecordStore[E, S]); ok {
+		err := recordStore.ImportStreamRecord(aggregate, record)
+		if !errors.Is(err, ErrUnsupported) {
+			return err
+		}
+	}
+	if !record.Metadata.IsZero() {
+		if err := SetMetadata(store, aggregate, record.Metadata); err != nil {
+			return err
+		}
+	}
+	if record.State != "" && record.State != StreamActive {
+		return SetState(store, aggregate, record.State)
+	}
+	return nil
+}
+
+// Copy copies every stream of the source store into the destination store, preserving the order,
+// timestamps and versions of events and snapshots if both stores implement RecordStore. The states,
+// metadata and scavenged chain heads of the streams are copied as well, see ImportStream. The aggregates
+// are the prototypes used to decode the streams, streams without an aggregate of the same name fail.
+func Copy[E lavender.Event, S lavender.Snapshot](src Store[E, S], dst Store[E, S], aggregates ...lavender.CustomAggregate[E, S]) error {
+	streams, err := ListStreams(src, aggregates...)
+	if err != nil {
+		return err
+	}
+	for _, stream := range streams {
+		aggregate, err := StreamAggregate(stream, aggregates...)
+		if err != nil {
+			return err
+		}
+		events, snapshots, err := ExportRecords(src, src, aggregate)
+		if err != nil {
+			return fmt.Errorf("export %s: %w", stream, err)
+		}
+		record, err := ExportStream(src, aggregate)
+		if err != nil {
+			return fmt.Errorf("export %s: %w", stream, err)
+		}
+		if err := ImportRecords(dst, dst, aggregate, events, snapshots); err != nil {
+			return fmt.Errorf("import %s: %w", stream, err)
+		}
+		if !record.IsZero() {
+			if err := ImportStream(dst, aggregate, record); err != nil {
+				return fmt.Errorf("import %s: %w", stream, err)
+			}
+		}
+	}
+	return nil
+}
+
+// StreamAggregate returns the aggregate of the stream, scoped to the stream's tenant.
+func StreamAggregate[E lavender.Event, S lavender.Snapshot](stream StreamRef, aggregates ...lavender.CustomAggregate[E, S]) (lavender.CustomAggregate[E, S], error) {
+	for _, aggregate := range aggregates {
+		if aggregate.Name() == stream.Name {
+			return lavender.WithTenant(aggregate, stream.Tenant), nil
+		}
+	}
+	return nil, fmt.Errorf("no aggregate for stream %s", stream)
+}
+
+// String returns the stream as "<tenant>/<name>", or just the name for streams without a tenant.
+func (ref StreamRef) String() string {
+	if ref.Tenant == "" {
+		return string(ref.Name)
+	}
+	return ref.Tenant + "/" + string(ref.Name)
+}
+
+// Stream returns the name the stores pass to their encoders for the stream, e.g. to delete the key of the
+// stream from the encoder's KeyStore.
+func (ref StreamRef) Stream() string {
+	return string(streamID(ref).Stream())
+}
+
+// sortStreams sorts the streams by tenant and name.
+func sortStreams(streams []StreamRef) []StreamRef {
+	slices.SortFunc(streams, func(a, b StreamRef) int {
+		if c := strings.Compare(a.Tenant, b.Tenant); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.Name), string(b.Name))
+	})
+	return slices.Compact(streams)
+}
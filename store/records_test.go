@@ -0,0 +1,183 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/example"
+	"github.com/FlauschigDings/lavender/lavendertest"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopy(t *testing.T) {
+	newGorm := func() store.Store[lavender.Event, lavender.Snapshot] {
+		db, err := Sqlite(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store.NewGormStore(db.DB).UseHashChain().RegisterAggregates(example.New())
+	}
+	newMemory := func() store.Store[lavender.Event, lavender.Snapshot] {
+		return store.NewInMemoryStore().UseHashChain()
+	}
+	for name, stores := range map[string][2]func() store.Store[lavender.Event, lavender.Snapshot]{
+		"memory-to-gorm": {newMemory, newGorm},
+		"gorm-to-memory": {newGorm, newMemory},
+		"gorm-to-gorm":   {newGorm, newGorm},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src, dst := stores[0](), stores[1]()
+			fillStore(t, src)
+			if err := store.Copy(src, dst, example.New()); err != nil {
+				t.Fatal(err)
+			}
+			assertSameRecords(t, src, dst)
+			for _, tenant := range []string{"", "ducks"} {
+				assert.NoError(t, store.VerifyStream(dst, lavender.WithTenant(example.New(), tenant)))
+			}
+
+			// Streams are only copied into empty streams.
+			assert.ErrorIs(t, store.Copy(src, dst, example.New()), store.ErrStreamNotEmpty)
+		})
+	}
+}
+
+func TestCopyStreamRecords(t *testing.T) {
+	newGorm := func() store.Store[lavender.Event, lavender.Snapshot] {
+		db, err := Sqlite(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store.NewGormStore(db.DB).UseHashChain().RegisterAggregates(example.New())
+	}
+	newMemory := func() store.Store[lavender.Event, lavender.Snapshot] {
+		return store.NewInMemoryStore().UseHashChain()
+	}
+	for name, stores := range map[string][2]func() store.Store[lavender.Event, lavender.Snapshot]{
+		"memory-to-gorm": {newMemory, newGorm},
+		"gorm-to-memory": {newGorm, newMemory},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src, dst := stores[0](), stores[1]()
+			fillStore(t, src)
+			closed := example.New()
+			ducks := lavender.WithTenant(example.New(), "ducks")
+			geese := lavender.WithTenant(example.New(), "geese")
+			if err := src.SaveEvents(geese, []lavender.Event{&example.Create{User: *example.NewUser("g@t.de", "secret")}}); err != nil {
+				t.Fatal(err)
+			}
+			metadata := store.StreamMetadata{MaxCount: 1}
+			assert.NoError(t, store.SetMetadata(src, closed, metadata))
+			removed, err := src.(store.Scavenger).Scavenge()
+			if assert.NoError(t, err) {
+				assert.Equal(t, 2, removed)
+			}
+			assert.NoError(t, store.SetState(src, closed, store.StreamClosed))
+			assert.NoError(t, store.SetState(src, ducks, store.StreamDeleted))
+			assert.NoError(t, store.SetState(src, geese, store.StreamTombstoned))
+
+			if err := store.Copy(src, dst, example.New()); err != nil {
+				t.Fatal(err)
+			}
+			assertSameRecords(t, src, dst)
+			for aggregate, state := range map[lavender.Aggregate]store.StreamState{
+				closed: store.StreamClosed,
+				ducks:  store.StreamDeleted,
+				geese:  store.StreamTombstoned,
+			} {
+				expected, err := store.ExportStream(src, aggregate)
+				if err != nil {
+					t.Fatal(err)
+				}
+				record, err := store.ExportStream(dst, aggregate)
+				if assert.NoError(t, err) {
+					assert.Equal(t, expected, record)
+					assert.Equal(t, state, record.State)
+				}
+			}
+			assert.NoError(t, store.VerifyStream(dst, closed))
+			copied, err := store.MetadataOf(dst, closed)
+			if assert.NoError(t, err) {
+				assert.Equal(t, metadata, copied)
+			}
+			assert.ErrorIs(t, dst.SaveEvents(closed, []lavender.Event{&example.Create{User: *example.NewUser("d@t.de", "secret")}}), store.ErrStreamClosed)
+		})
+	}
+}
+
+// fillStore stores events and snapshots in the streams of the default tenant and the ducks tenant.
+func fillStore(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	t.Helper()
+	clock := lavendertest.NewTickingClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), time.Minute)
+	switch s := s.(type) {
+	case *store.GormStore[lavender.Event, lavender.Snapshot]:
+		s.UseClock(clock)
+	case *store.InMemoryEventStore[lavender.Event, lavender.Snapshot]:
+		s.UseClock(clock)
+	}
+	for _, tenant := range []string{"", "ducks"} {
+		aggregate := lavender.WithTenant(example.New(), tenant)
+		for _, email := range []string{"a@t.de", "b@t.de"} {
+			if err := s.SaveEvents(aggregate, []lavender.Event{&example.Create{User: *example.NewUser(email, "secret")}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.SaveSnapshot(aggregate, example.New().TakeSnapshot()); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveEvents(aggregate, []lavender.Event{&example.Create{User: *example.NewUser("c@t.de", "secret")}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// assertSameRecords asserts that both stores hold the same streams with the same records.
+func assertSameRecords(t *testing.T, expected, actual store.Store[lavender.Event, lavender.Snapshot]) {
+	t.Helper()
+	streams, err := store.ListStreams(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualStreams, err := store.ListStreams(actual)
+	if assert.NoError(t, err) {
+		assert.Equal(t, streams, actualStreams)
+	}
+	for _, stream := range streams {
+		aggregate, err := store.StreamAggregate(stream, example.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedEvents, expectedSnapshots, err := store.ExportRecords(expected, expected, aggregate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events, snapshots, err := store.ExportRecords(actual, actual, aggregate)
+		if !assert.NoError(t, err) || !assert.Len(t, events, len(expectedEvents)) || !assert.Len(t, snapshots, len(expectedSnapshots)) {
+			continue
+		}
+		for i, record := range expectedEvents {
+			assert.Equal(t, record.ID, events[i].ID)
+			assert.Equal(t, record.Sequence, events[i].Sequence)
+			assert.True(t, record.CreatedAt.Equal(events[i].CreatedAt), "created at %s, expected %s", events[i].CreatedAt, record.CreatedAt)
+			assert.Equal(t, record.Version, events[i].Version)
+			assert.Equal(t, record.Hash, events[i].Hash)
+			assert.Equal(t, record.Event, events[i].Event)
+		}
+		for i, record := range expectedSnapshots {
+			assert.Equal(t, record.ID, snapshots[i].ID)
+			assert.Equal(t, record.Sequence, snapshots[i].Sequence)
+			assert.True(t, record.CreatedAt.Equal(snapshots[i].CreatedAt))
+			assert.Equal(t, record.ChainHead, snapshots[i].ChainHead)
+		}
+	}
+}
+
+func TestStreamRefStream(t *testing.T) {
+	tenanted := store.StreamRef{Tenant: "acme", Name: "account"}
+	prefixed := store.StreamRef{Name: "acme/account"}
+	assert.Equal(t, tenanted.String(), prefixed.String())
+	assert.NotEqual(t, tenanted.Stream(), prefixed.Stream(), "streams must get their own keys")
+	assert.Equal(t, "account", store.StreamRef{Name: "account"}.Stream())
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/FlauschigDings/lavender"
+)
+
+var (
+	// ErrSnapshotUndecodable is returned when the data of a stored snapshot can't be decoded into the
+	// snapshot type of the aggregate.
+	ErrSnapshotUndecodable = errors.New("snapshot can't be decoded")
+
+	// ErrSnapshotNotFound is returned when a snapshot to replace doesn't exist.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+)
+
+// SchemaStore is implemented by snapshot stores that keep the schema version of their snapshots and can
+// replace a stored snapshot, so snapshots of an older schema can be detected and upgraded in place.
+type SchemaStore[E lavender.Event, S lavender.Snapshot] interface {
+	// LoadStoredSnapshot returns the snapshot LoadSnapshot would return together with what the store
+	// keeps about it, both are nil if there is none. If the data can't be decoded the stored snapshot is
+	// returned with an error wrapping ErrSnapshotUndecodable.
+	LoadStoredSnapshot(aggregate lavender.CustomAggregate[E, S]) (*S, *RawSnapshot, error)
+
+	// ReplaceSnapshot replaces the data of the stored snapshot with the given ID and records the schema
+	// version of the new snapshot. The snapshot keeps its ID and the events it covers.
+	ReplaceSnapshot(aggregate lavender.CustomAggregate[E, S], id string, snapshot S) error
+}
+
+// LoadStoredSnapshot returns the latest snapshot of the aggregate with what the store keeps about it. Stores
+// that don't implement SchemaStore only report the schema version of the loaded snapshot.
+func LoadStoredSnapshot[E lavender.Event, S lavender.Snapshot](store SnapshotStore[E, S], aggregate lavender.CustomAggregate[E, S]) (*S, *RawSnapshot, error) {
+	if schemaStore, ok := store.(SchemaStore[E, S]); ok {
+		snapshot, raw, err := schemaStore.LoadStoredSnapshot(aggregate)
+		if !errors.Is(err, ErrUnsupported) {
+			return snapshot, raw, err
+		}
+	}
+	snapshot, err := store.LoadSnapshot(aggregate)
+	if err != nil || snapshot == nil {
+		return nil, nil, err
+	}
+	return snapshot, &RawSnapshot{
+		Tenant:  lavender.TenantOf(aggregate),
+		Name:    aggregate.Name(),
+		Version: aggregate.Version(),
+		Schema:  lavender.SchemaVersionOf(*snapshot),
+	}, nil
+}
+
+// ReplaceSnapshot replaces the stored snapshot with the given ID if the store supports it, otherwise the
+// snapshot is stored as a new one.
+func ReplaceSnapshot[E lavender.Event, S lavender.Snapshot](store SnapshotStore[E, S], aggregate lavender.CustomAggregate[E, S], id string, snapshot S) error {
+	if schemaStore, ok := store.(SchemaStore[E, S]); ok && id != "" {
+		err := schemaStore.ReplaceSnapshot(aggregate, id, snapshot)
+		if !errors.Is(err, ErrUnsupported) {
+			return err
+		}
+	}
+	return store.SaveSnapshot(aggregate, snapshot)
+}
@@ -0,0 +1,51 @@
+package store
+
+import "github.com/FlauschigDings/lavender"
+
+// Signature is the signature of an event envelope and the ID of the key that created it.
+// Unsigned events have an empty signature.
+type Signature struct {
+	KeyID     string // ID of the signing key
+	Signature []byte // Signature of the event envelope
+	EventID   string // ID of the signed event, stores use it instead of generating one if it is set
+	Sequence  uint64 // Sequence the event has been signed for, stores return ErrConflict if it can't be kept
+}
+
+// SignedEventStore is implemented by event stores that keep signatures alongside the events.
+type SignedEventStore[E lavender.Event, S lavender.Snapshot] interface {
+	// SaveSignedEvents stores new events for the given aggregate together with one signature per event.
+	SaveSignedEvents(aggregate lavender.CustomAggregate[E, S], events []E, signatures []Signature) error
+
+	// LoadSignedEvents retrieves all stored events for the given aggregate and their signatures.
+	LoadSignedEvents(aggregate lavender.CustomAggregate[E, S]) ([]E, []Signature, error)
+}
+
+// Envelope binds an event to where it is stored: its stream, its sequence inside the stream and its ID.
+// A signed event can't be copied into another stream, reordered or duplicated without breaking its signature.
+type Envelope struct {
+	Tenant    string           // Tenant the stream belongs to
+	Aggregate lavender.Name    // Aggregate name (stream ID)
+	Version   lavender.Version // Aggregate version
+	Sequence  uint64           // Sequence of the event inside the stream
+	EventID   string           // ID of the event
+}
+
+// envelope is the signed representation of an event.
+type envelope struct {
+	Envelope
+	Event   lavender.Name
+	Payload []byte
+}
+
+// Bytes returns the canonical bytes of the envelope and the event. These bytes are signed and verified.
+func (e Envelope) Bytes(event lavender.Event) ([]byte, error) {
+	payload, err := canonicalEncoder.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalEncoder.Marshal(envelope{
+		Envelope: e,
+		Event:    event.Name(),
+		Payload:  payload,
+	})
+}
@@ -0,0 +1,115 @@
+package storetest
+
+import "github.com/FlauschigDings/lavender"
+
+// Aggregate is the aggregate the suite stores events and snapshots for. Every stream of the suite is an
+// Aggregate with its own name.
+type Aggregate struct {
+	Stream  lavender.Name
+	Entries []string
+}
+
+var _ lavender.Aggregate = new(Aggregate)
+
+// NewAggregate creates an empty aggregate for the stream.
+func NewAggregate(stream lavender.Name) *Aggregate {
+	return &Aggregate{Stream: stream}
+}
+
+// Aggregates returns one aggregate per stream used by the suite, e.g. to register them with a store.
+func Aggregates() []lavender.Aggregate {
+	return []lavender.Aggregate{NewAggregate(StreamA), NewAggregate(StreamB)}
+}
+
+// Streams used by the suite.
+const (
+	StreamA lavender.Name = "storetest-a"
+	StreamB lavender.Name = "storetest-b"
+)
+
+// Name implements lavender.CustomAggregate.
+func (a *Aggregate) Name() lavender.Name {
+	return a.Stream
+}
+
+// Version implements lavender.CustomAggregate.
+func (a *Aggregate) Version() lavender.Version {
+	return "1.0.0"
+}
+
+// ApplyEvent implements lavender.CustomAggregate.
+func (a *Aggregate) ApplyEvent(event lavender.Event) {
+	event.Apply(a)
+}
+
+// Events implements lavender.CustomAggregate.
+func (a *Aggregate) Events() []lavender.Event {
+	return []lavender.Event{
+		new(Appended),
+	}
+}
+
+// ApplySnapshot implements lavender.CustomAggregate.
+func (a *Aggregate) ApplySnapshot(snapshot lavender.Snapshot) {
+	if snapshot, ok := snapshot.(*Snapshot); ok {
+		a.Entries = append([]string(nil), snapshot.Entries...)
+	}
+}
+
+// TakeSnapshot implements lavender.CustomAggregate.
+func (a *Aggregate) TakeSnapshot() lavender.Snapshot {
+	return &Snapshot{Stream: a.Stream, Entries: append([]string(nil), a.Entries...)}
+}
+
+// Appended is the event of the suite.
+type Appended struct {
+	Index int
+	Text  string
+	Data  []byte
+}
+
+var _ lavender.Event = new(Appended)
+
+// Name implements lavender.Event.
+func (e *Appended) Name() lavender.Name {
+	return "appended"
+}
+
+// Apply implements lavender.Event.
+func (e *Appended) Apply(aggregate lavender.Aggregate) {
+	a := lavender.Parse[*Aggregate](aggregate)
+	a.Entries = append(a.Entries, e.Text)
+}
+
+// Unknown is an event that is not part of Aggregate.Events.
+type Unknown struct {
+	Text string
+}
+
+var _ lavender.Event = new(Unknown)
+
+// Name implements lavender.Event.
+func (e *Unknown) Name() lavender.Name {
+	return "unknown"
+}
+
+// Apply implements lavender.Event.
+func (e *Unknown) Apply(aggregate lavender.Aggregate) {}
+
+// Snapshot is the snapshot of the suite.
+type Snapshot struct {
+	Stream  lavender.Name
+	Entries []string
+}
+
+var _ lavender.Snapshot = new(Snapshot)
+
+// AggregateID implements lavender.Snapshot.
+func (s *Snapshot) AggregateID() lavender.Name {
+	return s.Stream
+}
+
+// Version implements lavender.Snapshot.
+func (s *Snapshot) Version() lavender.Version {
+	return "1.0.0"
+}
@@ -0,0 +1,465 @@
+// Package storetest provides a conformance suite for EventStore and SnapshotStore implementations.
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Store[lavender.Event, lavender.Snapshot] {
+//			return NewRedisStore(...).RegisterAggregates(storetest.Aggregates()...)
+//		})
+//	}
+//
+// Stores that need to know their types must register the aggregates returned by Aggregates.
+package storetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/FlauschigDings/lavender"
+	"github.com/FlauschigDings/lavender/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// Factory creates a new, empty store for a test of the suite.
+type Factory func(t *testing.T) store.Store[lavender.Event, lavender.Snapshot]
+
+// LargePayloadSize is the size of the payload stored by the large payload test.
+const LargePayloadSize = 4 << 20
+
+// Run runs the conformance suite against stores created by the factory. Every test gets a new store.
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot])
+	}{
+		{"Ordering", testOrdering},
+		{"RoundTrip", testRoundTrip},
+		{"Clear", testClear},
+		{"SnapshotLatestWins", testSnapshotLatestWins},
+		{"Concurrency", testConcurrency},
+		{"UnknownTypes", testUnknownTypes},
+		{"LargePayload", testLargePayload},
+		{"TenantIsolation", testTenantIsolation},
+		{"Lifecycle", testLifecycle},
+		{"Metadata", testMetadata},
+		{"Tail", testTail},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, factory(t))
+		})
+	}
+}
+
+// appended creates events with consecutive indexes starting at from.
+func appended(from, count int) []lavender.Event {
+	events := make([]lavender.Event, 0, count)
+	for i := from; i < from+count; i++ {
+		events = append(events, &Appended{Index: i, Text: fmt.Sprintf("entry %d", i)})
+	}
+	return events
+}
+
+// mustSave stores the events and stops the test on an error.
+func mustSave(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot], stream lavender.Name, events []lavender.Event) {
+	t.Helper()
+	if err := s.SaveEvents(NewAggregate(stream), events); err != nil {
+		t.Fatalf("save events to %s: %v", stream, err)
+	}
+}
+
+// mustLoad loads the events and stops the test on an error.
+func mustLoad(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot], stream lavender.Name) []lavender.Event {
+	t.Helper()
+	events, err := s.LoadEvents(NewAggregate(stream))
+	if err != nil {
+		t.Fatalf("load events of %s: %v", stream, err)
+	}
+	return events
+}
+
+// indexes returns the indexes of Appended events, -1 for other events.
+func indexes(events []lavender.Event) []int {
+	result := make([]int, 0, len(events))
+	for _, event := range events {
+		if event, ok := event.(*Appended); ok {
+			result = append(result, event.Index)
+		} else {
+			result = append(result, -1)
+		}
+	}
+	return result
+}
+
+// sequence returns the numbers from 0 to n-1.
+func sequence(n int) []int {
+	result := make([]int, n)
+	for i := range result {
+		result[i] = i
+	}
+	return result
+}
+
+// testOrdering checks that events are loaded in the order they have been appended.
+func testOrdering(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	mustSave(t, s, StreamA, appended(0, 1))
+	mustSave(t, s, StreamB, appended(100, 2))
+	mustSave(t, s, StreamA, appended(1, 5))
+	mustSave(t, s, StreamA, appended(6, 2))
+
+	assert.Equal(t, sequence(8), indexes(mustLoad(t, s, StreamA)))
+	assert.Equal(t, []int{100, 101}, indexes(mustLoad(t, s, StreamB)))
+}
+
+// testRoundTrip checks that events are loaded unchanged and empty streams load without an error.
+func testRoundTrip(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	assert.Empty(t, mustLoad(t, s, StreamA))
+	snapshot, err := s.LoadSnapshot(NewAggregate(StreamA))
+	if assert.NoError(t, err) {
+		assert.Nil(t, snapshot)
+	}
+
+	event := &Appended{Index: 7, Text: "grüße, 🦆", Data: []byte{0, 1, 2, 255}}
+	mustSave(t, s, StreamA, []lavender.Event{event})
+	events := mustLoad(t, s, StreamA)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, event, events[0])
+	}
+	assert.Empty(t, mustLoad(t, s, StreamB))
+}
+
+// testClear checks that clearing removes the events of one stream only and the stream stays usable.
+func testClear(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	mustSave(t, s, StreamA, appended(0, 3))
+	mustSave(t, s, StreamB, appended(0, 2))
+	snapshot := &Snapshot{Stream: StreamA, Entries: []string{"a"}}
+	if err := s.SaveSnapshot(NewAggregate(StreamA), snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ClearEvents(NewAggregate(StreamA)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, mustLoad(t, s, StreamA))
+	assert.Len(t, mustLoad(t, s, StreamB), 2)
+
+	loaded, err := s.LoadSnapshot(NewAggregate(StreamA))
+	if assert.NoError(t, err) && assert.NotNil(t, loaded) {
+		assert.Equal(t, snapshot, *loaded)
+	}
+
+	mustSave(t, s, StreamA, appended(3, 1))
+	assert.Equal(t, []int{3}, indexes(mustLoad(t, s, StreamA)))
+}
+
+// testSnapshotLatestWins checks that the last saved snapshot is loaded and loaded snapshots don't change.
+func testSnapshotLatestWins(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	aggregate := NewAggregate(StreamA)
+	for _, entries := range [][]string{{"a"}, {"a", "b"}, {"a", "b", "c"}} {
+		if err := s.SaveSnapshot(aggregate, &Snapshot{Stream: StreamA, Entries: entries}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, err := s.LoadSnapshot(aggregate)
+	if !assert.NoError(t, err) || !assert.NotNil(t, first) {
+		return
+	}
+	assert.Equal(t, &Snapshot{Stream: StreamA, Entries: []string{"a", "b", "c"}}, *first)
+
+	if err := s.SaveSnapshot(aggregate, &Snapshot{Stream: StreamA, Entries: []string{"d"}}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.LoadSnapshot(aggregate)
+	if assert.NoError(t, err) && assert.NotNil(t, second) {
+		assert.Equal(t, &Snapshot{Stream: StreamA, Entries: []string{"d"}}, *second)
+	}
+	assert.Equal(t, &Snapshot{Stream: StreamA, Entries: []string{"a", "b", "c"}}, *first, "a loaded snapshot changed")
+
+	other, err := s.LoadSnapshot(NewAggregate(StreamB))
+	if assert.NoError(t, err) {
+		assert.Nil(t, other)
+	}
+}
+
+// testConcurrency checks that concurrent appends are neither lost nor interleaved within a batch. Stores
+// implementing store.ConcurrentEventStore must let exactly one of several conflicting appends win and clear
+// only the events up to a sequence.
+func testConcurrency(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	const writers, batch = 20, 5
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.SaveEvents(NewAggregate(StreamA), appended(w*batch, batch)); err != nil {
+				t.Error(err)
+			}
+			if _, err := s.LoadEvents(NewAggregate(StreamA)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	loaded := indexes(mustLoad(t, s, StreamA))
+	if !assert.Len(t, loaded, writers*batch) {
+		return
+	}
+	for i := 0; i < len(loaded); i += batch {
+		first := loaded[i]
+		assert.Equal(t, 0, first%batch, "batch starts at %d", i)
+		for j := 1; j < batch; j++ {
+			assert.Equal(t, first+j, loaded[i+j], "batch starting at %d is interleaved", i)
+		}
+	}
+
+	concurrentStore, ok := s.(store.ConcurrentEventStore[lavender.Event, lavender.Snapshot])
+	if !ok {
+		return
+	}
+	head, err := concurrentStore.Sequence(NewAggregate(StreamA))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualValues(t, writers*batch, head)
+
+	var mu sync.Mutex
+	succeeded := 0
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := concurrentStore.SaveEventsAt(NewAggregate(StreamA), appended(1000+w, 1), nil, head)
+			if err != nil && !errors.Is(err, store.ErrConflict) {
+				t.Error(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, succeeded, "conflicting appends")
+	assert.Len(t, mustLoad(t, s, StreamA), writers*batch+1)
+
+	// Snapshots are only stored at the current head, and clearing up to it keeps later events.
+	if snapshotStore, ok := s.(store.ConcurrentSnapshotStore[lavender.Event, lavender.Snapshot]); ok {
+		snapshot := NewAggregate(StreamA).TakeSnapshot()
+		assert.ErrorIs(t, snapshotStore.SaveSnapshotAt(NewAggregate(StreamA), snapshot, head), store.ErrConflict)
+		assert.NoError(t, snapshotStore.SaveSnapshotAt(NewAggregate(StreamA), snapshot, head+1))
+	}
+	assert.NoError(t, concurrentStore.ClearEventsUntil(NewAggregate(StreamA), head))
+	assert.Len(t, mustLoad(t, s, StreamA), 1)
+}
+
+// testUnknownTypes checks that events of types the aggregate doesn't know are either rejected or
+// returned unchanged, but never dropped silently.
+func testUnknownTypes(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	mustSave(t, s, StreamA, appended(0, 1))
+	unknown := &Unknown{Text: "unknown"}
+	if err := s.SaveEvents(NewAggregate(StreamA), []lavender.Event{unknown}); err != nil {
+		return
+	}
+	events, err := s.LoadEvents(NewAggregate(StreamA))
+	if err != nil {
+		return
+	}
+	if assert.Len(t, events, 2, "unknown event has been dropped") {
+		assert.Equal(t, unknown, events[1])
+	}
+}
+
+// testLargePayload checks that large events and snapshots are stored unchanged.
+func testLargePayload(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	data := bytes.Repeat([]byte("lavender"), LargePayloadSize/8)
+	event := &Appended{Index: 0, Text: string(data[:LargePayloadSize/4]), Data: data}
+	mustSave(t, s, StreamA, []lavender.Event{event})
+	events := mustLoad(t, s, StreamA)
+	if assert.Len(t, events, 1) {
+		loaded := events[0].(*Appended)
+		assert.True(t, bytes.Equal(event.Data, loaded.Data), "event data changed")
+		assert.Equal(t, len(event.Text), len(loaded.Text))
+	}
+
+	snapshot := &Snapshot{Stream: StreamA, Entries: []string{string(data)}}
+	if err := s.SaveSnapshot(NewAggregate(StreamA), snapshot); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := s.LoadSnapshot(NewAggregate(StreamA))
+	if assert.NoError(t, err) && assert.NotNil(t, loaded) {
+		assert.True(t, (*loaded).(*Snapshot).Entries[0] == snapshot.Entries[0], "snapshot data changed")
+	}
+}
+
+// testTenantIsolation checks that tenants can never load each other's events or snapshots, even if the
+// streams have the same name.
+func testTenantIsolation(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	tenants := []string{"", "tenant-1", "tenant-2"}
+	aggregate := func(tenant string) lavender.Aggregate {
+		return lavender.WithTenant[lavender.Event, lavender.Snapshot](NewAggregate(StreamA), tenant)
+	}
+	for i, tenant := range tenants {
+		if err := s.SaveEvents(aggregate(tenant), appended(i*10, i+1)); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveSnapshot(aggregate(tenant), &Snapshot{Stream: StreamA, Entries: []string{tenant}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.ClearEvents(aggregate("tenant-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tenant := range tenants {
+		events, err := s.LoadEvents(aggregate(tenant))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		if tenant == "tenant-1" {
+			assert.Empty(t, events, "tenant %q", tenant)
+		} else {
+			assert.Equal(t, indexes(appended(i*10, i+1)), indexes(events), "tenant %q", tenant)
+		}
+
+		snapshot, err := s.LoadSnapshot(aggregate(tenant))
+		if assert.NoError(t, err) && assert.NotNil(t, snapshot, "tenant %q", tenant) {
+			assert.Equal(t, []string{tenant}, (*snapshot).(*Snapshot).Entries, "tenant %q", tenant)
+		}
+	}
+
+	if concurrentStore, ok := s.(store.ConcurrentEventStore[lavender.Event, lavender.Snapshot]); ok {
+		head, err := concurrentStore.Sequence(aggregate("tenant-2"))
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, 3, head)
+		}
+	}
+}
+
+// testLifecycle checks that closed and deleted streams reject appends, that soft-deleted streams can be
+// undeleted and that tombstones remove a stream for good.
+func testLifecycle(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	lifecycleStore, ok := s.(store.LifecycleStore[lavender.Event, lavender.Snapshot])
+	if !ok {
+		t.Skip("store does not implement store.LifecycleStore")
+	}
+	stateOf := func(stream lavender.Name) store.StreamState {
+		t.Helper()
+		state, err := lifecycleStore.StreamState(NewAggregate(stream))
+		assert.NoError(t, err)
+		return state
+	}
+	mustSave(t, s, StreamA, appended(0, 2))
+	mustSave(t, s, StreamB, appended(0, 1))
+	assert.Equal(t, store.StreamActive, stateOf(StreamA))
+
+	// Closed streams can be loaded but not appended to.
+	assert.NoError(t, lifecycleStore.SetStreamState(NewAggregate(StreamA), store.StreamClosed))
+	assert.ErrorIs(t, s.SaveEvents(NewAggregate(StreamA), appended(2, 1)), store.ErrStreamClosed)
+	assert.Equal(t, sequence(2), indexes(mustLoad(t, s, StreamA)))
+	assert.ErrorIs(t, lifecycleStore.SetStreamState(NewAggregate(StreamA), store.StreamActive), store.ErrInvalidTransition)
+
+	// Soft-deleted streams keep their events and can be undeleted.
+	assert.NoError(t, lifecycleStore.SetStreamState(NewAggregate(StreamA), store.StreamDeleted))
+	assert.ErrorIs(t, s.SaveEvents(NewAggregate(StreamA), appended(2, 1)), store.ErrStreamDeleted)
+	assert.NoError(t, lifecycleStore.SetStreamState(NewAggregate(StreamA), store.StreamActive))
+	mustSave(t, s, StreamA, appended(2, 1))
+	assert.Equal(t, sequence(3), indexes(mustLoad(t, s, StreamA)))
+
+	// Tombstones remove events and snapshots and are final.
+	if err := s.SaveSnapshot(NewAggregate(StreamA), &Snapshot{Stream: StreamA, Entries: []string{"a"}}); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, lifecycleStore.SetStreamState(NewAggregate(StreamA), store.StreamTombstoned))
+	assert.Equal(t, store.StreamTombstoned, stateOf(StreamA))
+	assert.Empty(t, mustLoad(t, s, StreamA))
+	snapshot, err := s.LoadSnapshot(NewAggregate(StreamA))
+	if assert.NoError(t, err) {
+		assert.Nil(t, snapshot)
+	}
+	assert.ErrorIs(t, s.SaveEvents(NewAggregate(StreamA), appended(3, 1)), store.ErrStreamDeleted)
+	assert.ErrorIs(t, s.SaveSnapshot(NewAggregate(StreamA), &Snapshot{Stream: StreamA}), store.ErrStreamDeleted)
+	assert.ErrorIs(t, lifecycleStore.SetStreamState(NewAggregate(StreamA), store.StreamActive), store.ErrInvalidTransition)
+
+	// Other streams are not affected.
+	assert.Equal(t, store.StreamActive, stateOf(StreamB))
+	assert.Equal(t, sequence(1), indexes(mustLoad(t, s, StreamB)))
+}
+
+// testMetadata checks that the stream metadata hides events from LoadEvents and that scavenging removes
+// them without changing the sequence of the stream.
+func testMetadata(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	metadataStore, ok := s.(store.MetadataStore[lavender.Event, lavender.Snapshot])
+	if !ok {
+		t.Skip("store does not implement store.MetadataStore")
+	}
+	setMetadata := func(metadata store.StreamMetadata) {
+		t.Helper()
+		if err := metadataStore.SetStreamMetadata(NewAggregate(StreamA), metadata); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustSave(t, s, StreamA, appended(0, 5))
+	mustSave(t, s, StreamB, appended(0, 2))
+
+	setMetadata(store.StreamMetadata{MaxCount: 3})
+	assert.Equal(t, []int{2, 3, 4}, indexes(mustLoad(t, s, StreamA)))
+	setMetadata(store.StreamMetadata{MaxCount: 3, TruncateBefore: 4})
+	assert.Equal(t, []int{3, 4}, indexes(mustLoad(t, s, StreamA)))
+	assert.Equal(t, sequence(2), indexes(mustLoad(t, s, StreamB)))
+
+	metadata, err := metadataStore.StreamMetadata(NewAggregate(StreamA))
+	if assert.NoError(t, err) {
+		assert.Equal(t, store.StreamMetadata{MaxCount: 3, TruncateBefore: 4}, metadata)
+	}
+
+	if scavenger, ok := s.(store.Scavenger); ok {
+		removed, err := scavenger.Scavenge()
+		if assert.NoError(t, err) {
+			assert.Equal(t, 3, removed)
+		}
+		// Scavenged events are gone even without metadata.
+		setMetadata(store.StreamMetadata{})
+		assert.Equal(t, []int{3, 4}, indexes(mustLoad(t, s, StreamA)))
+		assert.Equal(t, sequence(2), indexes(mustLoad(t, s, StreamB)))
+	}
+
+	// New events continue the sequence of the stream.
+	mustSave(t, s, StreamA, appended(5, 1))
+	if concurrentStore, ok := s.(store.ConcurrentEventStore[lavender.Event, lavender.Snapshot]); ok {
+		head, err := concurrentStore.Sequence(NewAggregate(StreamA))
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, 6, head)
+		}
+	}
+}
+
+// testTail checks that LoadEventsAfter only loads the events following a sequence, respecting the stream
+// metadata like LoadEvents.
+func testTail(t *testing.T, s store.Store[lavender.Event, lavender.Snapshot]) {
+	mustSave(t, s, StreamA, appended(0, 5))
+	mustSave(t, s, StreamB, appended(0, 2))
+
+	loadAfter := func(sequence uint64) []int {
+		t.Helper()
+		events, signatures, err := store.LoadEventsAfter(s, NewAggregate(StreamA), sequence)
+		if err != nil {
+			t.Fatalf("load events of %s after %d: %v", StreamA, sequence, err)
+		}
+		assert.Len(t, signatures, len(events))
+		return indexes(events)
+	}
+	assert.Equal(t, sequence(5), loadAfter(0))
+	assert.Equal(t, []int{3, 4}, loadAfter(3))
+	assert.Empty(t, loadAfter(5))
+
+	if metadataStore, ok := s.(store.MetadataStore[lavender.Event, lavender.Snapshot]); ok {
+		if err := metadataStore.SetStreamMetadata(NewAggregate(StreamA), store.StreamMetadata{MaxCount: 3}); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []int{2, 3, 4}, loadAfter(1))
+		assert.Equal(t, []int{4}, loadAfter(4))
+	}
+}
@@ -0,0 +1,29 @@
+package store
+
+import (
+	"net/url"
+
+	"github.com/FlauschigDings/lavender"
+)
+
+// streamID identifies the stream of an aggregate of a tenant.
+type streamID struct {
+	Tenant string
+	Name   lavender.Name
+}
+
+// streamOf returns the stream of the aggregate.
+func streamOf[E lavender.Event, S lavender.Snapshot](aggregate lavender.CustomAggregate[E, S]) streamID {
+	return streamID{Tenant: lavender.TenantOf(aggregate), Name: aggregate.Name()}
+}
+
+// Stream returns the name the stream is encoded with. Encoders like the EncryptionEncoder use it, so every
+// tenant gets its own keys. Tenant and name are path escaped and joined as "<tenant>/<name>" for streams of
+// a tenant, so a name like "acme/account" can't be mistaken for the stream "account" of the tenant "acme".
+// Names without characters to escape are kept as they are for streams without a tenant.
+func (id streamID) Stream() lavender.Name {
+	if id.Tenant == "" {
+		return lavender.Name(url.PathEscape(string(id.Name)))
+	}
+	return lavender.Name(url.PathEscape(id.Tenant) + "/" + url.PathEscape(string(id.Name)))
+}
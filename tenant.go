@@ -0,0 +1,53 @@
+package lavender
+
+import "context"
+
+// TenantScoped is implemented by aggregates that belong to a tenant. Stores keep the streams of different
+// tenants apart, even if the aggregates have the same name.
+type TenantScoped interface {
+	Tenant() string
+}
+
+// TenantOf returns the tenant of an aggregate, or "" if it doesn't belong to a tenant.
+func TenantOf(aggregate any) string {
+	if scoped, ok := aggregate.(TenantScoped); ok {
+		return scoped.Tenant()
+	}
+	return ""
+}
+
+// tenantAggregate assigns an aggregate to a tenant.
+type tenantAggregate[E Event, S Snapshot] struct {
+	CustomAggregate[E, S]
+	tenant string
+}
+
+// Tenant implements TenantScoped.
+func (a tenantAggregate[E, S]) Tenant() string {
+	return a.tenant
+}
+
+// WithTenant assigns the aggregate to the tenant. An empty tenant returns the aggregate unchanged.
+func WithTenant[E Event, S Snapshot](aggregate CustomAggregate[E, S], tenant string) CustomAggregate[E, S] {
+	if tenant == "" {
+		return aggregate
+	}
+	if scoped, ok := aggregate.(tenantAggregate[E, S]); ok {
+		aggregate = scoped.CustomAggregate
+	}
+	return tenantAggregate[E, S]{CustomAggregate: aggregate, tenant: tenant}
+}
+
+// tenantKey is the context key of the tenant.
+type tenantKey struct{}
+
+// ContextWithTenant returns a context carrying the tenant.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant carried by the context, or "" if there is none.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
@@ -0,0 +1,100 @@
+package a
+
+import "github.com/FlauschigDings/lavender"
+
+var applied int
+
+type Account struct {
+	Users map[string]string
+}
+
+func (a *Account) Name() lavender.Name {
+	return "account"
+}
+
+func (a *Account) Version() lavender.Version {
+	return "1.0.0"
+}
+
+func (a *Account) Events() []lavender.Event {
+	return []lavender.Event{
+		new(Create),
+		Rename{}, // want `event Rename is returned by value, encoders can't decode into it: return a pointer`
+	}
+}
+
+func (a *Account) ApplyEvent(event lavender.Event) {
+	switch event := event.(type) {
+	case *Delete: // want `event Delete is applied to Account but missing from its Events method, stores can't load it`
+		delete(a.Users, event.ID)
+	default:
+		event.Apply(a)
+	}
+}
+
+func (a *Account) TakeSnapshot() lavender.Snapshot {
+	return AccountSnapshot{Users: a.Users} // want `snapshot AccountSnapshot is returned by value, encoders can't decode into it: return a pointer`
+}
+
+func (a *Account) ApplySnapshot(snapshot lavender.Snapshot) { // want `ApplySnapshot of Account doesn't handle the AccountSnapshot snapshots its TakeSnapshot method returns`
+	accountSnapshot, ok := snapshot.(*AccountSnapshot)
+	if !ok {
+		return
+	}
+	a.Users = accountSnapshot.Users
+}
+
+type AccountSnapshot struct {
+	Users map[string]string
+}
+
+func (s AccountSnapshot) AggregateID() lavender.Name {
+	return "account"
+}
+
+func (s AccountSnapshot) Version() lavender.Version {
+	return "1.0.0"
+}
+
+type Create struct {
+	ID    string
+	Email string
+}
+
+func (c *Create) Name() lavender.Name {
+	return "create"
+}
+
+func (c *Create) Apply(aggregate lavender.Aggregate) {
+	account := lavender.Parse[*Account](aggregate)
+	account.Users[c.ID] = c.Email
+	applied++    // want `Apply of Create assigns the package variable applied, events must only change the aggregate`
+	c.Email = "" // want `Apply of Create modifies the event, events must only change the aggregate`
+}
+
+type Rename struct {
+	ID    string
+	Email string
+}
+
+func (r Rename) Name() lavender.Name {
+	return "rename"
+}
+
+func (r Rename) Apply(aggregate lavender.Aggregate) {
+	if account, ok := aggregate.(*Account); ok {
+		account.Users[r.ID] = r.Email
+	}
+}
+
+type Delete struct {
+	ID string
+}
+
+func (d *Delete) Name() lavender.Name {
+	return "delete"
+}
+
+func (d *Delete) Apply(aggregate lavender.Aggregate) {
+	delete(lavender.Parse[*Account](aggregate).Users, d.ID) // want `event Delete is applied to Account but missing from its Events method, stores can't load it`
+}
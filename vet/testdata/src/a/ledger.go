@@ -0,0 +1,129 @@
+package a
+
+import "github.com/FlauschigDings/lavender"
+
+// Ledger uses lavender correctly.
+type Ledger struct {
+	Balance int
+}
+
+var ledgerEvents = []lavender.Event{new(Deposit)}
+
+func (l *Ledger) Name() lavender.Name {
+	return "ledger"
+}
+
+func (l *Ledger) Version() lavender.Version {
+	return "1.0.0"
+}
+
+func (l *Ledger) Events() []lavender.Event {
+	return []lavender.Event{
+		new(Deposit),
+		&Withdraw{},
+	}
+}
+
+func (l *Ledger) ApplyEvent(event lavender.Event) {
+	switch event := event.(type) {
+	case *Deposit:
+		l.Balance += event.Amount
+	default:
+		event.Apply(l)
+	}
+}
+
+func (l *Ledger) TakeSnapshot() lavender.Snapshot {
+	return &LedgerSnapshot{Balance: l.Balance}
+}
+
+func (l *Ledger) ApplySnapshot(snapshot lavender.Snapshot) {
+	if snapshot, ok := snapshot.(*LedgerSnapshot); ok {
+		l.Balance = snapshot.Balance
+	}
+}
+
+type LedgerSnapshot struct {
+	Balance int
+}
+
+func (s *LedgerSnapshot) AggregateID() lavender.Name {
+	return "ledger"
+}
+
+func (s *LedgerSnapshot) Version() lavender.Version {
+	return "1.0.0"
+}
+
+type Deposit struct {
+	Amount int
+}
+
+func (d *Deposit) Name() lavender.Name {
+	return "deposit"
+}
+
+func (d *Deposit) Apply(aggregate lavender.Aggregate) {
+	amount := d.Amount
+	amount++
+	aggregate.(*Ledger).Balance += amount
+}
+
+type Withdraw struct {
+	Amount int
+}
+
+func (w *Withdraw) Name() lavender.Name {
+	return "withdraw"
+}
+
+func (w *Withdraw) Apply(aggregate lavender.Aggregate) {
+	switch ledger := aggregate.(type) {
+	case *Ledger:
+		ledger.Balance -= w.Amount
+	case *Shelf:
+		ledger.Books = nil
+	}
+}
+
+// Shelf lists its events in a variable and ignores snapshots in different ways.
+type Shelf struct {
+	Books []string
+}
+
+func (s *Shelf) Name() lavender.Name {
+	return "shelf"
+}
+
+func (s *Shelf) Version() lavender.Version {
+	return "1.0.0"
+}
+
+func (s *Shelf) Events() []lavender.Event {
+	return ledgerEvents
+}
+
+func (s *Shelf) ApplyEvent(event lavender.Event) {
+	event.Apply(s)
+}
+
+func (s *Shelf) TakeSnapshot() lavender.Snapshot {
+	return &LedgerSnapshot{}
+}
+
+func (s *Shelf) ApplySnapshot(snapshot lavender.Snapshot) { // want `ApplySnapshot of Shelf doesn't handle the \*LedgerSnapshot snapshots its TakeSnapshot method returns`
+	switch snapshot.(type) {
+	case *ShelfSnapshot:
+		s.Books = nil
+	}
+}
+
+type ShelfSnapshot struct{}
+
+func (s *ShelfSnapshot) AggregateID() lavender.Name {
+	return "shelf"
+}
+
+func (s *ShelfSnapshot) Version() lavender.Version {
+	return "1.0.0"
+}
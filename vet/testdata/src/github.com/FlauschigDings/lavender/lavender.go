@@ -0,0 +1,31 @@
+// Package lavender is a stub of the lavender types the analyzer looks for.
+package lavender
+
+type Name string
+
+type Version string
+
+type Event interface {
+	Name() Name
+	Apply(aggregate CustomAggregate[Event, Snapshot])
+}
+
+type Snapshot interface {
+	AggregateID() Name
+	Version() Version
+}
+
+type Aggregate = CustomAggregate[Event, Snapshot]
+
+type CustomAggregate[E Event, S Snapshot] interface {
+	Name() Name
+	Version() Version
+	ApplyEvent(event E)
+	Events() []E
+	TakeSnapshot() S
+	ApplySnapshot(snapshot S)
+}
+
+func Parse[T CustomAggregate[E, S], E Event, S Snapshot](aggregate CustomAggregate[E, S]) T {
+	return aggregate.(T)
+}
@@ -0,0 +1,429 @@
+// Package vet implements a go/analysis analyzer that reports common mistakes in code using lavender
+// types. It is run by the lavender-vet command (cmd/lavender-vet), standalone or by go vet:
+//
+//	go vet -vettool=$(which lavender-vet) ./...
+package vet
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// lavenderPath is the import path of the lavender package.
+const lavenderPath = "github.com/FlauschigDings/lavender"
+
+// Analyzer reports events missing from the Events method of the aggregate they are applied to, events and
+// snapshots returned by value, ApplySnapshot methods that don't handle the aggregate's own snapshots and
+// Apply methods changing state outside of the aggregate.
+var Analyzer = &analysis.Analyzer{
+	Name: "lavender",
+	Doc: `report common mistakes in code using lavender
+
+The analyzer reports
+  - events applied to an aggregate by its ApplyEvent method or by their Apply method that are missing from
+    the aggregate's Events method, stores fail to load them with "invalid event type",
+  - events returned by value from Events and snapshots returned by value from TakeSnapshot, encoders
+    can't decode into them,
+  - ApplySnapshot methods that only handle other types than the snapshots TakeSnapshot returns, they
+    silently ignore or panic on every stored snapshot,
+  - Apply methods of events that assign package variables or fields of the event itself instead of
+    changing the aggregate.`,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// checker holds the state of an analysis pass.
+type checker struct {
+	pass  *analysis.Pass
+	event *types.Interface // lavender.Event
+
+	// events holds the event types listed by the Events method of the aggregates. Aggregates whose Events
+	// method doesn't return a composite literal are missing.
+	events map[*types.TypeName]map[*types.TypeName]bool
+
+	// snapshots holds the snapshot types returned by the TakeSnapshot method of the aggregates. Aggregates
+	// whose TakeSnapshot method returns an interface are missing.
+	snapshots map[*types.TypeName][]types.Type
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	event := lookupEvent(pass.Pkg)
+	if event == nil {
+		return nil, nil
+	}
+	c := &checker{
+		pass:      pass,
+		event:     event,
+		events:    make(map[*types.TypeName]map[*types.TypeName]bool),
+		snapshots: make(map[*types.TypeName][]types.Type),
+	}
+
+	// The Events and TakeSnapshot methods are read first, Apply methods may be declared before them.
+	var methods []*ast.FuncDecl
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	inspect.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(node ast.Node) {
+		decl := node.(*ast.FuncDecl)
+		if decl.Recv == nil || decl.Body == nil || len(decl.Recv.List) == 0 {
+			return
+		}
+		methods = append(methods, decl)
+		switch {
+		case decl.Name.Name == "Events" && c.isAggregate(c.receiver(decl)):
+			c.readEvents(decl)
+		case decl.Name.Name == "TakeSnapshot" && c.isAggregate(c.receiver(decl)):
+			c.readSnapshots(decl)
+		}
+	})
+	for _, decl := range methods {
+		receiver := c.receiver(decl)
+		switch {
+		case decl.Name.Name == "ApplyEvent" && c.isAggregate(receiver):
+			c.checkApplyEvent(decl, receiver)
+		case decl.Name.Name == "ApplySnapshot" && c.isAggregate(receiver):
+			c.checkApplySnapshot(decl, receiver)
+		case decl.Name.Name == "Apply" && c.isEvent(receiver):
+			c.checkApply(decl, receiver)
+		}
+	}
+	return nil, nil
+}
+
+// lookupEvent returns the lavender.Event interface if the package imports lavender.
+func lookupEvent(pkg *types.Package) *types.Interface {
+	for _, imported := range pkg.Imports() {
+		if imported.Path() != lavenderPath {
+			continue
+		}
+		if obj, ok := imported.Scope().Lookup("Event").(*types.TypeName); ok {
+			if iface, ok := obj.Type().Underlying().(*types.Interface); ok {
+				return iface
+			}
+		}
+	}
+	return nil
+}
+
+// receiver returns the type name of the receiver of a method.
+func (c *checker) receiver(decl *ast.FuncDecl) *types.TypeName {
+	fn, ok := c.pass.TypesInfo.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return nil
+	}
+	return typeName(fn.Type().(*types.Signature).Recv().Type())
+}
+
+// typeName returns the name of a named type or of a pointer to it.
+func typeName(t types.Type) *types.TypeName {
+	if pointer, ok := t.(*types.Pointer); ok {
+		t = pointer.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj()
+	}
+	return nil
+}
+
+// isAggregate reports if the type has the methods of lavender.CustomAggregate.
+func (c *checker) isAggregate(name *types.TypeName) bool {
+	if name == nil {
+		return false
+	}
+	methods := types.NewMethodSet(types.NewPointer(name.Type()))
+	for _, method := range []string{"Name", "Version", "ApplyEvent", "Events", "TakeSnapshot", "ApplySnapshot"} {
+		if methods.Lookup(name.Pkg(), method) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// isEvent reports if the type implements lavender.Event.
+func (c *checker) isEvent(name *types.TypeName) bool {
+	return name != nil && types.Implements(types.NewPointer(name.Type()), c.event)
+}
+
+// readEvents records the events listed by the Events method of an aggregate and reports events returned
+// by value.
+func (c *checker) readEvents(decl *ast.FuncDecl) {
+	if len(decl.Body.List) != 1 {
+		return
+	}
+	ret, ok := decl.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return
+	}
+	lit, ok := ast.Unparen(ret.Results[0]).(*ast.CompositeLit)
+	if !ok {
+		return
+	}
+	listed := make(map[*types.TypeName]bool)
+	for _, elt := range lit.Elts {
+		t := c.pass.TypesInfo.TypeOf(elt)
+		if t == nil {
+			continue
+		}
+		if name := typeName(t); name != nil {
+			listed[name] = true
+		}
+		if !isPointer(t) {
+			c.pass.Reportf(elt.Pos(), "event %s is returned by value, encoders can't decode into it: return a pointer", types.TypeString(t, types.RelativeTo(c.pass.Pkg)))
+		}
+	}
+	c.events[c.receiver(decl)] = listed
+}
+
+// isPointer reports if values of the type can be decoded into, which are pointers and interfaces
+// holding them.
+func isPointer(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Pointer, *types.Interface:
+		return true
+	}
+	return false
+}
+
+// requireListed reports an event type that is applied to the aggregate but missing from its Events method.
+func (c *checker) requireListed(pos token.Pos, aggregate *types.TypeName, event types.Type) {
+	name := typeName(event)
+	if name == nil || !c.isEvent(name) {
+		return
+	}
+	listed, ok := c.events[aggregate]
+	if !ok || listed[name] {
+		return
+	}
+	c.pass.Reportf(pos, "event %s is applied to %s but missing from its Events method, stores can't load it", name.Name(), aggregate.Name())
+}
+
+// checkApplyEvent checks that the events the ApplyEvent method of an aggregate handles are listed by its
+// Events method.
+func (c *checker) checkApplyEvent(decl *ast.FuncDecl, aggregate *types.TypeName) {
+	param := c.param(decl)
+	if param == nil {
+		return
+	}
+	c.assertions(decl.Body, param, func(pos token.Pos, t types.Type) {
+		c.requireListed(pos, aggregate, t)
+	})
+}
+
+// checkApply checks that an event is listed by the aggregates its Apply method applies it to, and that
+// it doesn't change state outside of the aggregate.
+func (c *checker) checkApply(decl *ast.FuncDecl, event *types.TypeName) {
+	if param := c.param(decl); param != nil {
+		c.assertions(decl.Body, param, func(pos token.Pos, t types.Type) {
+			if aggregate := typeName(t); aggregate != nil {
+				c.requireListed(pos, aggregate, event.Type())
+			}
+		})
+	}
+	// lavender.Parse[*Aggregate](aggregate)
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		var fun, typeArg ast.Expr
+		switch index := ast.Unparen(call.Fun).(type) {
+		case *ast.IndexExpr:
+			fun, typeArg = index.X, index.Index
+		case *ast.IndexListExpr:
+			fun, typeArg = index.X, index.Indices[0]
+		default:
+			return true
+		}
+		if fn := calledFunc(c.pass.TypesInfo, fun); fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == lavenderPath && fn.Name() == "Parse" {
+			if aggregate := typeName(c.pass.TypesInfo.TypeOf(typeArg)); aggregate != nil {
+				c.requireListed(call.Pos(), aggregate, event.Type())
+			}
+		}
+		return true
+	})
+
+	var recv *types.Var
+	if names := decl.Recv.List[0].Names; len(names) == 1 {
+		recv, _ = c.pass.TypesInfo.Defs[names[0]].(*types.Var)
+	}
+	check := func(lhs ast.Expr) {
+		ident := rootIdent(c.pass.TypesInfo, lhs)
+		if ident == nil {
+			return
+		}
+		v, ok := c.pass.TypesInfo.Uses[ident].(*types.Var)
+		switch {
+		case !ok:
+		case v.Parent() != nil && v.Parent() == v.Pkg().Scope():
+			c.pass.Reportf(lhs.Pos(), "Apply of %s assigns the package variable %s, events must only change the aggregate", event.Name(), v.Name())
+		case v == recv && ident != lhs:
+			c.pass.Reportf(lhs.Pos(), "Apply of %s modifies the event, events must only change the aggregate", event.Name())
+		}
+	}
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			if node.Tok != token.DEFINE {
+				for _, lhs := range node.Lhs {
+					check(lhs)
+				}
+			}
+		case *ast.IncDecStmt:
+			check(node.X)
+		}
+		return true
+	})
+}
+
+// readSnapshots records the snapshot types the TakeSnapshot method of an aggregate returns and reports
+// snapshots returned by value. Aggregates returning snapshots of an interface type are missing.
+func (c *checker) readSnapshots(decl *ast.FuncDecl) {
+	var taken []types.Type
+	known := true
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			for _, result := range node.Results {
+				t := c.pass.TypesInfo.TypeOf(result)
+				if t == nil || types.IsInterface(t) {
+					known = false
+					continue
+				}
+				taken = append(taken, t)
+				if !isPointer(t) && typeName(t) != nil {
+					c.pass.Reportf(result.Pos(), "snapshot %s is returned by value, encoders can't decode into it: return a pointer", types.TypeString(t, types.RelativeTo(c.pass.Pkg)))
+				}
+			}
+		}
+		return true
+	})
+	if known && len(taken) > 0 {
+		c.snapshots[c.receiver(decl)] = taken
+	}
+}
+
+// checkApplySnapshot reports ApplySnapshot methods that only handle other types than the snapshots of the
+// aggregate's TakeSnapshot method, so they silently ignore or panic on every stored snapshot. Snapshots of
+// other types may be ignored, e.g. by a checked type assertion.
+func (c *checker) checkApplySnapshot(decl *ast.FuncDecl, aggregate *types.TypeName) {
+	param := c.param(decl)
+	taken, ok := c.snapshots[aggregate]
+	if param == nil || !ok {
+		return
+	}
+	asserted, handled := false, false
+	c.assertions(decl.Body, param, func(_ token.Pos, t types.Type) {
+		asserted = true
+		for _, snapshot := range taken {
+			handled = handled || types.Identical(t, snapshot)
+		}
+	})
+	if asserted && !handled {
+		c.pass.Reportf(decl.Name.Pos(), "ApplySnapshot of %s doesn't handle the %s snapshots its TakeSnapshot method returns", aggregate.Name(), types.TypeString(taken[0], types.RelativeTo(c.pass.Pkg)))
+	}
+}
+
+// param returns the single parameter of a method.
+func (c *checker) param(decl *ast.FuncDecl) *types.Var {
+	params := decl.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) != 1 {
+		return nil
+	}
+	v, _ := c.pass.TypesInfo.Defs[params[0].Names[0]].(*types.Var)
+	return v
+}
+
+// assertions calls found with the types the variable is asserted to by type assertions and type switches.
+func (c *checker) assertions(body *ast.BlockStmt, v *types.Var, found func(pos token.Pos, t types.Type)) {
+	info := c.pass.TypesInfo
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.TypeAssertExpr:
+			if node.Type != nil && isIdent(info, node.X, v) {
+				found(node.Type.Pos(), info.TypeOf(node.Type))
+			}
+		case *ast.TypeSwitchStmt:
+			if !switchesOn(info, node, v) {
+				return true
+			}
+			for _, clause := range node.Body.List {
+				for _, expr := range clause.(*ast.CaseClause).List {
+					found(expr.Pos(), info.TypeOf(expr))
+				}
+			}
+			ast.Inspect(node.Body, func(node ast.Node) bool {
+				if assert, ok := node.(*ast.TypeAssertExpr); ok && assert.Type != nil && isIdent(info, assert.X, v) {
+					found(assert.Type.Pos(), info.TypeOf(assert.Type))
+				}
+				return true
+			})
+			return false
+		}
+		return true
+	})
+}
+
+// switchesOn reports if a type switch switches on the variable.
+func switchesOn(info *types.Info, stmt *ast.TypeSwitchStmt, v *types.Var) bool {
+	var x ast.Expr
+	switch assign := stmt.Assign.(type) {
+	case *ast.AssignStmt:
+		x = assign.Rhs[0]
+	case *ast.ExprStmt:
+		x = assign.X
+	}
+	assert, ok := x.(*ast.TypeAssertExpr)
+	return ok && isIdent(info, assert.X, v)
+}
+
+// isIdent reports if the expression is the variable.
+func isIdent(info *types.Info, expr ast.Expr, v *types.Var) bool {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	return ok && v != nil && info.ObjectOf(ident) == v
+}
+
+// rootIdent returns the variable an assigned expression belongs to, e.g. c for c.User.Email, m for m[k]
+// and Var for pkg.Var.
+func rootIdent(info *types.Info, expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return e
+		case *ast.SelectorExpr:
+			if x, ok := e.X.(*ast.Ident); ok {
+				if _, ok := info.Uses[x].(*types.PkgName); ok {
+					return e.Sel
+				}
+			}
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		default:
+			return nil
+		}
+	}
+}
+
+// calledFunc returns the function an expression refers to.
+func calledFunc(info *types.Info, expr ast.Expr) *types.Func {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		fn, _ := info.Uses[expr].(*types.Func)
+		return fn
+	case *ast.SelectorExpr:
+		fn, _ := info.Uses[expr.Sel].(*types.Func)
+		return fn
+	}
+	return nil
+}